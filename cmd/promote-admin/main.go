@@ -0,0 +1,55 @@
+// Command promote-admin gives the admin role to existing accounts. It is the
+// one-time bootstrap step for a fresh install: the operator runs it with the
+// email of an account they have checked belongs to them, e.g.
+//
+//	go run ./cmd/promote-admin owner@example.com
+//
+// Admins can then assign roles to everyone else through the API.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: promote-admin <email> [email...]")
+		os.Exit(2)
+	}
+
+	cfg := util.LoadENV()
+	db := util.ConnectDB(cfg)
+	users := repository.NewUserRepository(db)
+
+	failed := false
+	for _, email := range os.Args[1:] {
+		email = strings.TrimSpace(email)
+		user, err := users.FindByEmail(email)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Fprintf(os.Stderr, "%s: no account with this email\n", email)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", email, err)
+			}
+			failed = true
+			continue
+		}
+		if err := users.SetRoleByEmails([]string{user.Email}, model.UserRoleAdmin); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", email, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s (user %d) is now an admin\n", user.Email, user.ID)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
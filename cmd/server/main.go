@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"go_starter/internal/middleware"
 	"go_starter/internal/model"
 	_ "go_starter/internal/repository"
 	"go_starter/internal/router"
 	_ "go_starter/internal/service"
 	"go_starter/internal/util"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -47,13 +54,33 @@ func main() {
 	}))
 
 	// Setup routes
-	router.SetupRoutes(r, db, cfg, logger)
+	jobs := router.SetupRoutes(r, db, cfg, logger)
+
+	// Start background jobs (scheduled messages, ...) until the process is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	jobs.Start(ctx)
+
+	// Shut the HTTP server down gracefully once a stop signal arrives
+	srv := &http.Server{Addr: ":" + cfg.Server.Port, Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Server shutdown did not complete cleanly", zap.String("error", err.Error()))
+		}
+	}()
 
 	// Log server start
 	logger.Info("Starting server")
 
 	// Run server
-	if err := r.Run(":" + cfg.Server.Port); err != nil {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Fatal("Failed to start server")
 	}
+
+	// Wait for in-flight background jobs to finish
+	jobs.Wait()
+	logger.Info("Server stopped")
 }
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AgentHandler struct {
+	svc     *service.AgentService
+	routing *service.RoutingService
+	logger  *zap.Logger
+}
+
+func NewAgentHandler(svc *service.AgentService, routing *service.RoutingService, logger *zap.Logger) *AgentHandler {
+	return &AgentHandler{
+		svc:     svc,
+		routing: routing,
+		logger:  logger,
+	}
+}
+
+// List returns every support agent with their status and current load
+func (h *AgentHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	agents, err := h.svc.ListAgents(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, agents)
+}
+
+// Me returns the calling agent's routing profile
+func (h *AgentHandler) Me(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.svc.GetProfile(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *AgentHandler) SetStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required,oneof=online away offline"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.svc.SetStatus(userID, req.Status)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Agent status changed",
+		zap.Uint("agent_id", userID),
+		zap.String("status", profile.Status),
+	)
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// Heartbeat keeps the calling agent available; clients send it every 30 seconds
+func (h *AgentHandler) Heartbeat(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Heartbeat(userID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateCapacity sets an agent's maximum number of concurrent chats
+func (h *AgentHandler) UpdateCapacity(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	agentID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MaxChats int `json:"max_chats" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.svc.UpdateCapacity(userID, uint(agentID), req.MaxChats)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Agent capacity updated",
+		zap.Int64("agent_id", agentID),
+		zap.Int("max_chats", profile.MaxChats),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// SetRole changes a user's role (user, agent, supervisor or admin)
+func (h *AgentHandler) SetRole(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=user agent supervisor admin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.svc.SetRole(userID, uint(targetID), req.Role)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("User role changed",
+		zap.Uint("user_id", user.ID),
+		zap.String("role", user.Role),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":   user.ID,
+		"role": user.Role,
+	})
+}
+
+// Queue returns the support chats waiting for an agent, longest waiting first
+func (h *AgentHandler) Queue(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	queued, err := h.routing.ListQueue(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, queued)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Reports cover the last 30 days unless from and to are given
+const defaultAnalyticsReportDays = 30
+
+// AnalyticsHandler serves activity reports. Its routes are protected by
+// middleware.SupervisorMiddleware.
+type AnalyticsHandler struct {
+	svc    *service.AnalyticsService
+	logger *zap.Logger
+}
+
+func NewAnalyticsHandler(svc *service.AnalyticsService, logger *zap.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Report returns activity bucketed by hour, day or week in the requested
+// timezone, as JSON or, with format=csv, as a CSV file
+func (h *AnalyticsHandler) Report(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+	location, err := time.LoadLocation(c.DefaultQuery("timezone", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid timezone"})
+		return
+	}
+	from, to, ok := queryDateRangeIn(c, defaultAnalyticsReportDays, location)
+	if !ok {
+		return
+	}
+
+	buckets, err := h.svc.Report(service.ReportQuery{
+		From:     from,
+		To:       to,
+		Interval: c.DefaultQuery("interval", service.IntervalDay),
+		Location: location,
+	})
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, buckets)
+		return
+	}
+	writeReportCSV(c, buckets)
+}
+
+func writeReportCSV(c *gin.Context, buckets []*service.ReportBucket) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="report.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"start", "messages", "visitor_messages", "active_users", "conversations_started",
+		"support_chats_started", "support_chats_closed", "first_responses",
+		"average_first_response_seconds", "ratings", "average_rating", "csat",
+	})
+	for _, bucket := range buckets {
+		w.Write([]string{
+			bucket.Start.Format(time.RFC3339),
+			strconv.FormatInt(bucket.Messages, 10),
+			strconv.FormatInt(bucket.VisitorMessages, 10),
+			strconv.FormatInt(bucket.ActiveUsers, 10),
+			strconv.FormatInt(bucket.ConversationsStarted, 10),
+			strconv.FormatInt(bucket.SupportChatsStarted, 10),
+			strconv.FormatInt(bucket.SupportChatsClosed, 10),
+			strconv.FormatInt(bucket.FirstResponses, 10),
+			fmt.Sprintf("%.1f", bucket.AverageFirstResponseSeconds),
+			strconv.FormatInt(bucket.Ratings, 10),
+			fmt.Sprintf("%.2f", bucket.AverageRating),
+			fmt.Sprintf("%.4f", bucket.CSAT),
+		})
+	}
+	w.Flush()
+}
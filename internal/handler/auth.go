@@ -10,14 +10,18 @@ import (
 )
 
 type AuthHandler struct {
-	userSvc *service.UserService
-	logger  *zap.Logger
+	userSvc    *service.UserService
+	inviteSvc  *service.InviteService
+	visitorSvc *service.VisitorService
+	logger     *zap.Logger
 }
 
-func NewAuthHandler(userSvc *service.UserService, logger *zap.Logger) *AuthHandler {
+func NewAuthHandler(userSvc *service.UserService, inviteSvc *service.InviteService, visitorSvc *service.VisitorService, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
-		userSvc: userSvc,
-		logger:  logger,
+		userSvc:    userSvc,
+		inviteSvc:  inviteSvc,
+		visitorSvc: visitorSvc,
+		logger:     logger,
 	}
 }
 
@@ -27,6 +31,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6"`
+
+		// Optional widget session whose chat history moves to the new account
+		VisitorToken string `json:"visitor_token"`
+		// Optional token from an emailed group invitation to redeem
+		InviteToken string `json:"invite_token"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -54,6 +63,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Join the group of the emailed invitation the user registered from
+	if req.InviteToken != "" {
+		if _, err := h.inviteSvc.AcceptEmailInvite(user.ID, req.InviteToken); err != nil {
+			h.logger.Warn("Failed to accept email invite",
+				zap.String("error", err.Error()),
+				zap.Uint("user_id", user.ID),
+			)
+		}
+	}
+
+	// Link the website visitor session the user registered from
+	if req.VisitorToken != "" {
+		if _, err := h.visitorSvc.LinkToUser(req.VisitorToken, user.ID); err != nil {
+			h.logger.Warn("Failed to link visitor session",
+				zap.String("error", err.Error()),
+				zap.Uint("user_id", user.ID),
+			)
+		}
+	}
+
 	// Generate JWT token
 	token, err := util.GenerateToken(user.ID, user.Email)
 	if err != nil {
@@ -110,8 +139,8 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Verify password
-	if !util.CheckPassword(req.Password, user.Password) {
+	// Verify password; bot accounts have none and sign in with API keys only
+	if user.IsBot() || !util.CheckPassword(req.Password, user.Password) {
 		h.logger.Warn("Login failed - invalid password",
 			zap.String("email", req.Email),
 			zap.Uint("user_id", user.ID),
@@ -181,3 +210,35 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		"email": user.Email,
 	})
 }
+
+// UpdatePrivacy changes who may start a direct conversation with the current user
+func (h *AuthHandler) UpdatePrivacy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DMPrivacy string `json:"dm_privacy" binding:"required,oneof=everyone contacts"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userSvc.UpdateDMPrivacy(int64(userID), req.DMPrivacy)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("User privacy updated",
+		zap.Uint("user_id", user.ID),
+		zap.String("dm_privacy", user.DMPrivacy),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         user.ID,
+		"dm_privacy": user.DMPrivacy,
+	})
+}
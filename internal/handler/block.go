@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BlockHandler struct {
+	svc    *service.BlockService
+	logger *zap.Logger
+}
+
+func NewBlockHandler(svc *service.BlockService, logger *zap.Logger) *BlockHandler {
+	return &BlockHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+func (h *BlockHandler) Block(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	block, err := h.svc.Block(userID, req.UserID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("User blocked",
+		zap.Uint("blocker_id", userID),
+		zap.Uint("blocked_id", req.UserID),
+	)
+
+	c.JSON(http.StatusCreated, block)
+}
+
+func (h *BlockHandler) Unblock(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Unblock(userID, uint(targetID)); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user unblocked successfully"})
+}
+
+func (h *BlockHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	blocks, err := h.svc.ListBlocked(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BookmarkHandler struct {
+	svc    *service.BookmarkService
+	logger *zap.Logger
+}
+
+func NewBookmarkHandler(svc *service.BookmarkService, logger *zap.Logger) *BookmarkHandler {
+	return &BookmarkHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// List returns the current user's saved messages across all conversations
+func (h *BookmarkHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page number"})
+		return
+	}
+	pageSize, err := strconv.ParseInt(c.DefaultQuery("pageSize", "20"), 10, 32)
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid page size"})
+		return
+	}
+
+	bookmarks, err := h.svc.List(userID, int32(page), int32(pageSize))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":      page,
+		"pageSize":  pageSize,
+		"bookmarks": bookmarks,
+	})
+}
+
+func (h *BookmarkHandler) Add(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MessageID int64  `json:"message_id" binding:"required,min=1"`
+		Note      string `json:"note" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookmark, err := h.svc.Add(userID, req.MessageID, req.Note)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, bookmark)
+}
+
+func (h *BookmarkHandler) Remove(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	messageID, ok := paramID(c, "messageId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Remove(userID, messageID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "bookmark removed successfully"})
+}
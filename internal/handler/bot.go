@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"go_starter/internal/model"
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BotHandler struct {
+	svc    *service.BotService
+	logger *zap.Logger
+}
+
+func NewBotHandler(svc *service.BotService, logger *zap.Logger) *BotHandler {
+	return &BotHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// botFlowRequest is the body of create and update requests
+type botFlowRequest struct {
+	Name         string                  `json:"name" binding:"required,max=100"`
+	DepartmentID *uint                   `json:"department_id"`
+	Enabled      bool                    `json:"enabled"`
+	Definition   model.BotFlowDefinition `json:"definition"`
+}
+
+func (r botFlowRequest) input() service.BotFlowInput {
+	return service.BotFlowInput{
+		Name:         r.Name,
+		DepartmentID: r.DepartmentID,
+		Enabled:      r.Enabled,
+		Definition:   r.Definition,
+	}
+}
+
+func (h *BotHandler) ListFlows(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	flows, err := h.svc.ListFlows(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, flows)
+}
+
+func (h *BotHandler) GetFlow(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	flow, err := h.svc.GetFlow(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, flow)
+}
+
+// CreateFlow saves a bot flow, for the department given or as the default flow
+func (h *BotHandler) CreateFlow(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req botFlowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, err := h.svc.CreateFlow(userID, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Bot flow created",
+		zap.Uint("flow_id", flow.ID),
+		zap.Bool("enabled", flow.Enabled),
+	)
+
+	c.JSON(http.StatusCreated, flow)
+}
+
+func (h *BotHandler) UpdateFlow(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req botFlowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, err := h.svc.UpdateFlow(userID, id, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, flow)
+}
+
+func (h *BotHandler) DeleteFlow(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteFlow(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
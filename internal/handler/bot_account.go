@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BotAccountHandler struct {
+	svc    *service.BotAccountService
+	logger *zap.Logger
+}
+
+func NewBotAccountHandler(svc *service.BotAccountService, logger *zap.Logger) *BotAccountHandler {
+	return &BotAccountHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// botResponse is the public view of a bot user
+func botResponse(id uint, name string) gin.H {
+	return gin.H{"id": id, "name": name}
+}
+
+func (h *BotAccountHandler) ListBots(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	bots, err := h.svc.ListBots(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	response := make([]gin.H, 0, len(bots))
+	for _, bot := range bots {
+		response = append(response, botResponse(bot.ID, bot.Name))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateBot adds a bot user that integrations post as
+func (h *BotAccountHandler) CreateBot(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" binding:"required,max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bot, err := h.svc.CreateBot(userID, req.Name)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Bot created",
+		zap.Uint("bot_id", bot.ID),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, botResponse(bot.ID, bot.Name))
+}
+
+func (h *BotAccountHandler) ListKeys(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	keys, err := h.svc.ListKeys(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateKey issues an API key for the bot. The key is only ever returned here.
+func (h *BotAccountHandler) CreateKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      string     `json:"name" binding:"required,max=100"`
+		Scopes    []string   `json:"scopes" binding:"required"`
+		RateLimit int        `json:"rate_limit" binding:"min=0"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, secret, err := h.svc.CreateKey(userID, id, service.APIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		RateLimit: req.RateLimit,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("API key issued",
+		zap.Uint("key_id", key.ID),
+		zap.Uint("bot_id", key.BotID),
+		zap.Strings("scopes", key.Scopes),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     secret,
+		"api_key": key,
+	})
+}
+
+func (h *BotAccountHandler) RevokeKey(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	keyID, ok := paramID(c, "keyId")
+	if !ok {
+		return
+	}
+
+	key, err := h.svc.RevokeKey(userID, id, keyID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("API key revoked",
+		zap.Uint("key_id", key.ID),
+		zap.Uint("bot_id", key.BotID),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CannedResponseHandler struct {
+	svc    *service.CannedResponseService
+	logger *zap.Logger
+}
+
+func NewCannedResponseHandler(svc *service.CannedResponseService, logger *zap.Logger) *CannedResponseHandler {
+	return &CannedResponseHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// cannedResponseRequest is the body of create and update requests
+type cannedResponseRequest struct {
+	Shortcut string `json:"shortcut" binding:"required,max=51"`
+	Title    string `json:"title" binding:"max=100"`
+	Content  string `json:"content" binding:"required,max=4000"`
+	Shared   bool   `json:"shared"`
+}
+
+func (r cannedResponseRequest) input() service.CannedResponseInput {
+	return service.CannedResponseInput{
+		Shortcut: r.Shortcut,
+		Title:    r.Title,
+		Content:  r.Content,
+		Shared:   r.Shared,
+	}
+}
+
+// List returns the caller's personal and the shared canned responses, searched by shortcut with ?q=
+func (h *CannedResponseHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	responses, err := h.svc.List(userID, c.Query("q"))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses)
+}
+
+func (h *CannedResponseHandler) GetById(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	response, err := h.svc.Get(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CannedResponseHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req cannedResponseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.svc.Create(userID, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Canned response created",
+		zap.Uint("canned_response_id", response.ID),
+		zap.String("shortcut", response.Shortcut),
+		zap.Bool("shared", response.IsShared()),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *CannedResponseHandler) Update(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req cannedResponseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.svc.Update(userID, id, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CannedResponseHandler) Delete(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Preview renders a canned response for the conversation given with ?conversation_id=
+func (h *CannedResponseHandler) Preview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	conversationID, err := strconv.ParseInt(c.Query("conversation_id"), 10, 64)
+	if err != nil || conversationID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation id"})
+		return
+	}
+
+	content, err := h.svc.Preview(userID, id, conversationID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"content": content})
+}
+
+// Send posts a canned response, chosen by id or shortcut, into the conversation
+// with its placeholders filled in
+func (h *CannedResponseHandler) Send(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CannedResponseID int64  `json:"canned_response_id" binding:"required_without=Shortcut"`
+		Shortcut         string `json:"shortcut" binding:"required_without=CannedResponseID,max=51"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.Send(userID, id, req.CannedResponseID, req.Shortcut)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CollaborationHandler struct {
+	svc    *service.CollaborationService
+	logger *zap.Logger
+}
+
+func NewCollaborationHandler(svc *service.CollaborationService, logger *zap.Logger) *CollaborationHandler {
+	return &CollaborationHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Handoff transfers a live support chat to another agent or department
+func (h *CollaborationHandler) Handoff(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AgentID      *uint  `json:"agent_id"`
+		DepartmentID *uint  `json:"department_id"`
+		Note         string `json:"note" binding:"max=2000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.Transfer(userID, id, service.TransferRequest{
+		AgentID:      req.AgentID,
+		DepartmentID: req.DepartmentID,
+		Note:         req.Note,
+	})
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Support chat transferred",
+		zap.Int64("conversation_id", id),
+		zap.Uint("actor_id", userID),
+	)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// InviteCollaborator brings another agent into a support chat to observe or join
+func (h *CollaborationHandler) InviteCollaborator(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint   `json:"user_id" binding:"required"`
+		Mode   string `json:"mode" binding:"required,oneof=observe join"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.svc.InviteCollaborator(userID, id, req.UserID, req.Mode)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Collaborator added to support chat",
+		zap.Int64("conversation_id", id),
+		zap.Uint("user_id", req.UserID),
+		zap.String("mode", req.Mode),
+	)
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *CollaborationHandler) RemoveCollaborator(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RemoveCollaborator(userID, id, uint(targetID)); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "collaborator removed successfully"})
+}
+
+// Whisper posts an internal note only the agents in the chat can see
+func (h *CollaborationHandler) Whisper(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required,max=4000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.Whisper(userID, id, req.Content)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CommandHandler struct {
+	svc    *service.CommandService
+	logger *zap.Logger
+}
+
+func NewCommandHandler(svc *service.CommandService, logger *zap.Logger) *CommandHandler {
+	return &CommandHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// slashCommandRequest is the body of create and update requests
+type slashCommandRequest struct {
+	Name        string `json:"name" binding:"required,max=33"`
+	Description string `json:"description" binding:"max=255"`
+	UsageHint   string `json:"usage_hint" binding:"max=100"`
+	URL         string `json:"url" binding:"required,max=500"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+func (r slashCommandRequest) input() service.SlashCommandInput {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return service.SlashCommandInput{
+		Name:        r.Name,
+		Description: r.Description,
+		UsageHint:   r.UsageHint,
+		URL:         r.URL,
+		Enabled:     enabled,
+	}
+}
+
+// Available lists the commands users can run, for autocompletion
+func (h *CommandHandler) Available(c *gin.Context) {
+	if _, ok := currentUserID(c); !ok {
+		return
+	}
+
+	commands, err := h.svc.Available()
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, commands)
+}
+
+func (h *CommandHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	commands, err := h.svc.ListCommands(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, commands)
+}
+
+// Create registers an external command. The signing secret is only returned here.
+func (h *CommandHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req slashCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	command, secret, err := h.svc.CreateCommand(userID, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Slash command registered",
+		zap.Uint("command_id", command.ID),
+		zap.String("name", command.Name),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"command": command,
+		"secret":  secret,
+	})
+}
+
+func (h *CommandHandler) Update(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req slashCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	command, err := h.svc.UpdateCommand(userID, id, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, command)
+}
+
+func (h *CommandHandler) Delete(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteCommand(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RotateSecret replaces the command's signing secret and returns the new one
+func (h *CommandHandler) RotateSecret(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	secret, err := h.svc.RotateSecret(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Slash command secret rotated",
+		zap.Int64("command_id", id),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
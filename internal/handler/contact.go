@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ContactHandler struct {
+	svc    *service.ContactService
+	logger *zap.Logger
+}
+
+func NewContactHandler(svc *service.ContactService, logger *zap.Logger) *ContactHandler {
+	return &ContactHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// List returns the current user's contacts with their presence
+func (h *ContactHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	contacts, err := h.svc.ListContacts(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, contacts)
+}
+
+func (h *ContactHandler) Remove(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	contactID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RemoveContact(userID, uint(contactID)); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "contact removed successfully"})
+}
+
+// Mutual returns the contacts shared between the current user and another user
+func (h *ContactHandler) Mutual(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	otherID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	contacts, err := h.svc.MutualContacts(userID, uint(otherID))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, contacts)
+}
+
+func (h *ContactHandler) SendRequest(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.svc.SendRequest(userID, req.UserID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// ListRequests returns pending requests; direction is incoming (default) or outgoing
+func (h *ContactHandler) ListRequests(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	direction := c.DefaultQuery("direction", "incoming")
+	if direction != "incoming" && direction != "outgoing" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be incoming or outgoing"})
+		return
+	}
+
+	list := h.svc.ListIncoming
+	if direction == "outgoing" {
+		list = h.svc.ListOutgoing
+	}
+	requests, err := list(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+func (h *ContactHandler) AcceptRequest(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "requestId")
+	if !ok {
+		return
+	}
+
+	request, err := h.svc.AcceptRequest(userID, requestID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *ContactHandler) DeclineRequest(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "requestId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeclineRequest(userID, requestID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "contact request declined"})
+}
+
+func (h *ContactHandler) CancelRequest(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "requestId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.CancelRequest(userID, requestID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "contact request cancelled"})
+}
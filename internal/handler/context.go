@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserID returns the user ID set by AuthMiddleware, responding with 401 when it is missing
+func currentUserID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("user_id")
+	userID, ok := value.(uint)
+	if !exists || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// isBotRequest reports whether the request was authenticated with a bot API key
+func isBotRequest(c *gin.Context) bool {
+	_, exists := c.Get("api_key_id")
+	return exists
+}
+
+// paramID parses a positive numeric path parameter, responding with 400 when it is invalid
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// currentVisitorID returns the visitor ID set by VisitorMiddleware, responding with 401 when it is missing
+func currentVisitorID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get("visitor_id")
+	visitorID, ok := value.(uint)
+	if !exists || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	return visitorID, true
+}
+
+// queryID parses an optional positive numeric query parameter, responding with 400
+// when it is invalid. It returns nil when the parameter is absent.
+func queryID(c *gin.Context, name string) (*uint, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || parsed == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return nil, false
+	}
+	id := uint(parsed)
+	return &id, true
+}
+
+// queryDateRange parses the from and to query parameters, either RFC 3339 times or
+// YYYY-MM-DD dates, responding with 400 when they are invalid. A date for to
+// includes that whole day. The range defaults to the last defaultDays days.
+func queryDateRange(c *gin.Context, defaultDays int) (time.Time, time.Time, bool) {
+	return queryDateRangeIn(c, defaultDays, time.UTC)
+}
+
+// queryDateRangeIn is queryDateRange with dates taken as midnight in the location
+func queryDateRangeIn(c *gin.Context, defaultDays int, location *time.Location) (time.Time, time.Time, bool) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -defaultDays)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := parseQueryTime(raw, false, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := parseQueryTime(raw, true, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	return from, to, true
+}
+
+func parseQueryTime(raw string, endOfDay bool, location *time.Location) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", raw, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,496 @@
+package handler
+
+import (
+	"go_starter/internal/model"
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ConversationHandler struct {
+	svc      *service.ConversationService
+	commands *service.CommandService
+	logger   *zap.Logger
+}
+
+func NewConversationHandler(svc *service.ConversationService, commands *service.CommandService, logger *zap.Logger) *ConversationHandler {
+	return &ConversationHandler{
+		svc:      svc,
+		commands: commands,
+		logger:   logger,
+	}
+}
+
+// CreateDirect opens (or returns) a one-to-one conversation with another user
+func (h *ConversationHandler) CreateDirect(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.CreateDirect(userID, req.UserID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// CreateGroup creates a group conversation owned by the caller
+func (h *ConversationHandler) CreateGroup(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      string `json:"name" binding:"required,max=100"`
+		AvatarURL string `json:"avatar_url" binding:"omitempty,url,max=255"`
+		MemberIDs []uint `json:"member_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.CreateGroup(userID, req.Name, req.AvatarURL, req.MemberIDs)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Group conversation created",
+		zap.Uint("conversation_id", conversation.ID),
+		zap.Uint("owner_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// List returns the current user's conversations; ?archived=true lists the archive instead
+func (h *ConversationHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	archived := c.DefaultQuery("archived", "false") == "true"
+
+	conversations, err := h.svc.ListConversations(userID, archived)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+func (h *ConversationHandler) GetById(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	conversation, members, err := h.svc.GetConversation(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation": conversation,
+		"members":      members,
+	})
+}
+
+// Update renames the group and/or changes its avatar
+func (h *ConversationHandler) Update(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      *string `json:"name" binding:"omitempty,min=1,max=100"`
+		AvatarURL *string `json:"avatar_url" binding:"omitempty,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.UpdateGroup(userID, id, req.Name, req.AvatarURL)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// SetDisappearing sets how many seconds new messages live, or turns disappearing
+// messages off with 0
+func (h *ConversationHandler) SetDisappearing(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TTLSeconds *int `json:"ttl_seconds" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.SetMessageTTL(userID, id, time.Duration(*req.TTLSeconds)*time.Second)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+func (h *ConversationHandler) ListMessages(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	messages, err := h.svc.ListMessages(userID, id, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *ConversationHandler) SendMessage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Messages starting with a slash command run the command instead of being sent.
+	// Bot keys are scoped to posting messages, so their text is always sent as is.
+	content := req.Content
+	if !isBotRequest(c) {
+		if name, args, isCommand := service.ParseCommand(content); isCommand {
+			result, err := h.commands.Execute(userID, id, name, args)
+			if err != nil {
+				writeServiceError(c, h.logger, err)
+				return
+			}
+			c.JSON(http.StatusOK, result)
+			return
+		}
+		unescaped, err := h.commands.Unescape(content)
+		if err != nil {
+			writeServiceError(c, h.logger, err)
+			return
+		}
+		content = unescaped
+	}
+
+	message, err := h.svc.SendMessage(userID, id, content)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// Typing signals that the current user is typing in the conversation
+func (h *ConversationHandler) Typing(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Typing(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ConversationHandler) ListMembers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	members, err := h.svc.ListMembers(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *ConversationHandler) AddMembers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserIDs []uint `json:"user_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	members, err := h.svc.AddMembers(userID, id, req.UserIDs)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, members)
+}
+
+func (h *ConversationHandler) RemoveMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RemoveMember(userID, id, uint(targetID)); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed successfully"})
+}
+
+// ChangeRole promotes a member to admin or demotes an admin back to member
+func (h *ConversationHandler) ChangeRole(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=admin member"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.svc.ChangeRole(userID, id, uint(targetID), req.Role)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *ConversationHandler) Leave(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Leave(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "left conversation successfully"})
+}
+
+func (h *ConversationHandler) TransferOwnership(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.TransferOwnership(userID, id, req.UserID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Conversation ownership transferred",
+		zap.Int64("conversation_id", id),
+		zap.Uint("from_user_id", userID),
+		zap.Uint("to_user_id", req.UserID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"message": "ownership transferred successfully"})
+}
+
+// Mute silences notifications until the optional "until" time, or forever when omitted
+func (h *ConversationHandler) Mute(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Until *time.Time `json:"until"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	member, err := h.svc.Mute(userID, id, req.Until)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *ConversationHandler) Unmute(c *gin.Context) {
+	h.updateSettings(c, h.svc.Unmute)
+}
+
+func (h *ConversationHandler) Pin(c *gin.Context) {
+	h.updateSettings(c, h.svc.Pin)
+}
+
+func (h *ConversationHandler) Unpin(c *gin.Context) {
+	h.updateSettings(c, h.svc.Unpin)
+}
+
+func (h *ConversationHandler) Archive(c *gin.Context) {
+	h.updateSettings(c, h.svc.Archive)
+}
+
+func (h *ConversationHandler) Unarchive(c *gin.Context) {
+	h.updateSettings(c, h.svc.Unarchive)
+}
+
+// updateSettings runs a body-less per-member settings change for the current user
+func (h *ConversationHandler) updateSettings(c *gin.Context, update func(userId uint, conversationId int64) (*model.ConversationMember, error)) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	member, err := update(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DepartmentHandler struct {
+	svc    *service.DepartmentService
+	logger *zap.Logger
+}
+
+func NewDepartmentHandler(svc *service.DepartmentService, logger *zap.Logger) *DepartmentHandler {
+	return &DepartmentHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// departmentRequest is the body of create and update requests
+type departmentRequest struct {
+	Name                 string `json:"name" binding:"required,max=100"`
+	Description          string `json:"description" binding:"max=255"`
+	FallbackDepartmentID *uint  `json:"fallback_department_id"`
+	OverflowAfterSeconds int    `json:"overflow_after_seconds" binding:"min=0"`
+	Email                string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+func (r departmentRequest) input() service.DepartmentInput {
+	return service.DepartmentInput{
+		Name:                 r.Name,
+		Description:          r.Description,
+		FallbackDepartmentID: r.FallbackDepartmentID,
+		OverflowAfterSeconds: r.OverflowAfterSeconds,
+		Email:                r.Email,
+	}
+}
+
+func (h *DepartmentHandler) List(c *gin.Context) {
+	departments, err := h.svc.List()
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, departments)
+}
+
+// ListPublic returns the departments a visitor can choose from in the widget
+func (h *DepartmentHandler) ListPublic(c *gin.Context) {
+	departments, err := h.svc.ListPublic()
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, departments)
+}
+
+func (h *DepartmentHandler) GetById(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	department, err := h.svc.Get(id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, department)
+}
+
+func (h *DepartmentHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req departmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	department, err := h.svc.Create(userID, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Department created",
+		zap.Uint("department_id", department.ID),
+		zap.String("name", department.Name),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, department)
+}
+
+func (h *DepartmentHandler) Update(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req departmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	department, err := h.svc.Update(userID, id, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, department)
+}
+
+func (h *DepartmentHandler) Delete(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Department deleted",
+		zap.Int64("department_id", id),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"message": "department deleted successfully"})
+}
+
+func (h *DepartmentHandler) AddAgent(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agent, err := h.svc.AddAgent(userID, id, req.UserID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, agent)
+}
+
+func (h *DepartmentHandler) RemoveAgent(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	agentID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RemoveAgent(userID, id, uint(agentID)); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DepartmentHandler) AddRule(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PageURLContains string `json:"page_url_contains" binding:"max=255"`
+		Language        string `json:"language" binding:"max=10"`
+		Priority        int    `json:"priority"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.svc.AddRule(userID, id, req.PageURLContains, req.Language, req.Priority)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *DepartmentHandler) DeleteRule(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	ruleID, ok := paramID(c, "ruleId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteRule(userID, id, ruleID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetHours replaces the department's timezone and weekly business hours
+func (h *DepartmentHandler) SetHours(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Timezone string `json:"timezone" binding:"max=64"`
+		Hours    []struct {
+			Weekday  int    `json:"weekday" binding:"min=0,max=6"`
+			OpensAt  string `json:"opens_at" binding:"required"`
+			ClosesAt string `json:"closes_at" binding:"required"`
+		} `json:"hours" binding:"max=50,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hours := make([]service.BusinessHoursInput, 0, len(req.Hours))
+	for _, window := range req.Hours {
+		hours = append(hours, service.BusinessHoursInput{Weekday: window.Weekday, OpensAt: window.OpensAt, ClosesAt: window.ClosesAt})
+	}
+	department, err := h.svc.SetHours(userID, id, req.Timezone, hours)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Department business hours updated",
+		zap.Int64("department_id", id),
+		zap.String("timezone", department.Timezone),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, department)
+}
+
+func (h *DepartmentHandler) AddHoliday(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Date string `json:"date" binding:"required"`
+		Name string `json:"name" binding:"max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	holiday, err := h.svc.AddHoliday(userID, id, req.Date, req.Name)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+func (h *DepartmentHandler) DeleteHoliday(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	holidayID, ok := paramID(c, "holidayId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteHoliday(userID, id, holidayID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Availability tells the widget whether support is open, for one department when
+// department_id is given or for the general queue otherwise
+func (h *DepartmentHandler) Availability(c *gin.Context) {
+	departmentID, ok := queryID(c, "department_id")
+	if !ok {
+		return
+	}
+
+	availability, err := h.svc.Availability(departmentID, time.Now())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
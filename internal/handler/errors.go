@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"errors"
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// serviceErrorStatus maps service sentinel errors to HTTP status codes
+var serviceErrorStatus = map[error]int{
+	service.ErrConversationNotFound: http.StatusNotFound,
+	service.ErrUserNotFound:         http.StatusNotFound,
+	service.ErrTargetNotMember:      http.StatusNotFound,
+	service.ErrNotMember:            http.StatusForbidden,
+	service.ErrForbidden:            http.StatusForbidden,
+	service.ErrNotGroup:             http.StatusBadRequest,
+	service.ErrInvalidRole:          http.StatusBadRequest,
+	service.ErrCannotTargetSelf:     http.StatusBadRequest,
+	service.ErrEmptyMessage:         http.StatusBadRequest,
+	service.ErrInvalidMuteUntil:     http.StatusBadRequest,
+	service.ErrInvalidTopic:         http.StatusBadRequest,
+	service.ErrMessageNotFound:      http.StatusNotFound,
+	service.ErrAlreadyMember:        http.StatusConflict,
+	service.ErrOwnerMustTransfer:    http.StatusConflict,
+
+	service.ErrInviteNotFound:        http.StatusNotFound,
+	service.ErrJoinRequestNotFound:   http.StatusNotFound,
+	service.ErrInviteInvalid:         http.StatusGone,
+	service.ErrInvalidInviteSettings: http.StatusBadRequest,
+	service.ErrJoinRequestPending:    http.StatusConflict,
+	service.ErrJoinRequestReviewed:   http.StatusConflict,
+
+	service.ErrBlocked:        http.StatusForbidden,
+	service.ErrNotBlocked:     http.StatusNotFound,
+	service.ErrAlreadyBlocked: http.StatusConflict,
+
+	service.ErrDMNotAllowed:           http.StatusForbidden,
+	service.ErrInvalidPrivacy:         http.StatusBadRequest,
+	service.ErrContactRequestNotFound: http.StatusNotFound,
+	service.ErrNotContact:             http.StatusNotFound,
+	service.ErrContactRequestExists:   http.StatusConflict,
+	service.ErrContactRequestClosed:   http.StatusConflict,
+	service.ErrAlreadyContact:         http.StatusConflict,
+
+	service.ErrNotPinned:         http.StatusNotFound,
+	service.ErrNotBookmarked:     http.StatusNotFound,
+	service.ErrCannotPinEvent:    http.StatusBadRequest,
+	service.ErrAlreadyPinned:     http.StatusConflict,
+	service.ErrPinLimit:          http.StatusConflict,
+	service.ErrAlreadyBookmarked: http.StatusConflict,
+
+	service.ErrScheduledNotFound:   http.StatusNotFound,
+	service.ErrSendAtInPast:        http.StatusBadRequest,
+	service.ErrScheduledNotPending: http.StatusConflict,
+
+	service.ErrVisitorNotFound:    http.StatusNotFound,
+	service.ErrNoOpenChat:         http.StatusNotFound,
+	service.ErrNotSupportChat:     http.StatusBadRequest,
+	service.ErrInvalidVisitorLink: http.StatusBadRequest,
+	service.ErrVisitorLinked:      http.StatusConflict,
+	service.ErrConversationClosed: http.StatusConflict,
+
+	service.ErrNotAgent:           http.StatusForbidden,
+	service.ErrInvalidAgentStatus: http.StatusBadRequest,
+	service.ErrInvalidMaxChats:    http.StatusBadRequest,
+	service.ErrInvalidUserRole:    http.StatusBadRequest,
+
+	service.ErrDepartmentNotFound:     http.StatusNotFound,
+	service.ErrNotDepartmentAgent:     http.StatusNotFound,
+	service.ErrDepartmentRuleNotFound: http.StatusNotFound,
+	service.ErrInvalidFallback:        http.StatusBadRequest,
+	service.ErrInvalidOverflow:        http.StatusBadRequest,
+	service.ErrEmptyDepartmentRule:    http.StatusBadRequest,
+	service.ErrDepartmentExists:       http.StatusConflict,
+	service.ErrAlreadyDepartmentAgent: http.StatusConflict,
+
+	service.ErrInvalidTransfer:         http.StatusBadRequest,
+	service.ErrInvalidCollaboratorMode: http.StatusBadRequest,
+	service.ErrChatNotActive:           http.StatusConflict,
+	service.ErrAgentUnavailable:        http.StatusConflict,
+
+	service.ErrInvalidTimezone:        http.StatusBadRequest,
+	service.ErrInvalidBusinessHours:   http.StatusBadRequest,
+	service.ErrInvalidHolidayDate:     http.StatusBadRequest,
+	service.ErrInvalidOfflineStatus:   http.StatusBadRequest,
+	service.ErrHolidayNotFound:        http.StatusNotFound,
+	service.ErrOfflineMessageNotFound: http.StatusNotFound,
+	service.ErrHolidayExists:          http.StatusConflict,
+	service.ErrSupportOffline:         http.StatusConflict,
+
+	service.ErrCannedResponseNotFound: http.StatusNotFound,
+	service.ErrInvalidShortcut:        http.StatusBadRequest,
+	service.ErrEmptyCannedResponse:    http.StatusBadRequest,
+	service.ErrShortcutTaken:          http.StatusConflict,
+
+	service.ErrInvalidScore:      http.StatusBadRequest,
+	service.ErrInvalidRatingLink: http.StatusBadRequest,
+	service.ErrInvalidDateRange:  http.StatusBadRequest,
+	service.ErrChatNotClosed:     http.StatusConflict,
+	service.ErrAlreadyRated:      http.StatusConflict,
+
+	service.ErrInvalidTranscriptFormat: http.StatusBadRequest,
+	service.ErrInvalidDownloadLink:     http.StatusBadRequest,
+	service.ErrNoTranscriptEmail:       http.StatusBadRequest,
+	service.ErrExportNotFound:          http.StatusNotFound,
+	service.ErrExportNotReady:          http.StatusConflict,
+
+	service.ErrSLAPolicyNotFound: http.StatusNotFound,
+	service.ErrInvalidPriority:   http.StatusBadRequest,
+	service.ErrInvalidSLATargets: http.StatusBadRequest,
+	service.ErrInvalidSLAWarning: http.StatusBadRequest,
+	service.ErrInvalidSLAMetric:  http.StatusBadRequest,
+	service.ErrSLAPolicyExists:   http.StatusConflict,
+
+	service.ErrInvalidInterval:    http.StatusBadRequest,
+	service.ErrHourlyRangeTooLong: http.StatusBadRequest,
+
+	service.ErrBotFlowNotFound:   http.StatusNotFound,
+	service.ErrInvalidBotFlow:    http.StatusBadRequest,
+	service.ErrInvalidBotPattern: http.StatusBadRequest,
+	service.ErrBotFlowEnabled:    http.StatusConflict,
+	service.ErrNotWithBot:        http.StatusConflict,
+
+	service.ErrBotNotFound:       http.StatusNotFound,
+	service.ErrAPIKeyNotFound:    http.StatusNotFound,
+	service.ErrInvalidBotName:    http.StatusBadRequest,
+	service.ErrInvalidAPIKeyName: http.StatusBadRequest,
+	service.ErrInvalidScopes:     http.StatusBadRequest,
+	service.ErrInvalidRateLimit:  http.StatusBadRequest,
+	service.ErrInvalidKeyExpiry:  http.StatusBadRequest,
+	service.ErrBotAccount:        http.StatusBadRequest,
+	service.ErrAPIKeyRevoked:     http.StatusConflict,
+
+	service.ErrWebhookNotFound:      http.StatusNotFound,
+	service.ErrDeliveryNotFound:     http.StatusNotFound,
+	service.ErrInvalidWebhookURL:    http.StatusBadRequest,
+	service.ErrInvalidWebhookEvents: http.StatusBadRequest,
+	service.ErrDeliveryPending:      http.StatusConflict,
+
+	service.ErrIncomingWebhookNotFound:    http.StatusNotFound,
+	service.ErrIncomingWebhookRevoked:     http.StatusConflict,
+	service.ErrIncomingWebhooksSupport:    http.StatusBadRequest,
+	service.ErrInvalidIncomingWebhookName: http.StatusBadRequest,
+	service.ErrInvalidIncomingRateLimit:   http.StatusBadRequest,
+	service.ErrInvalidWebhookPayload:      http.StatusBadRequest,
+	service.ErrInvalidAttachment:          http.StatusBadRequest,
+	service.ErrWebhookRateLimited:         http.StatusTooManyRequests,
+
+	service.ErrUnknownCommand:       http.StatusBadRequest,
+	service.ErrSlashCommandNotFound: http.StatusNotFound,
+	service.ErrInvalidCommandName:   http.StatusBadRequest,
+	service.ErrCommandNameTaken:     http.StatusConflict,
+	service.ErrInvalidCommandURL:    http.StatusBadRequest,
+	service.ErrCommandFailed:        http.StatusBadGateway,
+
+	service.ErrPollNotFound:     http.StatusNotFound,
+	service.ErrPollClosed:       http.StatusConflict,
+	service.ErrInvalidPoll:      http.StatusBadRequest,
+	service.ErrInvalidPollClose: http.StatusBadRequest,
+	service.ErrInvalidVote:      http.StatusBadRequest,
+	service.ErrPollsUnsupported: http.StatusBadRequest,
+
+	service.ErrInvalidMessageTTL:       http.StatusBadRequest,
+	service.ErrRetentionPolicyNotFound: http.StatusNotFound,
+	service.ErrInvalidRetentionPolicy:  http.StatusBadRequest,
+}
+
+// writeServiceError responds with the status mapped to err, or logs it and
+// responds with a generic 500 when the error is unexpected
+func writeServiceError(c *gin.Context, logger *zap.Logger, err error) {
+	for target, status := range serviceErrorStatus {
+		if errors.Is(err, target) {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	logger.Error("Unexpected service error",
+		zap.String("error", err.Error()),
+		zap.String("path", c.FullPath()),
+	)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"go_starter/internal/realtime"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const eventStreamKeepAlive = 25 * time.Second
+
+type EventHandler struct {
+	hub    *realtime.Hub
+	logger *zap.Logger
+}
+
+func NewEventHandler(hub *realtime.Hub, logger *zap.Logger) *EventHandler {
+	return &EventHandler{
+		hub:    hub,
+		logger: logger,
+	}
+}
+
+// Stream pushes real-time events for the current user over Server-Sent Events
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	streamTopic(c, h.hub, realtime.UserTopic(userID))
+	h.logger.Debug("Event stream closed", zap.Uint("user_id", userID))
+}
+
+// streamTopic relays events from a hub topic to the client until it disconnects
+func streamTopic(c *gin.Context, hub *realtime.Hub, topic string) {
+	events, unsubscribe := hub.Subscribe(topic)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(eventStreamKeepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-ticker.C:
+			c.SSEvent("ping", gin.H{"time": time.Now().Unix()})
+			return true
+		}
+	})
+}
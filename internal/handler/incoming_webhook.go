@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"go_starter/internal/model"
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxWebhookPayloadBytes caps the body of incoming webhook posts, which need no sign-in
+const maxWebhookPayloadBytes = 64 << 10
+
+type IncomingWebhookHandler struct {
+	svc    *service.IncomingWebhookService
+	logger *zap.Logger
+}
+
+func NewIncomingWebhookHandler(svc *service.IncomingWebhookService, logger *zap.Logger) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+func (h *IncomingWebhookHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	hooks, err := h.svc.List(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+// Create adds an incoming webhook to a conversation. Its URL is only returned here
+// and when the token is regenerated.
+func (h *IncomingWebhookHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name      string `json:"name" binding:"required,max=100"`
+		RateLimit int    `json:"rate_limit" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, url, err := h.svc.Create(userID, id, req.Name, req.RateLimit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Incoming webhook created",
+		zap.Int64("conversation_id", id),
+		zap.Uint("incoming_webhook_id", hook.ID),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"url":     url,
+		"webhook": hook,
+	})
+}
+
+// RegenerateToken replaces the webhook's URL and returns the new one
+func (h *IncomingWebhookHandler) RegenerateToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	hookID, ok := paramID(c, "hookId")
+	if !ok {
+		return
+	}
+
+	url, err := h.svc.RegenerateToken(userID, id, hookID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Incoming webhook token regenerated",
+		zap.Int64("conversation_id", id),
+		zap.Int64("incoming_webhook_id", hookID),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"url": url})
+}
+
+func (h *IncomingWebhookHandler) Revoke(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	hookID, ok := paramID(c, "hookId")
+	if !ok {
+		return
+	}
+
+	hook, err := h.svc.Revoke(userID, id, hookID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Incoming webhook revoked",
+		zap.Int64("conversation_id", id),
+		zap.Int64("incoming_webhook_id", hookID),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, hook)
+}
+
+// Post publishes a message sent by an external system to a webhook URL. The
+// token in the URL is the only credential.
+func (h *IncomingWebhookHandler) Post(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookPayloadBytes)
+
+	var req struct {
+		Text        string                    `json:"text" binding:"max=4000"`
+		Username    string                    `json:"username" binding:"max=100"`
+		Attachments []model.MessageAttachment `json:"attachments" binding:"max=10"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	message, err := h.svc.Post(c.Param("token"), service.IncomingWebhookPayload{
+		Text:        req.Text,
+		Username:    req.Username,
+		Attachments: req.Attachments,
+	}, now)
+	if errors.Is(err, service.ErrWebhookRateLimited) {
+		c.Header("Retry-After", strconv.Itoa(int(h.svc.RetryAfter(now).Seconds())+1))
+	}
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type InviteHandler struct {
+	svc    *service.InviteService
+	logger *zap.Logger
+}
+
+func NewInviteHandler(svc *service.InviteService, logger *zap.Logger) *InviteHandler {
+	return &InviteHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Create generates an invite link for a group
+func (h *InviteHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ExpiresInHours   int  `json:"expires_in_hours" binding:"min=0"`
+		MaxUses          int  `json:"max_uses" binding:"min=0"`
+		RequiresApproval bool `json:"requires_approval"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invite, err := h.svc.CreateInvite(userID, id, time.Duration(req.ExpiresInHours)*time.Hour, req.MaxUses, req.RequiresApproval)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Invite link created",
+		zap.Int64("conversation_id", id),
+		zap.Uint("invite_id", invite.ID),
+		zap.Uint("created_by", userID),
+	)
+
+	c.JSON(http.StatusCreated, invite)
+}
+
+func (h *InviteHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	invites, err := h.svc.ListInvites(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func (h *InviteHandler) Revoke(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	inviteID, ok := paramID(c, "inviteId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RevokeInvite(userID, id, inviteID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invite revoked successfully"})
+}
+
+// Preview shows which group an invite code leads to
+func (h *InviteHandler) Preview(c *gin.Context) {
+	invite, conversation, memberCount, err := h.svc.PreviewInvite(c.Param("code"))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id":   conversation.ID,
+		"name":              conversation.Name,
+		"avatar_url":        conversation.AvatarURL,
+		"member_count":      memberCount,
+		"requires_approval": invite.RequiresApproval,
+		"expires_at":        invite.ExpiresAt,
+	})
+}
+
+// Join redeems an invite code for the current user
+func (h *InviteHandler) Join(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.svc.JoinByCode(userID, c.Param("code"))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	if result.JoinRequest != nil {
+		c.JSON(http.StatusAccepted, result)
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// AcceptEmail redeems the token of an emailed invitation for the current user
+func (h *InviteHandler) AcceptEmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.svc.AcceptEmailInvite(userID, req.Token)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// InviteByEmail invites an address to the group, emailing it if it has no account yet
+func (h *InviteHandler) InviteByEmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invite, members, err := h.svc.InviteByEmail(userID, id, req.Email)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	if invite != nil {
+		c.JSON(http.StatusAccepted, gin.H{"message": "invitation email sent", "invite": invite})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "user added to conversation", "members": members})
+}
+
+func (h *InviteHandler) ListJoinRequests(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	requests, err := h.svc.ListJoinRequests(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+func (h *InviteHandler) ApproveJoinRequest(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "requestId")
+	if !ok {
+		return
+	}
+
+	member, err := h.svc.ApproveJoinRequest(userID, id, requestID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *InviteHandler) DeclineJoinRequest(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	requestID, ok := paramID(c, "requestId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeclineJoinRequest(userID, id, requestID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "join request declined"})
+}
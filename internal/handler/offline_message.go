@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type OfflineMessageHandler struct {
+	svc    *service.OfflineMessageService
+	logger *zap.Logger
+}
+
+func NewOfflineMessageHandler(svc *service.OfflineMessageService, logger *zap.Logger) *OfflineMessageHandler {
+	return &OfflineMessageHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Submit records the offline message form a visitor sends while support is closed
+func (h *OfflineMessageHandler) Submit(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DepartmentID *uint  `json:"department_id"`
+		Name         string `json:"name" binding:"max=100"`
+		Email        string `json:"email" binding:"required,email,max=100"`
+		Message      string `json:"message" binding:"required,max=4000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.Submit(visitorID, service.OfflineMessageInput{
+		DepartmentID: req.DepartmentID,
+		Name:         req.Name,
+		Email:        req.Email,
+		Message:      req.Message,
+	})
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Offline message received",
+		zap.Uint("offline_message_id", message.ID),
+		zap.Uint("visitor_id", visitorID),
+	)
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// List returns offline messages, newest first, filtered by status and department
+func (h *OfflineMessageHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	departmentID, ok := queryID(c, "department_id")
+	if !ok {
+		return
+	}
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	messages, err := h.svc.List(userID, c.Query("status"), departmentID, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *OfflineMessageHandler) GetById(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	message, err := h.svc.Get(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// SetStatus resolves or reopens an offline message
+func (h *OfflineMessageHandler) SetStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required,oneof=open resolved"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.SetStatus(userID, id, req.Status)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
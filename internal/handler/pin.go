@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PinHandler struct {
+	svc    *service.PinService
+	logger *zap.Logger
+}
+
+func NewPinHandler(svc *service.PinService, logger *zap.Logger) *PinHandler {
+	return &PinHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+func (h *PinHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	pins, err := h.svc.ListPinned(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pins)
+}
+
+func (h *PinHandler) Pin(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MessageID int64 `json:"message_id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pin, err := h.svc.Pin(userID, id, req.MessageID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, pin)
+}
+
+func (h *PinHandler) Unpin(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	messageID, ok := paramID(c, "messageId")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Unpin(userID, id, messageID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "message unpinned successfully"})
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PollHandler struct {
+	svc    *service.PollService
+	logger *zap.Logger
+}
+
+func NewPollHandler(svc *service.PollService, logger *zap.Logger) *PollHandler {
+	return &PollHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Create posts a poll in the conversation
+func (h *PollHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Question       string     `json:"question" binding:"required,max=300"`
+		Options        []string   `json:"options" binding:"required,min=2,max=10"`
+		MultipleChoice bool       `json:"multiple_choice"`
+		Anonymous      bool       `json:"anonymous"`
+		ClosesAt       *time.Time `json:"closes_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.svc.Create(userID, id, service.PollInput{
+		Question:       req.Question,
+		Options:        req.Options,
+		MultipleChoice: req.MultipleChoice,
+		Anonymous:      req.Anonymous,
+		ClosesAt:       req.ClosesAt,
+	})
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, results)
+}
+
+// Get returns the poll's tallies and the caller's votes
+func (h *PollHandler) Get(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	pollID, ok := paramID(c, "pollId")
+	if !ok {
+		return
+	}
+
+	results, err := h.svc.Get(userID, id, pollID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// Vote replaces the caller's votes in the poll
+func (h *PollHandler) Vote(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	pollID, ok := paramID(c, "pollId")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		OptionIDs []uint `json:"option_ids" binding:"required,min=1,max=10"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.svc.Vote(userID, id, pollID, req.OptionIDs)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// Retract withdraws the caller's votes
+func (h *PollHandler) Retract(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	pollID, ok := paramID(c, "pollId")
+	if !ok {
+		return
+	}
+
+	results, err := h.svc.Retract(userID, id, pollID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// Close ends the poll before its close time
+func (h *PollHandler) Close(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	pollID, ok := paramID(c, "pollId")
+	if !ok {
+		return
+	}
+
+	results, err := h.svc.Close(userID, id, pollID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const maxPresenceLookup = 200
+
+type PresenceHandler struct {
+	svc    *service.PresenceService
+	logger *zap.Logger
+}
+
+func NewPresenceHandler(svc *service.PresenceService, logger *zap.Logger) *PresenceHandler {
+	return &PresenceHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Get returns online/offline status for a comma separated list of user_ids
+func (h *PresenceHandler) Get(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var ids []uint
+	for _, part := range strings.Split(c.Query("user_ids"), ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_ids"})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 || len(ids) > maxPresenceLookup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_ids must contain between 1 and 200 ids"})
+		return
+	}
+
+	statuses, err := h.svc.Presence(userID, ids)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Reports cover the last 30 days unless from and to are given
+const defaultRatingReportDays = 30
+
+type RatingHandler struct {
+	svc    *service.RatingService
+	logger *zap.Logger
+}
+
+func NewRatingHandler(svc *service.RatingService, logger *zap.Logger) *RatingHandler {
+	return &RatingHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// ratingRequest is the body of a rating
+type ratingRequest struct {
+	Score   int    `json:"score" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+// Rate lets a visitor rate one of their closed chats
+func (h *RatingHandler) Rate(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ratingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rating, err := h.svc.Rate(visitorID, id, req.Score, req.Comment)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Chat rated",
+		zap.Int64("conversation_id", id),
+		zap.Int("score", rating.Score),
+	)
+
+	c.JSON(http.StatusCreated, rating)
+}
+
+// GetLink describes the chat an emailed rating link is for
+func (h *RatingHandler) GetLink(c *gin.Context) {
+	request, err := h.svc.RatingRequest(c.Query("token"))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// RateWithLink stores a rating given through an emailed rating link
+func (h *RatingHandler) RateWithLink(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+		ratingRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rating, err := h.svc.RateWithLink(req.Token, req.Score, req.Comment)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Chat rated from email link",
+		zap.Uint("conversation_id", rating.ConversationID),
+		zap.Int("score", rating.Score),
+	)
+
+	c.JSON(http.StatusCreated, rating)
+}
+
+// List returns ratings with their comments, newest first
+func (h *RatingHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	filter, ok := ratingFilter(c)
+	if !ok {
+		return
+	}
+	if filter.AgentID, ok = queryID(c, "agent_id"); !ok {
+		return
+	}
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	ratings, err := h.svc.List(userID, filter, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ratings)
+}
+
+// AgentStats returns the rating summary of every agent, optionally within one department
+func (h *RatingHandler) AgentStats(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	filter, ok := ratingFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.svc.AgentStats(userID, filter)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// DepartmentStats returns the rating summary of every department
+func (h *RatingHandler) DepartmentStats(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	filter, ok := ratingFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.svc.DepartmentStats(userID, filter)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ratingFilter reads the from, to and department_id query parameters
+func ratingFilter(c *gin.Context) (service.RatingFilter, bool) {
+	from, to, ok := queryDateRange(c, defaultRatingReportDays)
+	if !ok {
+		return service.RatingFilter{}, false
+	}
+	departmentID, ok := queryID(c, "department_id")
+	if !ok {
+		return service.RatingFilter{}, false
+	}
+	return service.RatingFilter{From: from, To: to, DepartmentID: departmentID}, true
+}
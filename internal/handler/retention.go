@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RetentionHandler struct {
+	svc    *service.RetentionService
+	logger *zap.Logger
+}
+
+func NewRetentionHandler(svc *service.RetentionService, logger *zap.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// retentionPolicyRequest is the body of create and update requests
+type retentionPolicyRequest struct {
+	Name             string `json:"name" binding:"required,max=100"`
+	ConversationType string `json:"conversation_type" binding:"omitempty,oneof=direct group support"`
+	MaxAgeDays       int    `json:"max_age_days" binding:"required,min=1,max=3650"`
+	KeepPinned       *bool  `json:"keep_pinned"`
+	Enabled          *bool  `json:"enabled"`
+}
+
+func (r retentionPolicyRequest) input() service.RetentionPolicyInput {
+	keepPinned, enabled := true, true
+	if r.KeepPinned != nil {
+		keepPinned = *r.KeepPinned
+	}
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return service.RetentionPolicyInput{
+		Name:             r.Name,
+		ConversationType: r.ConversationType,
+		MaxAgeDays:       r.MaxAgeDays,
+		KeepPinned:       keepPinned,
+		Enabled:          enabled,
+	}
+}
+
+func (h *RetentionHandler) ListPolicies(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	policies, err := h.svc.ListPolicies(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+func (h *RetentionHandler) CreatePolicy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req retentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.svc.CreatePolicy(userID, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Retention policy created",
+		zap.Uint("policy_id", policy.ID),
+		zap.Int("max_age_days", policy.MaxAgeDays),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+func (h *RetentionHandler) UpdatePolicy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req retentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.svc.UpdatePolicy(userID, id, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *RetentionHandler) DeletePolicy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeletePolicy(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Purges lists the purge job's runs with what each deleted, newest first
+func (h *RetentionHandler) Purges(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	runs, err := h.svc.Runs(userID, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
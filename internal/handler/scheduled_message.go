@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ScheduledMessageHandler struct {
+	svc    *service.ScheduledMessageService
+	logger *zap.Logger
+}
+
+func NewScheduledMessageHandler(svc *service.ScheduledMessageService, logger *zap.Logger) *ScheduledMessageHandler {
+	return &ScheduledMessageHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Create schedules a message in the conversation for a future time
+func (h *ScheduledMessageHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string    `json:"content" binding:"required"`
+		SendAt  time.Time `json:"send_at" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.Schedule(userID, id, req.Content, req.SendAt)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Message scheduled",
+		zap.Uint("scheduled_message_id", message.ID),
+		zap.Int64("conversation_id", id),
+		zap.Time("send_at", message.SendAt),
+	)
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// List returns the current user's pending scheduled messages, optionally filtered by conversation_id
+func (h *ScheduledMessageHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	conversationID, err := strconv.ParseInt(c.DefaultQuery("conversation_id", "0"), 10, 64)
+	if err != nil || conversationID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation_id"})
+		return
+	}
+
+	messages, err := h.svc.ListPending(userID, conversationID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *ScheduledMessageHandler) Update(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content *string    `json:"content"`
+		SendAt  *time.Time `json:"send_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.Update(userID, id, req.Content, req.SendAt)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *ScheduledMessageHandler) Cancel(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Cancel(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "scheduled message cancelled"})
+}
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Breach reports cover the last 30 days unless from and to are given
+const defaultSLAReportDays = 30
+
+type SLAHandler struct {
+	svc    *service.SLAService
+	logger *zap.Logger
+}
+
+func NewSLAHandler(svc *service.SLAService, logger *zap.Logger) *SLAHandler {
+	return &SLAHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// slaTargetsRequest is the body of a policy's targets
+type slaTargetsRequest struct {
+	FirstResponseSeconds int `json:"first_response_seconds" binding:"min=0"`
+	ResolutionSeconds    int `json:"resolution_seconds" binding:"min=0"`
+	WarningPercent       int `json:"warning_percent" binding:"min=0,max=99"`
+}
+
+func (r slaTargetsRequest) targets() service.SLATargets {
+	return service.SLATargets{
+		FirstResponseSeconds: r.FirstResponseSeconds,
+		ResolutionSeconds:    r.ResolutionSeconds,
+		WarningPercent:       r.WarningPercent,
+	}
+}
+
+func (h *SLAHandler) ListPolicies(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	policies, err := h.svc.ListPolicies(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreatePolicy sets the targets of a priority in a department, or the default
+// targets of the priority when no department is given
+func (h *SLAHandler) CreatePolicy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DepartmentID *uint  `json:"department_id"`
+		Priority     string `json:"priority" binding:"required"`
+		slaTargetsRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.svc.CreatePolicy(userID, req.DepartmentID, req.Priority, req.targets())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("SLA policy created",
+		zap.Uint("policy_id", policy.ID),
+		zap.String("priority", policy.Priority),
+	)
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+func (h *SLAHandler) UpdatePolicy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req slaTargetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.svc.UpdatePolicy(userID, id, req.targets())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *SLAHandler) DeletePolicy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeletePolicy(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("SLA policy deleted", zap.Int64("policy_id", id))
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLA policy deleted successfully"})
+}
+
+// Timers returns the SLA timers of a support chat
+func (h *SLAHandler) Timers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	timers, err := h.svc.Timers(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, timers)
+}
+
+// SetPriority changes the priority of a support chat and with it its SLA targets
+func (h *SLAHandler) SetPriority(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Priority string `json:"priority" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.SetPriority(userID, id, req.Priority)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Support chat priority changed",
+		zap.Int64("conversation_id", id),
+		zap.String("priority", req.Priority),
+	)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// Breaches returns missed SLA targets, newest first
+func (h *SLAHandler) Breaches(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	filter, ok := slaBreachFilter(c)
+	if !ok {
+		return
+	}
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	breaches, err := h.svc.Breaches(userID, filter, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, breaches)
+}
+
+// BreachStats returns the number of missed targets per department and metric
+func (h *SLAHandler) BreachStats(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	filter, ok := slaBreachFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.svc.BreachStats(userID, filter)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// slaBreachFilter reads the from, to, department_id, agent_id and metric query parameters
+func slaBreachFilter(c *gin.Context) (service.SLABreachFilter, bool) {
+	from, to, ok := queryDateRange(c, defaultSLAReportDays)
+	if !ok {
+		return service.SLABreachFilter{}, false
+	}
+	filter := service.SLABreachFilter{From: from, To: to, Metric: c.Query("metric")}
+	if filter.DepartmentID, ok = queryID(c, "department_id"); !ok {
+		return service.SLABreachFilter{}, false
+	}
+	if filter.AgentID, ok = queryID(c, "agent_id"); !ok {
+		return service.SLABreachFilter{}, false
+	}
+	return filter, true
+}
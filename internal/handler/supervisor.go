@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"go_starter/internal/realtime"
+	"go_starter/internal/service"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// The live dashboard is recomputed and pushed this often
+const dashboardRefreshInterval = 5 * time.Second
+
+// Events pushed on the dashboard stream besides the user's own events
+const dashboardSnapshotEvent = "dashboard.snapshot"
+
+// SupervisorHandler serves the live monitoring dashboard. Its routes are
+// protected by middleware.SupervisorMiddleware.
+type SupervisorHandler struct {
+	svc    *service.SupervisorService
+	hub    *realtime.Hub
+	logger *zap.Logger
+}
+
+func NewSupervisorHandler(svc *service.SupervisorService, hub *realtime.Hub, logger *zap.Logger) *SupervisorHandler {
+	return &SupervisorHandler{
+		svc:    svc,
+		hub:    hub,
+		logger: logger,
+	}
+}
+
+// Overview returns queue lengths, waiting times and agent statuses
+func (h *SupervisorHandler) Overview(c *gin.Context) {
+	overview, err := h.svc.Overview(time.Now())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
+// Agents returns every agent with their status and active chats
+func (h *SupervisorHandler) Agents(c *gin.Context) {
+	agents, err := h.svc.Agents(time.Now())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, agents)
+}
+
+// Queue returns the chats waiting for an agent, longest waiting first
+func (h *SupervisorHandler) Queue(c *gin.Context) {
+	queue, err := h.svc.Queue(time.Now())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, queue)
+}
+
+// Stream pushes a dashboard snapshot over Server-Sent Events right away and then
+// every few seconds, along with the supervisor's own events such as SLA alerts
+func (h *SupervisorHandler) Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	snapshot, err := h.svc.Snapshot(time.Now())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	events, unsubscribe := h.hub.Subscribe(realtime.UserTopic(userID))
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent(dashboardSnapshotEvent, realtime.Event{Type: dashboardSnapshotEvent, Data: snapshot})
+
+	ticker := time.NewTicker(dashboardRefreshInterval)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case now := <-ticker.C:
+			snapshot, err := h.svc.Snapshot(now)
+			if err != nil {
+				h.logger.Error("Failed to compute dashboard snapshot", zap.String("error", err.Error()))
+				return false
+			}
+			c.SSEvent(dashboardSnapshotEvent, realtime.Event{Type: dashboardSnapshotEvent, Data: snapshot})
+			return true
+		}
+	})
+	h.logger.Debug("Dashboard stream closed", zap.Uint("user_id", userID))
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"fmt"
+	"go_starter/internal/model"
+	"go_starter/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TranscriptHandler struct {
+	svc    *service.TranscriptService
+	logger *zap.Logger
+}
+
+func NewTranscriptHandler(svc *service.TranscriptService, logger *zap.Logger) *TranscriptHandler {
+	return &TranscriptHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// Export downloads the transcript of a conversation. Long transcripts are
+// generated in the background and answered with 202 and the pending export.
+func (h *TranscriptHandler) Export(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	file, export, err := h.svc.Export(userID, id, c.DefaultQuery("format", model.TranscriptText))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.writeExport(c, file, export)
+}
+
+// GetExport returns a background export with its download link once ready
+func (h *TranscriptHandler) GetExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := h.svc.GetExport(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// RequestEmail has the transcript of a support chat emailed to its visitor
+func (h *TranscriptHandler) RequestEmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RequestEmail(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Transcript email requested",
+		zap.Int64("conversation_id", id),
+		zap.Uint("actor_id", userID),
+	)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "transcript will be emailed to the visitor"})
+}
+
+// Download serves a transcript through its signed link, without a session
+func (h *TranscriptHandler) Download(c *gin.Context) {
+	file, err := h.svc.Download(c.Query("token"))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	writeTranscriptFile(c, file)
+}
+
+// VisitorExport downloads the transcript of one of the visitor's chats
+func (h *TranscriptHandler) VisitorExport(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	file, export, err := h.svc.VisitorExport(visitorID, id, c.DefaultQuery("format", model.TranscriptText))
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.writeExport(c, file, export)
+}
+
+func (h *TranscriptHandler) GetVisitorExport(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := h.svc.GetVisitorExport(visitorID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// VisitorRequestEmail has the transcript of the visitor's current chat emailed when it ends
+func (h *TranscriptHandler) VisitorRequestEmail(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email" binding:"omitempty,email,max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.VisitorRequestEmail(visitorID, req.Email); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "transcript will be emailed when the chat ends"})
+}
+
+func (h *TranscriptHandler) writeExport(c *gin.Context, file *service.TranscriptFile, export *model.TranscriptExport) {
+	if export != nil {
+		h.logger.Info("Transcript export queued",
+			zap.Uint("export_id", export.ID),
+			zap.Uint("conversation_id", export.ConversationID),
+		)
+		c.JSON(http.StatusAccepted, export)
+		return
+	}
+	writeTranscriptFile(c, file)
+}
+
+func writeTranscriptFile(c *gin.Context, file *service.TranscriptFile) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	c.Data(http.StatusOK, file.ContentType, file.Body)
+}
@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"errors"
+	"go_starter/internal/realtime"
+	"go_starter/internal/service"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type VisitorHandler struct {
+	svc    *service.VisitorService
+	hub    *realtime.Hub
+	logger *zap.Logger
+}
+
+func NewVisitorHandler(svc *service.VisitorService, hub *realtime.Hub, logger *zap.Logger) *VisitorHandler {
+	return &VisitorHandler{
+		svc:    svc,
+		hub:    hub,
+		logger: logger,
+	}
+}
+
+// StartSession issues a visitor session token to the website widget. No account is required.
+func (h *VisitorHandler) StartSession(c *gin.Context) {
+	var req struct {
+		Name     string `json:"name" binding:"max=100"`
+		Email    string `json:"email" binding:"omitempty,email,max=100"`
+		PageURL  string `json:"page_url" binding:"max=500"`
+		Language string `json:"language" binding:"max=20"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visitor, token, err := h.svc.StartSession(req.Name, req.Email, req.PageURL, req.Language)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Visitor session started", zap.Uint("visitor_id", visitor.ID))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   token,
+		"visitor": visitor,
+	})
+}
+
+func (h *VisitorHandler) Get(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	visitor, err := h.svc.GetVisitor(visitorID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, visitor)
+}
+
+// Update changes the name and/or email the visitor shared
+func (h *VisitorHandler) Update(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name  *string `json:"name" binding:"omitempty,max=100"`
+		Email *string `json:"email" binding:"omitempty,email,max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visitor, err := h.svc.UpdateProfile(visitorID, req.Name, req.Email)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, visitor)
+}
+
+// Stream pushes real-time events for the visitor's chats over Server-Sent Events
+func (h *VisitorHandler) Stream(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	streamTopic(c, h.hub, realtime.VisitorTopic(visitorID))
+	h.logger.Debug("Visitor event stream closed", zap.Uint("visitor_id", visitorID))
+}
+
+// OpenChat starts a chat with the support team, or returns the one already open.
+// The body is optional and may pick a department.
+func (h *VisitorHandler) OpenChat(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DepartmentID *uint  `json:"department_id"`
+		PageURL      string `json:"page_url" binding:"max=500"`
+		Language     string `json:"language" binding:"max=20"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversation, err := h.svc.OpenChat(visitorID, service.ChatRequest{
+		DepartmentID: req.DepartmentID,
+		PageURL:      req.PageURL,
+		Language:     req.Language,
+	})
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+func (h *VisitorHandler) GetChat(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.svc.CurrentChat(visitorID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// QueuePosition returns the visitor's place in the queue, 0 once an agent has joined
+func (h *VisitorHandler) QueuePosition(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	conversation, position, err := h.svc.QueuePosition(visitorID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": conversation.ID,
+		"status":          conversation.Status,
+		"position":        position,
+	})
+}
+
+func (h *VisitorHandler) EndChat(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.svc.EndChat(visitorID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Support chat ended by visitor",
+		zap.Uint("conversation_id", conversation.ID),
+		zap.Uint("visitor_id", visitorID),
+	)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// RequestAgent takes the visitor's chat from the bot and queues it for an agent
+func (h *VisitorHandler) RequestAgent(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.svc.RequestAgent(visitorID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Visitor asked the bot for an agent",
+		zap.Uint("conversation_id", conversation.ID),
+		zap.Uint("visitor_id", visitorID),
+	)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+func (h *VisitorHandler) ListMessages(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	messages, err := h.svc.ListMessages(visitorID, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *VisitorHandler) SendMessage(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.svc.SendMessage(visitorID, req.Content)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+func (h *VisitorHandler) Typing(c *gin.Context) {
+	visitorID, ok := currentVisitorID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Typing(visitorID); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CloseChat lets an agent close a support conversation they take part in
+func (h *VisitorHandler) CloseChat(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	conversation, err := h.svc.CloseChat(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Support chat closed by agent",
+		zap.Int64("conversation_id", id),
+		zap.Uint("agent_id", userID),
+	)
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// Link attaches a visitor session to the current user's account
+func (h *VisitorHandler) Link(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		VisitorToken string `json:"visitor_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visitor, err := h.svc.LinkToUser(req.VisitorToken, userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Visitor linked to account",
+		zap.Uint("visitor_id", visitor.ID),
+		zap.Uint("user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, visitor)
+}
@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"go_starter/internal/model"
+	"go_starter/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type WebhookHandler struct {
+	svc    *service.WebhookService
+	logger *zap.Logger
+}
+
+func NewWebhookHandler(svc *service.WebhookService, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		svc:    svc,
+		logger: logger,
+	}
+}
+
+// webhookRequest is the body of create and update requests
+type webhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=500"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+func (r webhookRequest) input() service.WebhookInput {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return service.WebhookInput{
+		URL:         r.URL,
+		Description: r.Description,
+		Events:      r.Events,
+		Enabled:     enabled,
+	}
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	endpoints, err := h.svc.ListEndpoints(userID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// Create registers a webhook endpoint. The signing secret is only returned here.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, secret, err := h.svc.CreateEndpoint(userID, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Webhook endpoint created",
+		zap.Uint("webhook_id", endpoint.ID),
+		zap.Strings("events", endpoint.Events),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"secret":  secret,
+		"webhook": endpoint,
+	})
+}
+
+func (h *WebhookHandler) GetById(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.svc.GetEndpoint(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *WebhookHandler) Update(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.svc.UpdateEndpoint(userID, id, req.input())
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteEndpoint(userID, id); err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RotateSecret replaces the endpoint's signing secret and returns the new one
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	secret, err := h.svc.RotateSecret(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Webhook secret rotated",
+		zap.Int64("webhook_id", id),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
+
+// Ping queues a test event for the endpoint
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.svc.Ping(userID, id)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// Deliveries returns the endpoint's delivery log, newest first, optionally only
+// the deliveries with ?status=pending|delivered|dead
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	if status != "" && status != model.WebhookDeliveryPending && status != model.WebhookDeliveryDelivered && status != model.WebhookDeliveryDead {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	beforeID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || beforeID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	deliveries, err := h.svc.Deliveries(userID, id, status, beforeID, limit)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Delivery returns one delivery with its payload and attempt log
+func (h *WebhookHandler) Delivery(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := paramID(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.svc.Delivery(userID, id, deliveryID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver queues a finished delivery again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := paramID(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.svc.Redeliver(userID, id, deliveryID)
+	if err != nil {
+		writeServiceError(c, h.logger, err)
+		return
+	}
+
+	h.logger.Info("Webhook delivery requeued",
+		zap.Int64("webhook_id", id),
+		zap.Int64("delivery_id", deliveryID),
+		zap.Uint("by_user_id", userID),
+	)
+
+	c.JSON(http.StatusAccepted, delivery)
+}
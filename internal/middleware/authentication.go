@@ -1,15 +1,37 @@
 package middleware
 
 import (
+	"go_starter/internal/model"
 	"go_starter/internal/util"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// BotKeyLookup authenticates a bot API key. It returns a nil key when the key is
+// unknown, revoked or expired.
+type BotKeyLookup func(key string, now time.Time) (*model.APIKey, error)
+
+// BotRequestCounter counts a request against the key's rate limit and returns the
+// number of requests made with it in the current minute
+type BotRequestCounter func(key *model.APIKey, now time.Time) (int64, error)
+
+// BotAuth lets AuthMiddleware accept bot API keys
+type BotAuth struct {
+	Lookup BotKeyLookup
+	// Count is only called for requests the key's scopes allow, so refused
+	// requests do not use up the key's quota
+	Count BotRequestCounter
+	// Scopes maps "METHOD /full/route/path" to the scope a key needs to call the
+	// route. Routes missing from it are closed to bots.
+	Scopes map[string]string
+}
+
+// AuthMiddleware validates JWT tokens, and bot API keys when bots is set
+func AuthMiddleware(bots *BotAuth) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -19,8 +41,12 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Check if it's Bearer
+		// Check if it's Bearer, or Bot when bot keys are accepted
 		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bot" && bots != nil {
+			bots.authenticate(c, parts[1])
+			return
+		}
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "invalid authorization header format",
@@ -46,3 +72,60 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// authenticate lets a bot API key through when it is valid, has the scope the
+// route needs and is within its rate limit
+func (b *BotAuth) authenticate(c *gin.Context, raw string) {
+	now := time.Now()
+	key, err := b.Lookup(raw, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Abort()
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired API key"})
+		c.Abort()
+		return
+	}
+
+	scope, allowed := b.Scopes[c.Request.Method+" "+c.FullPath()]
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint is not available to bots"})
+		c.Abort()
+		return
+	}
+	if !key.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	// Fixed one-minute windows, counted in the database so every replica agrees
+	count, err := b.Count(key, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		c.Abort()
+		return
+	}
+	reset := now.Truncate(time.Minute).Add(time.Minute)
+	remaining := int64(key.RateLimit) - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Header("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+	c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if count > int64(key.RateLimit) {
+		c.Header("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		c.Abort()
+		return
+	}
+
+	// Set bot information in context
+	c.Set("user_id", key.BotID)
+	c.Set("api_key_id", key.ID)
+
+	c.Next()
+}
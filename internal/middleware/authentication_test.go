@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go_starter/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBotAuthCountsOnlyAllowedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	counted := 0
+	bots := &BotAuth{
+		Lookup: func(key string, now time.Time) (*model.APIKey, error) {
+			return &model.APIKey{ID: 1, BotID: 9, Scopes: []string{model.ScopeMessagesRead}, RateLimit: 1}, nil
+		},
+		Count: func(key *model.APIKey, now time.Time) (int64, error) {
+			counted++
+			return int64(counted), nil
+		},
+		Scopes: map[string]string{
+			"GET /messages":  model.ScopeMessagesRead,
+			"POST /messages": model.ScopeMessagesWrite,
+		},
+	}
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/messages", AuthMiddleware(bots), ok)
+	r.POST("/messages", AuthMiddleware(bots), ok)
+	r.GET("/admin", AuthMiddleware(bots), ok)
+
+	call := func(method, path string) int {
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set("Authorization", "Bot bk_key.secret")
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// Refused requests must not use up the key's one request per minute
+	if code := call(http.MethodGet, "/admin"); code != http.StatusForbidden {
+		t.Errorf("unmapped route: got %d, want 403", code)
+	}
+	if code := call(http.MethodPost, "/messages"); code != http.StatusForbidden {
+		t.Errorf("route outside the key's scopes: got %d, want 403", code)
+	}
+	if counted != 0 {
+		t.Errorf("refused requests were counted %d times", counted)
+	}
+
+	if code := call(http.MethodGet, "/messages"); code != http.StatusOK {
+		t.Errorf("allowed request: got %d, want 200", code)
+	}
+	if code := call(http.MethodGet, "/messages"); code != http.StatusTooManyRequests {
+		t.Errorf("request over the limit: got %d, want 429", code)
+	}
+}
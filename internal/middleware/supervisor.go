@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"go_starter/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleLookup returns the current role of a user, or an empty role when the user no longer exists
+type RoleLookup func(userId uint) (string, error)
+
+// SupervisorMiddleware only lets supervisors and admins through. It must run after
+// AuthMiddleware. The role is looked up on every request, so a demoted supervisor
+// loses access at once rather than when their token expires.
+func SupervisorMiddleware(lookup RoleLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		id, valid := userID.(uint)
+		if !ok || !valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		role, err := lookup(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			c.Abort()
+			return
+		}
+		if role == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+		if !model.CanSupervise(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "supervisor role required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"go_starter/internal/util"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VisitorMiddleware validates visitor session tokens issued to the website widget
+func VisitorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get token from Authorization header
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "visitor token required"})
+			c.Abort()
+			return
+		}
+
+		claims, err := util.ValidateVisitorToken(parts[1])
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired visitor token"})
+			c.Abort()
+			return
+		}
+
+		// Set visitor information in context
+		c.Set("visitor_id", claims.VisitorID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Agent availability statuses
+const (
+	AgentStatusOnline  = "online"
+	AgentStatusAway    = "away"
+	AgentStatusOffline = "offline"
+)
+
+// DefaultAgentMaxChats is how many chats an agent handles at once unless configured otherwise
+const DefaultAgentMaxChats = 3
+
+// AgentProfile holds the routing state of a user with the agent role
+type AgentProfile struct {
+	UserID         uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Status         string     `gorm:"size:20;not null;default:offline;index" json:"status"`
+	MaxChats       int        `gorm:"not null;default:3" json:"max_chats"`
+	ActiveChats    int        `gorm:"not null;default:0" json:"active_chats"`
+	LastAssignedAt *time.Time `json:"last_assigned_at"` // drives round-robin ordering
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// HasCapacity reports whether the agent can take another chat right now
+func (a *AgentProfile) HasCapacity() bool {
+	return a.Status == AgentStatusOnline && a.ActiveChats < a.MaxChats
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// AnalyticsHour is the activity of one hour, rolled up by a background job so
+// reports never scan raw messages. Hours stay provisional until the job marks
+// them final some time after they end.
+type AnalyticsHour struct {
+	ID    uint      `gorm:"primaryKey" json:"-"`
+	Hour  time.Time `gorm:"not null;uniqueIndex" json:"hour"`
+	Final bool      `gorm:"not null;default:false;index" json:"final"`
+
+	Messages             int64 `gorm:"not null;default:0" json:"messages"` // excluding system messages
+	VisitorMessages      int64 `gorm:"not null;default:0" json:"visitor_messages"`
+	ConversationsStarted int64 `gorm:"not null;default:0" json:"conversations_started"`
+	SupportChatsStarted  int64 `gorm:"not null;default:0" json:"support_chats_started"`
+	SupportChatsClosed   int64 `gorm:"not null;default:0" json:"support_chats_closed"`
+
+	// First agent replies sent in the hour and the seconds visitors waited for them
+	FirstResponses       int64 `gorm:"not null;default:0" json:"first_responses"`
+	FirstResponseSeconds int64 `gorm:"not null;default:0" json:"first_response_seconds"`
+
+	Ratings         int64 `gorm:"not null;default:0" json:"ratings"`
+	RatingScoreSum  int64 `gorm:"not null;default:0" json:"rating_score_sum"`
+	PositiveRatings int64 `gorm:"not null;default:0" json:"positive_ratings"` // scores of 4 or 5
+
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// AnalyticsUserHour records that a user posted at least one message in the hour,
+// so active users can be counted over any period without double counting
+type AnalyticsUserHour struct {
+	Hour   time.Time `gorm:"primaryKey;autoIncrement:false"`
+	UserID uint      `gorm:"primaryKey;autoIncrement:false;index"`
+}
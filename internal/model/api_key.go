@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// API key scopes
+const (
+	ScopeConversationsRead = "conversations:read"
+	ScopeMessagesRead      = "messages:read"
+	ScopeMessagesWrite     = "messages:write"
+)
+
+// APIKeyScopes lists every scope a key may be granted
+var APIKeyScopes = []string{ScopeConversationsRead, ScopeMessagesRead, ScopeMessagesWrite}
+
+// APIKey lets a bot user call the API. Only a hash of the secret is stored; the
+// prefix identifies the key without revealing it.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	BotID      uint       `gorm:"not null;index" json:"bot_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:20;not null;uniqueIndex" json:"prefix"`
+	Hash       string     `gorm:"size:64;not null" json:"-"`
+	Scopes     []string   `gorm:"type:text;serializer:json" json:"scopes"`
+	RateLimit  int        `gorm:"not null" json:"rate_limit"` // requests per minute
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyUsage counts a key's requests in one minute for rate limiting
+type APIKeyUsage struct {
+	KeyID       uint      `gorm:"primaryKey;autoIncrement:false"`
+	WindowStart time.Time `gorm:"primaryKey;index"`
+	Count       int64     `gorm:"not null"`
+}
+
+// IsActive reports whether the key may still be used at the given time
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+// HasScope reports whether the key was granted the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidScope reports whether scope is one of the API key scopes
+func IsValidScope(scope string) bool {
+	for _, known := range APIKeyScopes {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// UserBlock records that BlockerID no longer wants contact with BlockedID
+type UserBlock struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_user_block" json:"blocker_id"`
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_user_block;index" json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Bot session statuses
+const (
+	BotSessionActive    = "active"
+	BotSessionHandedOff = "handed_off" // the chat went to the agent queue
+)
+
+// Bot field types
+const (
+	BotFieldText  = "text"
+	BotFieldEmail = "email"
+)
+
+// BotFlow is the script of the auto-responder that greets visitors before an
+// agent picks up their chat. A flow without a department serves the general
+// queue and departments without an enabled flow of their own.
+type BotFlow struct {
+	ID           uint              `gorm:"primaryKey" json:"id"`
+	Name         string            `gorm:"size:100;not null" json:"name"`
+	DepartmentID *uint             `gorm:"index" json:"department_id"`
+	Enabled      bool              `gorm:"not null;default:false;index" json:"enabled"`
+	Definition   BotFlowDefinition `gorm:"type:text;serializer:json" json:"definition"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// BotFlowDefinition is what the bot says and how it reacts to visitor messages
+type BotFlowDefinition struct {
+	Greeting        string      `json:"greeting"`
+	Intents         []BotIntent `json:"intents"`
+	HandoffKeywords []string    `json:"handoff_keywords"` // words that make the bot hand off at once, such as "agent"
+	HandoffReply    string      `json:"handoff_reply"`
+	FallbackReply   string      `json:"fallback_reply"`
+	MaxFallbacks    int         `json:"max_fallbacks"` // unmatched messages after which the chat goes to an agent
+}
+
+// BotIntent answers the visitor messages it matches and may collect fields or
+// hand the chat off afterwards
+type BotIntent struct {
+	Name     string     `json:"name"`
+	Keywords []string   `json:"keywords"`
+	Pattern  string     `json:"pattern"` // a regular expression, matched case-insensitively
+	Reply    string     `json:"reply"`
+	Collect  []BotField `json:"collect"`
+	Done     string     `json:"done"` // sent once every field was collected
+	Handoff  bool       `json:"handoff"`
+}
+
+// BotField is a value the bot asks the visitor for
+type BotField struct {
+	Name    string `json:"name"`
+	Prompt  string `json:"prompt"`
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"` // an optional regular expression the whole value must match
+	Invalid string `json:"invalid"` // sent when the value is rejected
+}
+
+// BotSession tracks where a support chat is in its bot flow. Step counts the
+// messages handled so concurrent ones only advance the session once.
+type BotSession struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	ConversationID uint              `gorm:"not null;uniqueIndex" json:"conversation_id"`
+	FlowID         uint              `gorm:"not null;index" json:"flow_id"`
+	Status         string            `gorm:"size:20;not null" json:"status"`
+	Intent         string            `gorm:"size:100" json:"intent"` // the intent collecting fields, empty otherwise
+	FieldIndex     int               `gorm:"not null;default:0" json:"field_index"`
+	Fallbacks      int               `gorm:"not null;default:0" json:"fallbacks"`
+	Data           map[string]string `gorm:"type:text;serializer:json" json:"data"`
+	Step           int               `gorm:"not null;default:0" json:"-"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Intent returns the intent with the name, nil when the flow has none
+func (d *BotFlowDefinition) Intent(name string) *BotIntent {
+	for i := range d.Intents {
+		if d.Intents[i].Name == name {
+			return &d.Intents[i]
+		}
+	}
+	return nil
+}
+
+// Match returns the first intent matching the text, nil when none does
+func (d *BotFlowDefinition) Match(text string) *BotIntent {
+	for i := range d.Intents {
+		if d.Intents[i].Matches(text) {
+			return &d.Intents[i]
+		}
+	}
+	return nil
+}
+
+// WantsHandoff reports whether the text contains one of the handoff keywords
+func (d *BotFlowDefinition) WantsHandoff(text string) bool {
+	return containsKeyword(text, d.HandoffKeywords)
+}
+
+// Matches reports whether the text contains one of the intent's keywords or
+// matches its pattern
+func (i *BotIntent) Matches(text string) bool {
+	if containsKeyword(text, i.Keywords) {
+		return true
+	}
+	if i.Pattern == "" {
+		return false
+	}
+	pattern, err := regexp.Compile("(?i)" + i.Pattern)
+	return err == nil && pattern.MatchString(text)
+}
+
+// containsKeyword reports whether one of the keywords appears in the text as
+// whole words, ignoring case
+func containsKeyword(text string, keywords []string) bool {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(text), isWordSeparator), " ") + " "
+	for _, keyword := range keywords {
+		phrase := strings.Join(strings.FieldsFunc(strings.ToLower(keyword), isWordSeparator), " ")
+		if phrase != "" && strings.Contains(words, " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+}
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// Days NextOpening searches ahead, enough to skip a week of holidays
+	nextOpeningLookahead = 28
+
+	// Days AddBusinessTime searches ahead before falling back to wall-clock time
+	businessTimeLookahead = 366
+)
+
+// BusinessHours is a weekly window in which a department takes chats
+type BusinessHours struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	DepartmentID uint   `gorm:"not null;index" json:"department_id"`
+	Weekday      int    `gorm:"not null" json:"weekday"`          // 0 is Sunday
+	OpensAt      string `gorm:"size:5;not null" json:"opens_at"`  // "09:00"
+	ClosesAt     string `gorm:"size:5;not null" json:"closes_at"` // exclusive, "24:00" for midnight
+}
+
+// Holiday closes a department for a whole day in its timezone
+type Holiday struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	DepartmentID uint      `gorm:"not null;uniqueIndex:idx_department_holiday" json:"department_id"`
+	Date         string    `gorm:"size:10;not null;uniqueIndex:idx_department_holiday" json:"date"` // "2026-12-25"
+	Name         string    `gorm:"size:100" json:"name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Location returns the department's timezone, falling back to UTC when it is unknown
+func (d *Department) Location() *time.Location {
+	if d.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// IsOpen reports whether the department takes chats at t. Departments without
+// business hours are always open, except on their holidays.
+func (d *Department) IsOpen(t time.Time) bool {
+	local := t.In(d.Location())
+	if d.isHoliday(local) {
+		return false
+	}
+	if len(d.Hours) == 0 {
+		return true
+	}
+	clock := local.Format("15:04")
+	for _, hours := range d.Hours {
+		if hours.Weekday == int(local.Weekday()) && clock >= hours.OpensAt && clock < hours.ClosesAt {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns when the department opens next after t, looking up to four
+// weeks ahead. It returns nil when the department is open or has no opening in that time.
+func (d *Department) NextOpening(t time.Time) *time.Time {
+	if d.IsOpen(t) || len(d.Hours) == 0 {
+		return nil
+	}
+	location := d.Location()
+	local := t.In(location)
+
+	hours := make([]*BusinessHours, len(d.Hours))
+	copy(hours, d.Hours)
+	sort.Slice(hours, func(i, j int) bool { return hours[i].OpensAt < hours[j].OpensAt })
+
+	for offset := 0; offset < nextOpeningLookahead; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, location)
+		if d.isHoliday(day) {
+			continue
+		}
+		for _, window := range hours {
+			if window.Weekday != int(day.Weekday()) {
+				continue
+			}
+			opensAt, err := time.ParseInLocation(dateLayout+" 15:04", day.Format(dateLayout)+" "+window.OpensAt, location)
+			if err != nil || !opensAt.After(t) {
+				continue
+			}
+			return &opensAt
+		}
+	}
+	return nil
+}
+
+// AddBusinessTime returns when duration of business time has passed after t,
+// skipping the hours the department is closed and its holidays. Departments
+// without business hours count every hour outside holidays. When the department
+// does not open within a year, wall-clock time is used instead.
+func (d *Department) AddBusinessTime(t time.Time, duration time.Duration) time.Time {
+	location := d.Location()
+	local := t.In(location)
+	remaining := duration
+	for offset := 0; offset < businessTimeLookahead; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, location)
+		if d.isHoliday(day) {
+			continue
+		}
+		for _, window := range d.windows(day) {
+			start := window[0]
+			if start.Before(t) {
+				start = t
+			}
+			if !start.Before(window[1]) {
+				continue
+			}
+			if open := window[1].Sub(start); remaining > open {
+				remaining -= open
+				continue
+			}
+			return start.Add(remaining)
+		}
+	}
+	return t.Add(duration)
+}
+
+// windows returns the department's open intervals on the local day, earliest first
+func (d *Department) windows(day time.Time) [][2]time.Time {
+	if len(d.Hours) == 0 {
+		return [][2]time.Time{{day, time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())}}
+	}
+	var windows [][2]time.Time
+	for _, hours := range d.Hours {
+		if hours.Weekday != int(day.Weekday()) {
+			continue
+		}
+		opensAt, ok := clockOn(day, hours.OpensAt)
+		if !ok {
+			continue
+		}
+		closesAt, ok := clockOn(day, hours.ClosesAt)
+		if !ok {
+			continue
+		}
+		windows = append(windows, [2]time.Time{opensAt, closesAt})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i][0].Before(windows[j][0]) })
+	return windows
+}
+
+// clockOn returns the "HH:MM" clock time on the local day, "24:00" being the next midnight
+func clockOn(day time.Time, clock string) (time.Time, bool) {
+	hourText, minuteText, found := strings.Cut(clock, ":")
+	if !found {
+		return time.Time{}, false
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil {
+		return time.Time{}, false
+	}
+	minute, err := strconv.Atoi(minuteText)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location()), true
+}
+
+func (d *Department) isHoliday(local time.Time) bool {
+	date := local.Format(dateLayout)
+	for _, holiday := range d.Holidays {
+		if holiday.Date == date {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// CannedResponse is a saved reply agents insert by typing its shortcut, e.g. /refund.
+// Personal responses belong to one agent; shared ones, with no owner, are managed by admins.
+type CannedResponse struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OwnerID     *uint     `gorm:"index" json:"owner_id"`                  // nil for shared responses
+	Shortcut    string    `gorm:"size:50;not null;index" json:"shortcut"` // without the leading slash
+	Title       string    `gorm:"size:100" json:"title"`
+	Content     string    `gorm:"type:text;not null" json:"content"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsShared reports whether every agent can use the response
+func (r *CannedResponse) IsShared() bool {
+	return r.OwnerID == nil
+}
+
+// Render fills the {{name}} placeholders of the content with the given values.
+// Unknown placeholders are left as they are.
+func (r *CannedResponse) Render(values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for name, value := range values {
+		pairs = append(pairs, "{{"+name+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(r.Content)
+}
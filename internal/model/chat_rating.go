@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ChatRating is the satisfaction score a visitor gave a closed support chat
+type ChatRating struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"not null;uniqueIndex" json:"conversation_id"`
+	VisitorID      uint      `gorm:"not null;index" json:"visitor_id"`
+	AgentID        *uint     `gorm:"index" json:"agent_id"`      // the agent who handled the chat last
+	DepartmentID   *uint     `gorm:"index" json:"department_id"` // nil for the general queue
+	Score          int       `gorm:"not null" json:"score"`      // 1 to 5
+	Comment        string    `gorm:"type:text" json:"comment"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Contact request statuses
+const (
+	ContactRequestPending   = "pending"
+	ContactRequestAccepted  = "accepted"
+	ContactRequestDeclined  = "declined"
+	ContactRequestCancelled = "cancelled"
+)
+
+type ContactRequest struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RequesterID uint      `gorm:"not null;index" json:"requester_id"`
+	AddresseeID uint      `gorm:"not null;index" json:"addressee_id"`
+	Status      string    `gorm:"size:20;not null;default:pending;index" json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Contact is one direction of an accepted contact relationship; accepting a
+// request stores a row for each user
+type Contact struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_contact_pair" json:"user_id"`
+	ContactID uint      `gorm:"not null;uniqueIndex:idx_contact_pair;index" json:"contact_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
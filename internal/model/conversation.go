@@ -0,0 +1,118 @@
+package model
+
+import "time"
+
+// Conversation types
+const (
+	ConversationTypeDirect = "direct"
+	ConversationTypeGroup  = "group"
+
+	// ConversationTypeSupport is a chat between a website visitor and the support team
+	ConversationTypeSupport = "support"
+)
+
+// Support conversation statuses
+const (
+	SupportStatusBot    = "bot"    // talking to the auto-responder before joining the queue
+	SupportStatusQueued = "queued" // waiting for an agent
+	SupportStatusActive = "active" // assigned to an agent
+	SupportStatusClosed = "closed"
+)
+
+// Support conversation priorities
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// IsValidPriority reports whether p is one of the support conversation priorities
+func IsValidPriority(p string) bool {
+	return p == PriorityLow || p == PriorityNormal || p == PriorityHigh || p == PriorityUrgent
+}
+
+// Member roles, ordered from most to least privileged
+const (
+	MemberRoleOwner  = "owner"
+	MemberRoleAdmin  = "admin"
+	MemberRoleMember = "member"
+
+	// MemberRoleObserver follows a support chat without posting to the visitor
+	MemberRoleObserver = "observer"
+)
+
+type Conversation struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Type       string    `gorm:"size:20;not null;index" json:"type"`
+	Name       string    `gorm:"size:100" json:"name"`
+	AvatarURL  string    `gorm:"size:255" json:"avatar_url"`
+	Topic      string    `gorm:"size:255" json:"topic,omitempty"`
+	MessageTTL int       `gorm:"not null;default:0" json:"message_ttl,omitempty"` // seconds new messages last before they disappear, 0 when off
+	OwnerID    uint      `gorm:"index" json:"owner_id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Support conversations only
+	VisitorID       *uint      `gorm:"index" json:"visitor_id,omitempty"`
+	Status          string     `gorm:"size:20;index" json:"status,omitempty"`
+	DepartmentID    *uint      `gorm:"index" json:"department_id,omitempty"`
+	Priority        string     `gorm:"size:10" json:"priority,omitempty"`
+	RoutedAt        *time.Time `json:"routed_at,omitempty"` // when the chat entered its current department queue
+	AssignedAgentID *uint      `gorm:"index" json:"assigned_agent_id,omitempty"`
+	QueuedAt        *time.Time `gorm:"index" json:"queued_at,omitempty"`
+	AssignedAt      *time.Time `json:"assigned_at,omitempty"`
+	ClosedAt        *time.Time `gorm:"index" json:"closed_at,omitempty"`
+
+	// When the visitor was emailed a rating link after leaving without rating
+	RatingRequestedAt *time.Time `json:"-"`
+	// Where the transcript is emailed when the chat ends, if someone asked for it
+	TranscriptEmail string `gorm:"size:100" json:"-"`
+}
+
+type ConversationMember struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"not null;uniqueIndex:idx_conversation_member" json:"conversation_id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_conversation_member;index" json:"user_id"`
+	Role           string    `gorm:"size:20;not null;default:member" json:"role"`
+	JoinedAt       time.Time `json:"joined_at"`
+
+	// Per-member settings
+	Muted      bool       `gorm:"not null;default:false" json:"muted"`
+	MutedUntil *time.Time `json:"muted_until"` // nil while Muted means muted forever
+	PinnedAt   *time.Time `json:"pinned_at"`
+	ArchivedAt *time.Time `json:"archived_at"`
+}
+
+// IsGroup reports whether the conversation supports roles and membership changes
+func (c *Conversation) IsGroup() bool {
+	return c.Type == ConversationTypeGroup
+}
+
+// IsSupport reports whether the conversation is a visitor chat with the support team
+func (c *Conversation) IsSupport() bool {
+	return c.Type == ConversationTypeSupport
+}
+
+// IsClosed reports whether the support chat has ended
+func (c *Conversation) IsClosed() bool {
+	return c.ClosedAt != nil
+}
+
+// CanManageMembers reports whether the member may add or remove other members
+func (m *ConversationMember) CanManageMembers() bool {
+	return m.Role == MemberRoleOwner || m.Role == MemberRoleAdmin
+}
+
+// IsMuted reports whether notifications are silenced for the member at the given time
+func (m *ConversationMember) IsMuted(now time.Time) bool {
+	if !m.Muted {
+		return false
+	}
+	return m.MutedUntil == nil || m.MutedUntil.After(now)
+}
+
+// IsOwner reports whether the member owns the conversation
+func (m *ConversationMember) IsOwner() bool {
+	return m.Role == MemberRoleOwner
+}
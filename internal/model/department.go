@@ -0,0 +1,64 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Department is a team of agents that support chats can be routed to
+type Department struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string `gorm:"size:255" json:"description"`
+
+	// Chats still waiting after OverflowAfterSeconds move to the fallback department.
+	// 0 disables overflow.
+	FallbackDepartmentID *uint `json:"fallback_department_id"`
+	OverflowAfterSeconds int   `gorm:"not null;default:0" json:"overflow_after_seconds"`
+
+	// Offline messages are emailed here, or to the department's agents when empty
+	Email string `gorm:"size:255" json:"email"`
+
+	// Business hours are evaluated in Timezone, an IANA name such as "Europe/Paris"
+	Timezone string           `gorm:"size:64;not null;default:UTC" json:"timezone"`
+	Hours    []*BusinessHours `gorm:"foreignKey:DepartmentID" json:"hours,omitempty"`
+	Holidays []*Holiday       `gorm:"foreignKey:DepartmentID" json:"holidays,omitempty"`
+
+	Rules     []*DepartmentRule `gorm:"foreignKey:DepartmentID" json:"rules,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// DepartmentAgent records that an agent answers chats for a department
+type DepartmentAgent struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	DepartmentID uint      `gorm:"not null;uniqueIndex:idx_department_agent" json:"department_id"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_department_agent;index" json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DepartmentRule sends chats to a department when the visitor did not pick one.
+// Empty conditions match anything; rules are tried by descending priority.
+type DepartmentRule struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	DepartmentID    uint      `gorm:"not null;index" json:"department_id"`
+	PageURLContains string    `gorm:"size:255" json:"page_url_contains"`
+	Language        string    `gorm:"size:10" json:"language"` // primary language subtag, e.g. "en"
+	Priority        int       `gorm:"not null;default:0" json:"priority"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Matches reports whether a chat from the given page and language satisfies the rule
+func (r *DepartmentRule) Matches(pageURL, language string) bool {
+	if r.PageURLContains != "" && !strings.Contains(strings.ToLower(pageURL), strings.ToLower(r.PageURLContains)) {
+		return false
+	}
+	if r.Language != "" {
+		// Compare the primary subtag only, so "en" matches "en-US"
+		primary, _, _ := strings.Cut(language, "-")
+		if !strings.EqualFold(primary, r.Language) {
+			return false
+		}
+	}
+	return true
+}
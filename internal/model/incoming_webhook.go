@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// IncomingWebhook lets an external system, such as CI or monitoring, post messages
+// into a group or direct conversation through a secret URL. Only a hash of the
+// URL's token is stored.
+type IncomingWebhook struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ConversationID uint       `gorm:"not null;index" json:"conversation_id"`
+	Name           string     `gorm:"size:100;not null" json:"name"` // shown as the sender of its messages
+	TokenHash      string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	RateLimit      int        `gorm:"not null" json:"rate_limit"` // messages per minute
+	CreatedByID    uint       `gorm:"not null" json:"created_by_id"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// IncomingWebhookUsage counts a webhook's posts in one minute for rate limiting
+type IncomingWebhookUsage struct {
+	HookID      uint      `gorm:"primaryKey;autoIncrement:false"`
+	WindowStart time.Time `gorm:"primaryKey;index"`
+	Count       int64     `gorm:"not null"`
+}
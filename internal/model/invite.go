@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Join request statuses
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestDeclined = "declined"
+)
+
+// ConversationInvite is a shareable link that lets users join a group
+type ConversationInvite struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	ConversationID   uint       `gorm:"not null;index" json:"conversation_id"`
+	Code             string     `gorm:"size:64;uniqueIndex;not null" json:"code"`
+	CreatedBy        uint       `gorm:"not null" json:"created_by"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	MaxUses          int        `gorm:"not null;default:0" json:"max_uses"` // 0 means unlimited
+	Uses             int        `gorm:"not null;default:0" json:"uses"`
+	RequiresApproval bool       `gorm:"not null;default:false" json:"requires_approval"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// JoinRequest is queued when a user follows an invite that requires admin approval
+type JoinRequest struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"not null;index:idx_join_request_lookup" json:"conversation_id"`
+	UserID         uint      `gorm:"not null;index:idx_join_request_lookup" json:"user_id"`
+	InviteID       uint      `gorm:"not null" json:"invite_id"`
+	Status         string    `gorm:"size:20;not null;default:pending;index:idx_join_request_lookup" json:"status"`
+	ReviewedBy     uint      `json:"reviewed_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// EmailInvite records an invitation sent to an address with no account yet. It
+// is redeemed with the token from the email, which proves the invitee reads that
+// mailbox; registering with the address alone joins nothing.
+type EmailInvite struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ConversationID uint       `gorm:"not null;index" json:"conversation_id"`
+	Email          string     `gorm:"size:100;not null;index" json:"email"`
+	TokenHash      string     `gorm:"size:64;index" json:"-"`
+	InvitedBy      uint       `gorm:"not null" json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// IsUsable reports whether the invite can still be redeemed at the given time
+func (i *ConversationInvite) IsUsable(now time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(now) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Message types
+const (
+	MessageTypeText    = "text"
+	MessageTypeSystem  = "system"
+	MessageTypeBot     = "bot"     // sent by the support auto-responder
+	MessageTypeWebhook = "webhook" // posted by an external system through an incoming webhook
+	MessageTypeCommand = "command" // a slash command's public reply
+	MessageTypePoll    = "poll"    // carries a poll; the content is its question
+)
+
+type Message struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ConversationID uint       `gorm:"not null;index" json:"conversation_id"`
+	SenderID       uint       `gorm:"index" json:"sender_id"`            // 0 for system and visitor messages
+	VisitorID      *uint      `gorm:"index" json:"visitor_id,omitempty"` // set when a website visitor sent the message
+	Type           string     `gorm:"size:20;not null;default:text" json:"type"`
+	Content        string     `gorm:"type:text;not null" json:"content"`
+	Internal       bool       `gorm:"not null;default:false" json:"internal,omitempty"` // agent-only whispers and events
+	PollID         *uint      `gorm:"index" json:"poll_id,omitempty"`                   // set on poll messages
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at,omitempty"`                // set while the conversation has disappearing messages on
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Incoming webhook and slash command messages only
+	IncomingWebhookID *uint               `gorm:"index" json:"incoming_webhook_id,omitempty"`
+	SenderName        string              `gorm:"size:100" json:"sender_name,omitempty"`
+	Attachments       []MessageAttachment `gorm:"type:text;serializer:json" json:"attachments,omitempty"`
+}
+
+// MessageAttachment links to a file or page hosted elsewhere; only its URL and
+// description are stored
+type MessageAttachment struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Offline message statuses
+const (
+	OfflineMessageOpen     = "open"
+	OfflineMessageResolved = "resolved"
+)
+
+// OfflineMessage is a message a visitor left while support was closed. Agents
+// follow up by email and resolve it like a ticket.
+type OfflineMessage struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	DepartmentID *uint      `gorm:"index" json:"department_id"` // nil for the general queue
+	VisitorID    uint       `gorm:"not null;index" json:"visitor_id"`
+	Name         string     `gorm:"size:100" json:"name"`
+	Email        string     `gorm:"size:100;not null" json:"email"`
+	Message      string     `gorm:"type:text;not null" json:"message"`
+	PageURL      string     `gorm:"size:500" json:"page_url"`
+	Status       string     `gorm:"size:20;not null;default:open;index" json:"status"`
+	ResolvedByID *uint      `json:"resolved_by_id"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// PinnedMessage is a message pinned for everyone in a conversation
+type PinnedMessage struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ConversationID uint      `gorm:"not null;index" json:"conversation_id"`
+	MessageID      uint      `gorm:"not null;uniqueIndex" json:"message_id"`
+	PinnedBy       uint      `gorm:"not null" json:"pinned_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	Message        *Message  `gorm:"foreignKey:MessageID" json:"message,omitempty"`
+}
+
+// Bookmark is a message a user saved privately for later
+type Bookmark struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_bookmark_user_message" json:"user_id"`
+	MessageID uint      `gorm:"not null;uniqueIndex:idx_bookmark_user_message" json:"message_id"`
+	Note      string    `gorm:"size:255" json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+	Message   *Message  `gorm:"foreignKey:MessageID" json:"message,omitempty"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Poll is a question members vote on, posted as a poll message
+type Poll struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ConversationID uint       `gorm:"not null;index" json:"conversation_id"`
+	CreatorID      uint       `gorm:"not null" json:"creator_id"`
+	Question       string     `gorm:"size:300;not null" json:"question"`
+	MultipleChoice bool       `gorm:"not null;default:false" json:"multiple_choice"`
+	Anonymous      bool       `gorm:"not null;default:false" json:"anonymous"` // hides who voted for what from everyone
+	ClosesAt       *time.Time `gorm:"index" json:"closes_at,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// PollOption is one of a poll's answers
+type PollOption struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	PollID   uint   `gorm:"not null;index" json:"poll_id"`
+	Position int    `gorm:"not null" json:"position"`
+	Text     string `gorm:"size:100;not null" json:"text"`
+}
+
+// PollVote is a member's vote for one option
+type PollVote struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PollID    uint      `gorm:"not null;uniqueIndex:idx_poll_vote,priority:1" json:"poll_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_poll_vote,priority:2" json:"user_id"`
+	OptionID  uint      `gorm:"not null;uniqueIndex:idx_poll_vote,priority:3;index" json:"option_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PollBallot is one row per member who voted in a poll. Upserting it locks the
+// row, which serializes a member's concurrent votes in the same poll.
+type PollBallot struct {
+	PollID    uint `gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint `gorm:"primaryKey;autoIncrement:false"`
+	UpdatedAt time.Time
+}
+
+// IsOpen reports whether the poll still accepts votes at the given time
+func (p *Poll) IsOpen(now time.Time) bool {
+	return p.ClosedAt == nil && (p.ClosesAt == nil || p.ClosesAt.After(now))
+}
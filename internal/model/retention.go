@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// RetentionPolicy deletes messages older than MaxAgeDays, in every conversation
+// or only in conversations of one type
+type RetentionPolicy struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Name             string    `gorm:"size:100;not null" json:"name"`
+	ConversationType string    `gorm:"size:20" json:"conversation_type,omitempty"` // empty for every conversation
+	MaxAgeDays       int       `gorm:"not null" json:"max_age_days"`
+	KeepPinned       bool      `gorm:"not null" json:"keep_pinned"`
+	Enabled          bool      `gorm:"not null" json:"enabled"`
+	CreatedByID      uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// PurgeRun records what one run of the message purge job deleted. Runs that
+// found nothing to delete are not recorded.
+type PurgeRun struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	StartedAt         time.Time `gorm:"not null;index" json:"started_at"`
+	FinishedAt        time.Time `gorm:"not null" json:"finished_at"`
+	Batches           int       `gorm:"not null" json:"batches"`
+	ExpiredMessages   int64     `gorm:"not null" json:"expired_messages"`   // disappearing messages
+	RetentionMessages int64     `gorm:"not null" json:"retention_messages"` // messages past a retention policy
+	Attachments       int64     `gorm:"not null" json:"attachments"`
+	Pins              int64     `gorm:"not null" json:"pins"`
+	Bookmarks         int64     `gorm:"not null" json:"bookmarks"`
+	Polls             int64     `gorm:"not null" json:"polls"`
+	Error             string    `gorm:"size:255" json:"error,omitempty"`
+}
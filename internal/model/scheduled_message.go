@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Scheduled message statuses
+const (
+	ScheduledPending   = "pending"
+	ScheduledSending   = "sending" // claimed by a dispatcher; failed if it stops before finishing
+	ScheduledSent      = "sent"
+	ScheduledCancelled = "cancelled"
+	ScheduledFailed    = "failed"
+)
+
+// ScheduledMessage is written now and delivered through the normal send path at SendAt
+type ScheduledMessage struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ConversationID uint       `gorm:"not null;index" json:"conversation_id"`
+	SenderID       uint       `gorm:"not null;index" json:"sender_id"`
+	Content        string     `gorm:"type:text;not null" json:"content"`
+	SendAt         time.Time  `gorm:"not null;index:idx_scheduled_due,priority:2" json:"send_at"`
+	Status         string     `gorm:"size:20;not null;default:pending;index:idx_scheduled_due,priority:1" json:"status"`
+	ClaimedAt      *time.Time `json:"claimed_at,omitempty"`
+	MessageID      *uint      `gorm:"index" json:"message_id"`
+	Error          string     `gorm:"size:255" json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// SLA metrics
+const (
+	SLAFirstResponse = "first_response" // until an agent first replies to the visitor
+	SLAResolution    = "resolution"     // until the chat is closed
+)
+
+// SLA timer statuses
+const (
+	SLATimerRunning   = "running"
+	SLATimerMet       = "met"
+	SLATimerBreached  = "breached"
+	SLATimerCancelled = "cancelled" // the chat ended or lost its policy before the target was due
+)
+
+// SLAPolicy sets the response targets of support chats of one priority in a
+// department. A policy without a department applies to the general queue and to
+// departments without a policy of their own for that priority.
+type SLAPolicy struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	DepartmentID         *uint     `gorm:"uniqueIndex:idx_sla_policy" json:"department_id"`
+	Priority             string    `gorm:"size:10;not null;uniqueIndex:idx_sla_policy" json:"priority"`
+	FirstResponseSeconds int       `gorm:"not null;default:0" json:"first_response_seconds"` // 0 disables the target
+	ResolutionSeconds    int       `gorm:"not null;default:0" json:"resolution_seconds"`     // 0 disables the target
+	WarningPercent       int       `gorm:"not null;default:80" json:"warning_percent"`       // share of the target after which agents are warned
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// Target returns the policy's target for the metric, 0 when it has none
+func (p *SLAPolicy) Target(metric string) time.Duration {
+	switch metric {
+	case SLAFirstResponse:
+		return time.Duration(p.FirstResponseSeconds) * time.Second
+	case SLAResolution:
+		return time.Duration(p.ResolutionSeconds) * time.Second
+	}
+	return 0
+}
+
+// SLATimer tracks one target of a support chat. WarnAt and DueAt are counted in
+// the department's business hours, so the timer pauses while it is closed.
+type SLATimer struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ConversationID uint       `gorm:"not null;uniqueIndex:idx_sla_timer" json:"conversation_id"`
+	Metric         string     `gorm:"size:20;not null;uniqueIndex:idx_sla_timer" json:"metric"`
+	PolicyID       uint       `gorm:"not null;index" json:"policy_id"`
+	TargetSeconds  int        `gorm:"not null" json:"target_seconds"`
+	Status         string     `gorm:"size:20;not null;index" json:"status"`
+	StartedAt      time.Time  `json:"started_at"`
+	WarnAt         time.Time  `json:"warn_at"`
+	DueAt          time.Time  `gorm:"index" json:"due_at"`
+	WarnedAt       *time.Time `json:"warned_at,omitempty"`
+	BreachedAt     *time.Time `json:"breached_at,omitempty"`
+	CompletedAt    *time.Time `gorm:"index" json:"completed_at,omitempty"` // when the agent replied or the chat closed
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// SLABreach records a missed target for reporting. CompletedAt is set once the
+// chat eventually got its reply or was closed.
+type SLABreach struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	TimerID        uint       `gorm:"not null;uniqueIndex" json:"timer_id"`
+	ConversationID uint       `gorm:"not null;index" json:"conversation_id"`
+	Metric         string     `gorm:"size:20;not null;index" json:"metric"`
+	Priority       string     `gorm:"size:10;not null" json:"priority"`
+	DepartmentID   *uint      `gorm:"index" json:"department_id,omitempty"`
+	AgentID        *uint      `gorm:"index" json:"agent_id,omitempty"` // the assigned agent when the target was missed
+	TargetSeconds  int        `gorm:"not null" json:"target_seconds"`
+	DueAt          time.Time  `json:"due_at"`
+	BreachedAt     time.Time  `gorm:"index" json:"breached_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Slash command reply visibilities
+const (
+	CommandReplyEphemeral = "ephemeral"  // shown only to the user who ran the command
+	CommandReplyInChannel = "in_channel" // posted to the conversation
+)
+
+// SlashCommand is an admin-registered command, such as /deploy, that is handled
+// by an external endpoint. Requests to the endpoint are signed with the secret.
+type SlashCommand struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:32;not null;uniqueIndex" json:"name"` // without the leading slash
+	Description string    `gorm:"size:255" json:"description"`
+	UsageHint   string    `gorm:"size:100" json:"usage_hint"`
+	URL         string    `gorm:"size:500;not null" json:"url"`
+	Secret      string    `gorm:"size:64;not null" json:"-"`
+	Enabled     bool      `gorm:"not null" json:"enabled"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
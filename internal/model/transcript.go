@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Transcript formats
+const (
+	TranscriptText = "txt"
+	TranscriptHTML = "html"
+	TranscriptJSON = "json"
+)
+
+// Transcript export statuses
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+)
+
+// TranscriptExport is a conversation transcript generated in the background,
+// either for download through a signed link or to be emailed to the visitor
+type TranscriptExport struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	ConversationID  uint       `gorm:"not null;index" json:"conversation_id"`
+	RequestedByID   *uint      `gorm:"index" json:"requested_by_id,omitempty"`
+	VisitorID       *uint      `gorm:"index" json:"visitor_id,omitempty"` // set when the visitor asked for it
+	Format          string     `gorm:"size:10;not null" json:"format"`
+	IncludeInternal bool       `gorm:"not null;default:false" json:"include_internal"`
+	EmailTo         string     `gorm:"size:100" json:"email_to,omitempty"`
+	Status          string     `gorm:"size:20;not null;index" json:"status"`
+	Content         string     `gorm:"type:longtext" json:"-"`
+	Size            int        `gorm:"not null;default:0" json:"size"`
+	Error           string     `gorm:"size:255" json:"error,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt       time.Time  `gorm:"index" json:"expires_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	DownloadURL string `gorm:"-" json:"download_url,omitempty"` // signed link, set once ready
+}
@@ -2,13 +2,121 @@ package model
 
 import "gorm.io/gorm"
 
+// Direct message privacy settings
+const (
+	DMPrivacyEveryone = "everyone"
+	DMPrivacyContacts = "contacts"
+)
+
+// User roles
+const (
+	UserRoleUser  = "user"
+	UserRoleAgent = "agent" // member of the support team
+	UserRoleAdmin = "admin"
+
+	// UserRoleSupervisor oversees the support team without taking chats
+	UserRoleSupervisor = "supervisor"
+)
+
+// User types
+const (
+	UserTypeHuman = "human"
+
+	// UserTypeBot is an integration account that signs in with API keys only
+	UserTypeBot = "bot"
+)
+
 type User struct {
-	ID       uint   `gorm:"primaryKey"`
-	Name     string `gorm:"size:100;not null"`
-	Email    string `gorm:"size:100;uniqueIndex;not null"`
-	Password string `gorm:"size:255;not null"`
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"size:100;not null"`
+	Email     string `gorm:"size:100;uniqueIndex;not null"`
+	Password  string `gorm:"size:255;not null"`
+	DMPrivacy string `gorm:"size:20;not null;default:everyone"` // who may start a direct conversation
+	Role      string `gorm:"size:20;not null;default:user;index"`
+	Type      string `gorm:"size:20;not null;default:human;index"`
+}
+
+// IsBot reports whether the user is an integration account
+func (u *User) IsBot() bool {
+	return u.Type == UserTypeBot
+}
+
+// IsAgent reports whether the user answers support chats
+func (u *User) IsAgent() bool {
+	return u.Role == UserRoleAgent
+}
+
+// IsAdmin reports whether the user manages the workspace
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// IsSupervisor reports whether the user oversees the support team
+func (u *User) IsSupervisor() bool {
+	return u.Role == UserRoleSupervisor
+}
+
+// CanSupervise reports whether the user may monitor the support team and its reports
+func (u *User) CanSupervise() bool {
+	return CanSupervise(u.Role)
+}
+
+// IsStaff reports whether the user may see agent-only content in support chats
+func (u *User) IsStaff() bool {
+	return u.IsAgent() || u.IsAdmin() || u.IsSupervisor()
+}
+
+// CanSupervise reports whether users with the role may monitor the support team
+func CanSupervise(role string) bool {
+	return role == UserRoleSupervisor || role == UserRoleAdmin
 }
 
 func AutoMigrate(db *gorm.DB) {
-	db.AutoMigrate(&User{})
+	db.AutoMigrate(
+		&User{},
+		&Conversation{},
+		&ConversationMember{},
+		&Message{},
+		&ConversationInvite{},
+		&JoinRequest{},
+		&EmailInvite{},
+		&UserBlock{},
+		&ContactRequest{},
+		&Contact{},
+		&PinnedMessage{},
+		&Bookmark{},
+		&ScheduledMessage{},
+		&Visitor{},
+		&AgentProfile{},
+		&Department{},
+		&DepartmentAgent{},
+		&DepartmentRule{},
+		&BusinessHours{},
+		&Holiday{},
+		&OfflineMessage{},
+		&CannedResponse{},
+		&ChatRating{},
+		&TranscriptExport{},
+		&SLAPolicy{},
+		&SLATimer{},
+		&SLABreach{},
+		&AnalyticsHour{},
+		&AnalyticsUserHour{},
+		&BotFlow{},
+		&BotSession{},
+		&APIKey{},
+		&APIKeyUsage{},
+		&WebhookEndpoint{},
+		&WebhookDelivery{},
+		&WebhookAttempt{},
+		&IncomingWebhook{},
+		&IncomingWebhookUsage{},
+		&SlashCommand{},
+		&Poll{},
+		&PollOption{},
+		&PollVote{},
+		&PollBallot{},
+		&RetentionPolicy{},
+		&PurgeRun{},
+	)
 }
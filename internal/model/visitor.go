@@ -0,0 +1,27 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Visitor is an anonymous website visitor chatting through the embeddable widget
+type Visitor struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"size:100" json:"name"`
+	Email      string    `gorm:"size:100;index" json:"email"`
+	UserID     *uint     `gorm:"index" json:"user_id"` // set once the visitor is linked to a registered account
+	PageURL    string    `gorm:"size:500" json:"page_url"`
+	Language   string    `gorm:"size:20" json:"language"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// DisplayName returns the name the visitor gave, or a numbered placeholder
+func (v *Visitor) DisplayName() string {
+	if v.Name != "" {
+		return v.Name
+	}
+	return fmt.Sprintf("Visitor #%d", v.ID)
+}
@@ -0,0 +1,89 @@
+package model
+
+import "time"
+
+// Webhook event types
+const (
+	WebhookMessageCreated      = "message.created"
+	WebhookConversationCreated = "conversation.created"
+	WebhookConversationClosed  = "conversation.closed"
+	WebhookUserRegistered      = "user.registered"
+
+	// WebhookPing is sent on request to check an endpoint; it needs no subscription
+	WebhookPing = "webhook.ping"
+)
+
+// WebhookEvents lists the event types endpoints may subscribe to
+var WebhookEvents = []string{WebhookMessageCreated, WebhookConversationCreated, WebhookConversationClosed, WebhookUserRegistered}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead" // gave up after the last retry; can be redelivered by hand
+)
+
+// WebhookEndpoint receives the events it subscribed to as signed JSON POSTs
+type WebhookEndpoint struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	URL         string    `gorm:"size:500;not null" json:"url"`
+	Description string    `gorm:"size:255" json:"description"`
+	Secret      string    `gorm:"size:64;not null" json:"-"` // signs the payloads with HMAC-SHA256
+	Events      []string  `gorm:"type:text;serializer:json" json:"events"`
+	Enabled     bool      `gorm:"not null;index" json:"enabled"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event queued for one endpoint. Failed attempts are
+// retried with exponential backoff until the delivery is dead-lettered.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	EndpointID     uint       `gorm:"not null;index" json:"endpoint_id"`
+	EventID        string     `gorm:"size:40;not null;index" json:"event_id"`
+	EventType      string     `gorm:"size:50;not null" json:"event_type"`
+	Payload        string     `gorm:"type:mediumtext;not null" json:"payload"`
+	MessageID      *uint      `gorm:"index" json:"message_id,omitempty"` // the message a message.created event carries; purged with it
+	Status         string     `gorm:"size:20;not null;index:idx_webhook_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_webhook_due,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `gorm:"size:255" json:"error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	RedeliveryOf   *uint      `json:"redelivery_of,omitempty"` // the delivery this one resends
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebhookAttempt logs one try at a delivery
+type WebhookAttempt struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	DeliveryID     uint      `gorm:"not null;index" json:"delivery_id"`
+	Attempt        int       `gorm:"not null" json:"attempt"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	ResponseBody   string    `gorm:"size:1000" json:"response_body,omitempty"`
+	Error          string    `gorm:"size:255" json:"error,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the endpoint wants events of the type
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	for _, event := range e.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidWebhookEvent reports whether endpoints may subscribe to the event type
+func IsValidWebhookEvent(eventType string) bool {
+	for _, event := range WebhookEvents {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package realtime
+
+// Event types published to subscribers
+const (
+	EventMessageCreated   = "message.created"
+	EventMessageEphemeral = "message.ephemeral" // only shown to the user it is sent to
+	EventNotification     = "notification"
+	EventMessagePinned    = "message.pinned"
+	EventMessageUnpinned  = "message.unpinned"
+	EventMessagesDeleted  = "messages.deleted" // disappearing or past retention
+	EventTyping           = "typing"
+	EventPresence         = "presence"
+
+	EventPollUpdated = "poll.updated"
+	EventPollClosed  = "poll.closed"
+
+	EventConversationCreated = "conversation.created"
+	EventConversationUpdated = "conversation.updated"
+	EventMembershipUpdated   = "conversation.membership_updated"
+	EventConversationClosed  = "conversation.closed"
+
+	EventMemberAdded       = "conversation.member_added"
+	EventMemberRemoved     = "conversation.member_removed"
+	EventMemberLeft        = "conversation.member_left"
+	EventMemberRoleChanged = "conversation.member_role_changed"
+	EventOwnerTransferred  = "conversation.owner_transferred"
+
+	EventJoinRequested       = "conversation.join_requested"
+	EventJoinRequestApproved = "conversation.join_request_approved"
+	EventJoinRequestDeclined = "conversation.join_request_declined"
+
+	EventChatAssigned  = "support.chat_assigned"
+	EventChatRequeued  = "support.chat_requeued"
+	EventQueuePosition = "support.queue_position"
+	EventAgentStatus   = "support.agent_status"
+
+	EventChatDepartmentChanged = "support.chat_department_changed"
+	EventChatTransferred       = "support.chat_transferred"
+
+	EventOfflineMessageReceived = "support.offline_message_received"
+
+	EventSLAWarning  = "support.sla_warning"
+	EventSLABreached = "support.sla_breached"
+
+	EventContactRequestReceived  = "contact.request_received"
+	EventContactRequestAccepted  = "contact.request_accepted"
+	EventContactRequestDeclined  = "contact.request_declined"
+	EventContactRequestCancelled = "contact.request_cancelled"
+)
@@ -0,0 +1,91 @@
+package realtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Event is a real-time notification pushed to subscribers
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Hub fans events out to in-process subscribers keyed by topic
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+	bufferSize  int
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[chan Event]struct{}),
+		bufferSize:  32,
+	}
+}
+
+// UserTopic returns the topic a registered user listens on
+func UserTopic(userId uint) string {
+	return fmt.Sprintf("user:%d", userId)
+}
+
+// VisitorTopic returns the topic an anonymous website visitor listens on
+func VisitorTopic(visitorId uint) string {
+	return fmt.Sprintf("visitor:%d", visitorId)
+}
+
+// Subscribe registers a new listener on the topic. The returned function must be
+// called to release the subscription.
+func (h *Hub) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, h.bufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[chan Event]struct{})
+	}
+	h.subscribers[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if subs, ok := h.subscribers[topic]; ok {
+			if _, ok := subs[ch]; ok {
+				delete(subs, ch)
+				close(ch)
+			}
+			if len(subs) == 0 {
+				delete(h.subscribers, topic)
+			}
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers the event to every subscriber of the topic. Slow subscribers
+// whose buffer is full miss the event rather than blocking the publisher.
+func (h *Hub) Publish(topic string, event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// PublishToUsers delivers the event to each user's topic
+func (h *Hub) PublishToUsers(userIds []uint, event Event) {
+	for _, id := range userIds {
+		h.Publish(UserTopic(id), event)
+	}
+}
+
+// IsOnline reports whether the topic has at least one active subscriber
+func (h *Hub) IsOnline(topic string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[topic]) > 0
+}
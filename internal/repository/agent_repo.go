@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AgentRepository struct {
+	db *gorm.DB
+}
+
+func NewAgentRepository(db *gorm.DB) *AgentRepository {
+	return &AgentRepository{db: db}
+}
+
+// Ensure creates the agent's routing profile if it does not exist yet
+func (r *AgentRepository) Ensure(userId uint) error {
+	profile := &model.AgentProfile{
+		UserID:     userId,
+		Status:     model.AgentStatusOffline,
+		MaxChats:   model.DefaultAgentMaxChats,
+		LastSeenAt: time.Now(),
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(profile).Error
+}
+
+func (r *AgentRepository) FindByUser(userId uint) (*model.AgentProfile, error) {
+	var profile model.AgentProfile
+	err := r.db.First(&profile, "user_id = ?", userId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *AgentRepository) FindAll() ([]*model.AgentProfile, error) {
+	var profiles []*model.AgentProfile
+	err := r.db.Order("user_id ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *AgentRepository) Update(userId uint, fields map[string]interface{}) error {
+	return r.db.Model(&model.AgentProfile{}).Where("user_id = ?", userId).Updates(fields).Error
+}
+
+func (r *AgentRepository) Delete(userId uint) error {
+	return r.db.Where("user_id = ?", userId).Delete(&model.AgentProfile{}).Error
+}
+
+// Candidates returns online agents with free capacity in the order they should be
+// offered the next chat, limited to the department's agents when departmentId is set.
+// Least-busy prefers the fewest active chats; otherwise the agent who was assigned
+// longest ago comes first (round-robin).
+func (r *AgentRepository) Candidates(leastBusy bool, departmentId *uint, limit int) ([]*model.AgentProfile, error) {
+	var profiles []*model.AgentProfile
+	query := r.db.Where("agent_profiles.status = ? AND agent_profiles.active_chats < agent_profiles.max_chats", model.AgentStatusOnline)
+	if departmentId != nil {
+		query = query.Joins("JOIN department_agents ON department_agents.user_id = agent_profiles.user_id AND department_agents.department_id = ?", *departmentId)
+	}
+	if leastBusy {
+		query = query.Order("agent_profiles.active_chats ASC")
+	}
+	err := query.
+		Order("agent_profiles.last_assigned_at IS NOT NULL").
+		Order("agent_profiles.last_assigned_at ASC").
+		Limit(limit).
+		Find(&profiles).Error
+	return profiles, err
+}
+
+// Claim takes one chat slot of the agent if they are online and below capacity.
+// The check and increment happen in one statement so concurrent routers cannot
+// push an agent over their limit.
+func (r *AgentRepository) Claim(userId uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.AgentProfile{}).
+		Where("user_id = ? AND status = ? AND active_chats < max_chats", userId, model.AgentStatusOnline).
+		Updates(map[string]interface{}{
+			"active_chats":     gorm.Expr("active_chats + 1"),
+			"last_assigned_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release frees one chat slot of the agent
+func (r *AgentRepository) Release(userId uint) error {
+	return r.db.Model(&model.AgentProfile{}).
+		Where("user_id = ? AND active_chats > 0", userId).
+		Update("active_chats", gorm.Expr("active_chats - 1")).Error
+}
+
+// MarkOffline switches an agent to offline and clears their load. It reports false
+// when the agent was already offline, so only one caller reassigns their chats.
+func (r *AgentRepository) MarkOffline(userId uint) (bool, error) {
+	result := r.db.Model(&model.AgentProfile{}).
+		Where("user_id = ? AND status <> ?", userId, model.AgentStatusOffline).
+		Updates(map[string]interface{}{"status": model.AgentStatusOffline, "active_chats": 0})
+	return result.RowsAffected > 0, result.Error
+}
+
+// StaleIds returns agents that are not offline but have not been seen since before
+func (r *AgentRepository) StaleIds(before time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.AgentProfile{}).
+		Where("status <> ? AND last_seen_at < ?", model.AgentStatusOffline, before).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IAgentRepository interface {
+	Ensure(userId uint) error
+	FindByUser(userId uint) (*model.AgentProfile, error)
+	FindAll() ([]*model.AgentProfile, error)
+	Update(userId uint, fields map[string]interface{}) error
+	Delete(userId uint) error
+	Candidates(leastBusy bool, departmentId *uint, limit int) ([]*model.AgentProfile, error)
+	Claim(userId uint, now time.Time) (bool, error)
+	Release(userId uint) error
+	MarkOffline(userId uint) (bool, error)
+	StaleIds(before time.Time) ([]uint, error)
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// RollupHour computes the activity of the hour starting at hour and stores it,
+// replacing any earlier rollup of that hour. Running it again gives the same
+// result, so concurrent replicas may roll up the same hour.
+func (r *AnalyticsRepository) RollupHour(hour time.Time, final bool, now time.Time) error {
+	end := hour.Add(time.Hour)
+	rollup := &model.AnalyticsHour{Hour: hour, Final: final, ComputedAt: now}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		messages := tx.Model(&model.Message{}).Where("created_at >= ? AND created_at < ? AND type <> ?", hour, end, model.MessageTypeSystem)
+		if err := messages.Count(&rollup.Messages).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Message{}).
+			Where("created_at >= ? AND created_at < ? AND visitor_id IS NOT NULL", hour, end).
+			Count(&rollup.VisitorMessages).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Conversation{}).
+			Where("created_at >= ? AND created_at < ?", hour, end).
+			Count(&rollup.ConversationsStarted).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Conversation{}).
+			Where("created_at >= ? AND created_at < ? AND type = ?", hour, end, model.ConversationTypeSupport).
+			Count(&rollup.SupportChatsStarted).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Conversation{}).
+			Where("closed_at >= ? AND closed_at < ? AND type = ?", hour, end, model.ConversationTypeSupport).
+			Count(&rollup.SupportChatsClosed).Error; err != nil {
+			return err
+		}
+
+		// A reply is the first one when no earlier reply exists in its support chat
+		var responses struct {
+			Count int64
+			Total int64
+		}
+		if err := tx.Raw(`SELECT COUNT(*) AS count, COALESCE(SUM(TIMESTAMPDIFF(SECOND, c.created_at, m.created_at)), 0) AS total
+			FROM messages m JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.type = ? AND m.created_at >= ? AND m.created_at < ?
+				AND m.sender_id <> 0 AND m.type = ? AND m.internal = ?
+				AND NOT EXISTS (SELECT 1 FROM messages p WHERE p.conversation_id = m.conversation_id
+					AND p.sender_id <> 0 AND p.type = ? AND p.internal = ? AND p.id < m.id)`,
+			model.ConversationTypeSupport, hour, end, model.MessageTypeText, false, model.MessageTypeText, false).
+			Scan(&responses).Error; err != nil {
+			return err
+		}
+		rollup.FirstResponses = responses.Count
+		rollup.FirstResponseSeconds = responses.Total
+
+		var ratings struct {
+			Count    int64
+			Total    int64
+			Positive int64
+		}
+		if err := tx.Model(&model.ChatRating{}).
+			Select("COUNT(*) AS count, COALESCE(SUM(score), 0) AS total, COALESCE(SUM(CASE WHEN score >= 4 THEN 1 ELSE 0 END), 0) AS positive").
+			Where("created_at >= ? AND created_at < ?", hour, end).
+			Scan(&ratings).Error; err != nil {
+			return err
+		}
+		rollup.Ratings = ratings.Count
+		rollup.RatingScoreSum = ratings.Total
+		rollup.PositiveRatings = ratings.Positive
+
+		if err := tx.Exec(`INSERT IGNORE INTO analytics_user_hours (hour, user_id)
+			SELECT DISTINCT ?, sender_id FROM messages
+			WHERE created_at >= ? AND created_at < ? AND sender_id <> 0 AND type <> ?`,
+			hour, hour, end, model.MessageTypeSystem).Error; err != nil {
+			return err
+		}
+
+		// A provisional rollup from a slower replica must not undo a final one
+		updates := append(clause.Set{{Column: clause.Column{Name: "final"}, Value: gorm.Expr("final OR VALUES(final)")}},
+			clause.AssignmentColumns([]string{"messages", "visitor_messages", "conversations_started", "support_chats_started", "support_chats_closed", "first_responses", "first_response_seconds", "ratings", "rating_score_sum", "positive_ratings", "computed_at"})...)
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hour"}},
+			DoUpdates: updates,
+		}).Create(rollup).Error
+	})
+}
+
+// LastFinalHour returns the latest hour whose rollup is final, or nil before the first rollup
+func (r *AnalyticsRepository) LastFinalHour() (*time.Time, error) {
+	var hour sql.NullTime
+	err := r.db.Model(&model.AnalyticsHour{}).Where("final = ?", true).Select("MAX(hour)").Scan(&hour).Error
+	if err != nil || !hour.Valid {
+		return nil, err
+	}
+	return &hour.Time, nil
+}
+
+// FirstActivity returns when the first conversation was started, or nil on an empty database
+func (r *AnalyticsRepository) FirstActivity() (*time.Time, error) {
+	var first sql.NullTime
+	err := r.db.Model(&model.Conversation{}).Select("MIN(created_at)").Scan(&first).Error
+	if err != nil || !first.Valid {
+		return nil, err
+	}
+	return &first.Time, nil
+}
+
+// FindHours returns the rollups of the hours from from up to to, oldest first
+func (r *AnalyticsRepository) FindHours(from, to time.Time) ([]*model.AnalyticsHour, error) {
+	var hours []*model.AnalyticsHour
+	err := r.db.Where("hour >= ? AND hour < ?", from, to).Order("hour ASC").Find(&hours).Error
+	return hours, err
+}
+
+// EachUserHour calls fn for every hour from from up to to in which a user posted,
+// reading the rows one at a time
+func (r *AnalyticsRepository) EachUserHour(from, to time.Time, fn func(hour time.Time, userId uint)) error {
+	rows, err := r.db.Model(&model.AnalyticsUserHour{}).
+		Select("hour, user_id").
+		Where("hour >= ? AND hour < ?", from, to).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hour time.Time
+		var userId uint
+		if err := rows.Scan(&hour, &userId); err != nil {
+			return err
+		}
+		fn(hour, userId)
+	}
+	return rows.Err()
+}
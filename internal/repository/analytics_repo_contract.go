@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IAnalyticsRepository interface {
+	RollupHour(hour time.Time, final bool, now time.Time) error
+	LastFinalHour() (*time.Time, error)
+	FirstActivity() (*time.Time, error)
+	FindHours(from, to time.Time) ([]*model.AnalyticsHour, error)
+	EachUserHour(from, to time.Time, fn func(hour time.Time, userId uint)) error
+}
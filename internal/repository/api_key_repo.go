@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *APIKeyRepository) FindById(id int64) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.First(&key, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) FindByPrefix(prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindByBot returns the bot's keys, newest first, revoked ones included
+func (r *APIKeyRepository) FindByBot(botId uint) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	err := r.db.Where("bot_id = ?", botId).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke disables the key. It reports false when the key was already revoked.
+func (r *APIKeyRepository) Revoke(id int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// TouchLastUsed records that the key was used, at most once per interval so busy
+// keys do not write on every request
+func (r *APIKeyRepository) TouchLastUsed(id uint, now time.Time, interval time.Duration) error {
+	return r.db.Model(&model.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-interval)).
+		Update("last_used_at", now).Error
+}
+
+// Hit counts a request of the key in the window and returns the window's total so far
+func (r *APIKeyRepository) Hit(keyId uint, window time.Time) (int64, error) {
+	usage := &model.APIKeyUsage{KeyID: keyId, WindowStart: window, Count: 1}
+	err := r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("count + 1")}),
+	}).Create(usage).Error
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = r.db.Model(&model.APIKeyUsage{}).
+		Where("key_id = ? AND window_start = ?", keyId, window).
+		Pluck("count", &count).Error
+	return count, err
+}
+
+// PurgeUsage deletes the request counts of windows that started before the time
+func (r *APIKeyRepository) PurgeUsage(before time.Time) error {
+	return r.db.Where("window_start < ?", before).Delete(&model.APIKeyUsage{}).Error
+}
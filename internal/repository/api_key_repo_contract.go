@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IAPIKeyRepository interface {
+	Create(key *model.APIKey) error
+	FindById(id int64) (*model.APIKey, error)
+	FindByPrefix(prefix string) (*model.APIKey, error)
+	FindByBot(botId uint) ([]*model.APIKey, error)
+	Revoke(id int64, now time.Time) (bool, error)
+	TouchLastUsed(id uint, now time.Time, interval time.Duration) error
+	Hit(keyId uint, window time.Time) (int64, error)
+	PurgeUsage(before time.Time) error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type BlockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+func (r *BlockRepository) Create(block *model.UserBlock) error {
+	return r.db.Create(block).Error
+}
+
+// Delete removes the block and reports whether one existed
+func (r *BlockRepository) Delete(blockerId, blockedId uint) (bool, error) {
+	result := r.db.Where("blocker_id = ? AND blocked_id = ?", blockerId, blockedId).Delete(&model.UserBlock{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *BlockRepository) Exists(blockerId, blockedId uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.UserBlock{}).Where("blocker_id = ? AND blocked_id = ?", blockerId, blockedId).Count(&count).Error
+	return count > 0, err
+}
+
+// IsBlockedEither reports whether either user has blocked the other
+func (r *BlockRepository) IsBlockedEither(userId, otherUserId uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userId, otherUserId, otherUserId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *BlockRepository) FindByBlocker(blockerId uint) ([]*model.UserBlock, error) {
+	var blocks []*model.UserBlock
+	err := r.db.Where("blocker_id = ?", blockerId).Order("created_at DESC").Find(&blocks).Error
+	return blocks, err
+}
+
+// BlockedIds returns the users the blocker has blocked
+func (r *BlockRepository) BlockedIds(blockerId uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.UserBlock{}).Where("blocker_id = ?", blockerId).Pluck("blocked_id", &ids).Error
+	return ids, err
+}
+
+// BlockerIds returns the users who have blocked the given user
+func (r *BlockRepository) BlockerIds(blockedId uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.UserBlock{}).Where("blocked_id = ?", blockedId).Pluck("blocker_id", &ids).Error
+	return ids, err
+}
+
+// RelatedIds returns every user who has blocked, or been blocked by, the user
+func (r *BlockRepository) RelatedIds(userId uint) ([]uint, error) {
+	blocked, err := r.BlockedIds(userId)
+	if err != nil {
+		return nil, err
+	}
+	blockers, err := r.BlockerIds(userId)
+	if err != nil {
+		return nil, err
+	}
+	return append(blocked, blockers...), nil
+}
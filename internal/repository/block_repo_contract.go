@@ -0,0 +1,14 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IBlockRepository interface {
+	Create(block *model.UserBlock) error
+	Delete(blockerId, blockedId uint) (bool, error)
+	Exists(blockerId, blockedId uint) (bool, error)
+	IsBlockedEither(userId, otherUserId uint) (bool, error)
+	FindByBlocker(blockerId uint) ([]*model.UserBlock, error)
+	BlockedIds(blockerId uint) ([]uint, error)
+	BlockerIds(blockedId uint) ([]uint, error)
+	RelatedIds(userId uint) ([]uint, error)
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type BookmarkRepository struct {
+	db *gorm.DB
+}
+
+func NewBookmarkRepository(db *gorm.DB) *BookmarkRepository {
+	return &BookmarkRepository{db: db}
+}
+
+func (r *BookmarkRepository) Create(bookmark *model.Bookmark) error {
+	return r.db.Create(bookmark).Error
+}
+
+func (r *BookmarkRepository) Exists(userId uint, messageId int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Bookmark{}).Where("user_id = ? AND message_id = ?", userId, messageId).Count(&count).Error
+	return count > 0, err
+}
+
+// FindByUser returns a page of the user's bookmarks with their messages, newest first
+func (r *BookmarkRepository) FindByUser(userId uint, page int32, pageSize int32) ([]*model.Bookmark, error) {
+	var bookmarks []*model.Bookmark
+	offset := (page - 1) * pageSize
+	err := r.db.Preload("Message").
+		Where("user_id = ?", userId).
+		Order("created_at DESC").
+		Offset(int(offset)).Limit(int(pageSize)).
+		Find(&bookmarks).Error
+	return bookmarks, err
+}
+
+// Delete removes the bookmark and reports whether one existed
+func (r *BookmarkRepository) Delete(userId uint, messageId int64) (bool, error) {
+	result := r.db.Where("user_id = ? AND message_id = ?", userId, messageId).Delete(&model.Bookmark{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IBookmarkRepository interface {
+	Create(bookmark *model.Bookmark) error
+	Exists(userId uint, messageId int64) (bool, error)
+	FindByUser(userId uint, page int32, pageSize int32) ([]*model.Bookmark, error)
+	Delete(userId uint, messageId int64) (bool, error)
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type BotFlowRepository struct {
+	db *gorm.DB
+}
+
+func NewBotFlowRepository(db *gorm.DB) *BotFlowRepository {
+	return &BotFlowRepository{db: db}
+}
+
+func (r *BotFlowRepository) Create(flow *model.BotFlow) error {
+	return r.db.Create(flow).Error
+}
+
+func (r *BotFlowRepository) FindById(id int64) (*model.BotFlow, error) {
+	var flow model.BotFlow
+	err := r.db.First(&flow, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
+
+// FindAll returns every flow, default flows first and then by department
+func (r *BotFlowRepository) FindAll() ([]*model.BotFlow, error) {
+	var flows []*model.BotFlow
+	err := r.db.Order("department_id IS NOT NULL, department_id, id").Find(&flows).Error
+	return flows, err
+}
+
+// FindEnabled returns the enabled flow of the department, or the enabled default
+// flow when departmentId is nil
+func (r *BotFlowRepository) FindEnabled(departmentId *uint) (*model.BotFlow, error) {
+	var flow model.BotFlow
+	err := r.forDepartment(departmentId).Where("enabled = ?", true).Order("id DESC").First(&flow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &flow, nil
+}
+
+// EnabledTaken reports whether another flow is already enabled for the department,
+// or for the general queue when departmentId is nil
+func (r *BotFlowRepository) EnabledTaken(departmentId *uint, exceptId int64) (bool, error) {
+	var count int64
+	err := r.forDepartment(departmentId).Model(&model.BotFlow{}).
+		Where("enabled = ? AND id <> ?", true, exceptId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Save writes the flow's editable fields
+func (r *BotFlowRepository) Save(flow *model.BotFlow) error {
+	return r.db.Model(flow).Select("name", "department_id", "enabled", "definition").Updates(flow).Error
+}
+
+func (r *BotFlowRepository) Delete(id int64) error {
+	return r.db.Delete(&model.BotFlow{}, id).Error
+}
+
+func (r *BotFlowRepository) CreateSession(session *model.BotSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *BotFlowRepository) FindSession(conversationId int64) (*model.BotSession, error) {
+	var session model.BotSession
+	err := r.db.Where("conversation_id = ?", conversationId).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// AdvanceSession writes the session's new state if no other message advanced it
+// since it was read, and reports whether it did
+func (r *BotFlowRepository) AdvanceSession(session *model.BotSession) (bool, error) {
+	result := r.db.Model(&model.BotSession{}).
+		Where("id = ? AND step = ?", session.ID, session.Step).
+		Select("status", "intent", "field_index", "fallbacks", "data", "step").
+		Updates(&model.BotSession{
+			Status:     session.Status,
+			Intent:     session.Intent,
+			FieldIndex: session.FieldIndex,
+			Fallbacks:  session.Fallbacks,
+			Data:       session.Data,
+			Step:       session.Step + 1,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	session.Step++
+	return true, nil
+}
+
+func (r *BotFlowRepository) forDepartment(departmentId *uint) *gorm.DB {
+	if departmentId == nil {
+		return r.db.Where("department_id IS NULL")
+	}
+	return r.db.Where("department_id = ?", *departmentId)
+}
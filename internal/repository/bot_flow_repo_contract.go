@@ -0,0 +1,16 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IBotFlowRepository interface {
+	Create(flow *model.BotFlow) error
+	FindById(id int64) (*model.BotFlow, error)
+	FindAll() ([]*model.BotFlow, error)
+	FindEnabled(departmentId *uint) (*model.BotFlow, error)
+	EnabledTaken(departmentId *uint, exceptId int64) (bool, error)
+	Save(flow *model.BotFlow) error
+	Delete(id int64) error
+	CreateSession(session *model.BotSession) error
+	FindSession(conversationId int64) (*model.BotSession, error)
+	AdvanceSession(session *model.BotSession) (bool, error)
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type CannedResponseRepository struct {
+	db *gorm.DB
+}
+
+func NewCannedResponseRepository(db *gorm.DB) *CannedResponseRepository {
+	return &CannedResponseRepository{db: db}
+}
+
+func (r *CannedResponseRepository) Create(response *model.CannedResponse) error {
+	return r.db.Create(response).Error
+}
+
+func (r *CannedResponseRepository) FindById(id int64) (*model.CannedResponse, error) {
+	var response model.CannedResponse
+	err := r.db.First(&response, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// FindVisible returns the user's personal responses and the shared ones whose
+// shortcut starts with prefix, ordered by shortcut with personal ones first
+func (r *CannedResponseRepository) FindVisible(userId uint, prefix string, limit int) ([]*model.CannedResponse, error) {
+	var responses []*model.CannedResponse
+	query := r.db.Where("owner_id = ? OR owner_id IS NULL", userId)
+	if prefix != "" {
+		query = query.Where("shortcut LIKE ?", escapeLike(prefix)+"%")
+	}
+	err := query.Order("shortcut ASC, owner_id IS NULL ASC").Limit(limit).Find(&responses).Error
+	return responses, err
+}
+
+// FindByShortcut returns the response the user gets for the shortcut: their
+// personal one when they have it, the shared one otherwise
+func (r *CannedResponseRepository) FindByShortcut(userId uint, shortcut string) (*model.CannedResponse, error) {
+	var response model.CannedResponse
+	err := r.db.Where("(owner_id = ? OR owner_id IS NULL) AND shortcut = ?", userId, shortcut).
+		Order("owner_id IS NULL ASC").
+		First(&response).Error
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ShortcutTaken reports whether the owner, or the shared set when ownerId is nil,
+// already has another response with the shortcut
+func (r *CannedResponseRepository) ShortcutTaken(ownerId *uint, shortcut string, exceptId int64) (bool, error) {
+	var count int64
+	query := r.db.Model(&model.CannedResponse{}).Where("shortcut = ? AND id <> ?", shortcut, exceptId)
+	if ownerId == nil {
+		query = query.Where("owner_id IS NULL")
+	} else {
+		query = query.Where("owner_id = ?", *ownerId)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *CannedResponseRepository) Update(id int64, fields map[string]interface{}) error {
+	return r.db.Model(&model.CannedResponse{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *CannedResponseRepository) Delete(id int64) error {
+	return r.db.Delete(&model.CannedResponse{}, id).Error
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "go_starter/internal/model"
+
+type ICannedResponseRepository interface {
+	Create(response *model.CannedResponse) error
+	FindById(id int64) (*model.CannedResponse, error)
+	FindVisible(userId uint, prefix string, limit int) ([]*model.CannedResponse, error)
+	FindByShortcut(userId uint, shortcut string) (*model.CannedResponse, error)
+	ShortcutTaken(ownerId *uint, shortcut string, exceptId int64) (bool, error)
+	Update(id int64, fields map[string]interface{}) error
+	Delete(id int64) error
+}
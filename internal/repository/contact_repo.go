@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ContactRepository struct {
+	db *gorm.DB
+}
+
+func NewContactRepository(db *gorm.DB) *ContactRepository {
+	return &ContactRepository{db: db}
+}
+
+func (r *ContactRepository) CreateRequest(request *model.ContactRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *ContactRepository) FindRequest(requestId int64) (*model.ContactRequest, error) {
+	var request model.ContactRequest
+	err := r.db.First(&request, requestId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// FindPendingBetween returns a pending request in either direction between the two users
+func (r *ContactRepository) FindPendingBetween(userId, otherUserId uint) (*model.ContactRequest, error) {
+	var request model.ContactRequest
+	err := r.db.
+		Where("status = ?", model.ContactRequestPending).
+		Where("(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)", userId, otherUserId, otherUserId, userId).
+		First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *ContactRepository) FindIncoming(userId uint) ([]*model.ContactRequest, error) {
+	var requests []*model.ContactRequest
+	err := r.db.Where("addressee_id = ? AND status = ?", userId, model.ContactRequestPending).Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+func (r *ContactRepository) FindOutgoing(userId uint) ([]*model.ContactRequest, error) {
+	var requests []*model.ContactRequest
+	err := r.db.Where("requester_id = ? AND status = ?", userId, model.ContactRequestPending).Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+// UpdateRequestStatus moves a pending request to status and reports false if it was no longer pending
+func (r *ContactRepository) UpdateRequestStatus(requestId int64, status string) (bool, error) {
+	result := r.db.Model(&model.ContactRequest{}).
+		Where("id = ? AND status = ?", requestId, model.ContactRequestPending).
+		Update("status", status)
+	return result.RowsAffected == 1, result.Error
+}
+
+// CancelPendingBetween cancels any pending request in either direction between the two users
+func (r *ContactRepository) CancelPendingBetween(userId, otherUserId uint) error {
+	return r.db.Model(&model.ContactRequest{}).
+		Where("status = ?", model.ContactRequestPending).
+		Where("(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)", userId, otherUserId, otherUserId, userId).
+		Update("status", model.ContactRequestCancelled).Error
+}
+
+// AcceptRequest marks the request accepted and stores the contact in both directions atomically.
+// It reports false if the request was no longer pending.
+func (r *ContactRepository) AcceptRequest(request *model.ContactRequest) (bool, error) {
+	accepted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ContactRequest{}).
+			Where("id = ? AND status = ?", request.ID, model.ContactRequestPending).
+			Update("status", model.ContactRequestAccepted)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		contacts := []*model.Contact{
+			{UserID: request.RequesterID, ContactID: request.AddresseeID},
+			{UserID: request.AddresseeID, ContactID: request.RequesterID},
+		}
+		if err := tx.Create(&contacts).Error; err != nil {
+			return err
+		}
+		accepted = true
+		return nil
+	})
+	return accepted, err
+}
+
+func (r *ContactRepository) IsContact(userId, otherUserId uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Contact{}).Where("user_id = ? AND contact_id = ?", userId, otherUserId).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *ContactRepository) FindContacts(userId uint) ([]*model.Contact, error) {
+	var contacts []*model.Contact
+	err := r.db.Where("user_id = ?", userId).Order("created_at DESC").Find(&contacts).Error
+	return contacts, err
+}
+
+func (r *ContactRepository) ContactIds(userId uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Contact{}).Where("user_id = ?", userId).Pluck("contact_id", &ids).Error
+	return ids, err
+}
+
+// MutualIds returns the users who are contacts of both users
+func (r *ContactRepository) MutualIds(userId, otherUserId uint) ([]uint, error) {
+	var ids []uint
+	sub := r.db.Model(&model.Contact{}).Select("contact_id").Where("user_id = ?", otherUserId)
+	err := r.db.Model(&model.Contact{}).Where("user_id = ? AND contact_id IN (?)", userId, sub).Pluck("contact_id", &ids).Error
+	return ids, err
+}
+
+// DeleteContact removes the relationship in both directions and reports whether it existed
+func (r *ContactRepository) DeleteContact(userId, otherUserId uint) (bool, error) {
+	result := r.db.
+		Where("(user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?)", userId, otherUserId, otherUserId, userId).
+		Delete(&model.Contact{})
+	return result.RowsAffected > 0, result.Error
+}
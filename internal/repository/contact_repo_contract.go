@@ -0,0 +1,19 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IContactRepository interface {
+	CreateRequest(request *model.ContactRequest) error
+	FindRequest(requestId int64) (*model.ContactRequest, error)
+	FindPendingBetween(userId, otherUserId uint) (*model.ContactRequest, error)
+	FindIncoming(userId uint) ([]*model.ContactRequest, error)
+	FindOutgoing(userId uint) ([]*model.ContactRequest, error)
+	UpdateRequestStatus(requestId int64, status string) (bool, error)
+	CancelPendingBetween(userId, otherUserId uint) error
+	AcceptRequest(request *model.ContactRequest) (bool, error)
+	IsContact(userId, otherUserId uint) (bool, error)
+	FindContacts(userId uint) ([]*model.Contact, error)
+	ContactIds(userId uint) ([]uint, error)
+	MutualIds(userId, otherUserId uint) ([]uint, error)
+	DeleteContact(userId, otherUserId uint) (bool, error)
+}
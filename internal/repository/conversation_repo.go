@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ConversationRepository struct {
+	db *gorm.DB
+}
+
+func NewConversationRepository(db *gorm.DB) *ConversationRepository {
+	return &ConversationRepository{db: db}
+}
+
+// Create stores the conversation together with its initial members in a single transaction
+func (r *ConversationRepository) Create(conversation *model.Conversation, members []*model.ConversationMember) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+		for _, member := range members {
+			member.ConversationID = conversation.ID
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		return tx.Create(&members).Error
+	})
+}
+
+func (r *ConversationRepository) FindById(conversationId int64) (*model.Conversation, error) {
+	var conversation model.Conversation
+	err := r.db.First(&conversation, conversationId).Error
+	return &conversation, err
+}
+
+// FindDirect returns the direct conversation shared by the two users, if any
+func (r *ConversationRepository) FindDirect(userId, otherUserId uint) (*model.Conversation, error) {
+	var conversation model.Conversation
+	err := r.db.
+		Joins("JOIN conversation_members a ON a.conversation_id = conversations.id AND a.user_id = ?", userId).
+		Joins("JOIN conversation_members b ON b.conversation_id = conversations.id AND b.user_id = ?", otherUserId).
+		Where("conversations.type = ?", model.ConversationTypeDirect).
+		First(&conversation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// FindOpenSupport returns the visitor's support conversation that has not been closed yet
+func (r *ConversationRepository) FindOpenSupport(visitorId uint) (*model.Conversation, error) {
+	var conversation model.Conversation
+	err := r.db.
+		Where("type = ? AND visitor_id = ? AND closed_at IS NULL", model.ConversationTypeSupport, visitorId).
+		Order("id DESC").
+		First(&conversation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// FindSupportByVisitor returns every support conversation the visitor has had
+func (r *ConversationRepository) FindSupportByVisitor(visitorId uint) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	err := r.db.
+		Where("type = ? AND visitor_id = ?", model.ConversationTypeSupport, visitorId).
+		Order("id ASC").
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// Close marks a support conversation as closed. It reports false when the
+// conversation was already closed, so concurrent closes only take effect once.
+func (r *ConversationRepository) Close(conversationId int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND closed_at IS NULL", conversationId).
+		Updates(map[string]interface{}{"status": model.SupportStatusClosed, "closed_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Enqueue puts a chat the bot was handling in the agent queue. It reports false
+// when the chat already left the bot, so each handoff takes effect once.
+func (r *ConversationRepository) Enqueue(conversationId int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND status = ?", conversationId, model.SupportStatusBot).
+		Updates(map[string]interface{}{
+			"status":    model.SupportStatusQueued,
+			"queued_at": now,
+			"routed_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindQueued returns support conversations waiting for an agent, longest waiting first
+func (r *ConversationRepository) FindQueued(limit int) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	err := r.db.
+		Where("type = ? AND status = ?", model.ConversationTypeSupport, model.SupportStatusQueued).
+		Order("queued_at ASC").
+		Order("id ASC").
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// QueuePosition returns the 1-based position of a queued conversation among the
+// chats waiting for the same department
+func (r *ConversationRepository) QueuePosition(conversation *model.Conversation) (int64, error) {
+	var ahead int64
+	query := r.db.Model(&model.Conversation{}).
+		Where("type = ? AND status = ?", model.ConversationTypeSupport, model.SupportStatusQueued).
+		Where("queued_at < ? OR (queued_at = ? AND id < ?)", conversation.QueuedAt, conversation.QueuedAt, conversation.ID)
+	if conversation.DepartmentID != nil {
+		query = query.Where("department_id = ?", *conversation.DepartmentID)
+	} else {
+		query = query.Where("department_id IS NULL")
+	}
+	err := query.Count(&ahead).Error
+	return ahead + 1, err
+}
+
+// FindOverflowDue returns queued conversations that have waited in their department
+// longer than its overflow time and should move to its fallback department
+func (r *ConversationRepository) FindOverflowDue(now time.Time, limit int) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	err := r.db.
+		Joins("JOIN departments ON departments.id = conversations.department_id").
+		Where("conversations.type = ? AND conversations.status = ?", model.ConversationTypeSupport, model.SupportStatusQueued).
+		Where("departments.fallback_department_id IS NOT NULL AND departments.overflow_after_seconds > 0").
+		Where("conversations.routed_at <= DATE_SUB(?, INTERVAL departments.overflow_after_seconds SECOND)", now).
+		Order("conversations.queued_at ASC").
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// MoveDepartment moves a queued conversation from one department queue to another.
+// It reports false when the conversation was assigned, closed or moved meanwhile.
+func (r *ConversationRepository) MoveDepartment(conversationId int64, fromDepartmentId uint, toDepartmentId *uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND status = ? AND department_id = ?", conversationId, model.SupportStatusQueued, fromDepartmentId).
+		Updates(map[string]interface{}{"department_id": toDepartmentId, "routed_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindActiveByAgent returns the open support conversations assigned to the agent
+func (r *ConversationRepository) FindActiveByAgent(agentId uint) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	err := r.db.
+		Where("type = ? AND status = ? AND assigned_agent_id = ?", model.ConversationTypeSupport, model.SupportStatusActive, agentId).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// FindLiveSupport returns every support conversation that is queued or with an
+// agent, longest waiting first
+func (r *ConversationRepository) FindLiveSupport() ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	err := r.db.
+		Where("type = ? AND status IN ?", model.ConversationTypeSupport, []string{model.SupportStatusQueued, model.SupportStatusActive}).
+		Order("queued_at ASC").
+		Order("id ASC").
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// Assign hands a queued conversation to an agent. It reports false when the
+// conversation is no longer queued, so each chat is assigned exactly once.
+func (r *ConversationRepository) Assign(conversationId int64, agentId uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND status = ?", conversationId, model.SupportStatusQueued).
+		Updates(map[string]interface{}{
+			"status":            model.SupportStatusActive,
+			"assigned_agent_id": agentId,
+			"assigned_at":       now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Reassign moves an active conversation from one agent to another. It reports false
+// when the conversation is no longer assigned to fromAgentId.
+func (r *ConversationRepository) Reassign(conversationId int64, fromAgentId, toAgentId uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND status = ? AND assigned_agent_id = ?", conversationId, model.SupportStatusActive, fromAgentId).
+		Updates(map[string]interface{}{"assigned_agent_id": toAgentId, "assigned_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// TransferToDepartment takes an active conversation away from its agent and queues
+// it for the department. It reports false when the conversation is no longer
+// assigned to fromAgentId.
+func (r *ConversationRepository) TransferToDepartment(conversationId int64, fromAgentId, departmentId uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND status = ? AND assigned_agent_id = ?", conversationId, model.SupportStatusActive, fromAgentId).
+		Updates(map[string]interface{}{
+			"status":            model.SupportStatusQueued,
+			"department_id":     departmentId,
+			"routed_at":         now,
+			"assigned_agent_id": nil,
+			"assigned_at":       nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Requeue puts a conversation assigned to the agent back in the queue. It keeps the
+// original queued_at so the visitor does not lose their place.
+func (r *ConversationRepository) Requeue(conversationId int64, agentId uint) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND status = ? AND assigned_agent_id = ?", conversationId, model.SupportStatusActive, agentId).
+		Updates(map[string]interface{}{
+			"status":            model.SupportStatusQueued,
+			"assigned_agent_id": nil,
+			"assigned_at":       nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindRatingRequestsDue returns support chats closed between since and closedBefore
+// that were neither rated nor sent a rating request, whose visitor left an email
+func (r *ConversationRepository) FindRatingRequestsDue(since, closedBefore time.Time, limit int) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	err := r.db.
+		Joins("JOIN visitors ON visitors.id = conversations.visitor_id").
+		Where("conversations.type = ? AND conversations.closed_at >= ? AND conversations.closed_at < ?", model.ConversationTypeSupport, since, closedBefore).
+		Where("conversations.rating_requested_at IS NULL AND visitors.email <> ''").
+		Where("NOT EXISTS (SELECT 1 FROM chat_ratings WHERE chat_ratings.conversation_id = conversations.id)").
+		Order("conversations.closed_at ASC").
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// MarkRatingRequested claims a chat for a rating request email, reporting false
+// when another replica already did
+func (r *ConversationRepository) MarkRatingRequested(conversationId int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Conversation{}).
+		Where("id = ? AND rating_requested_at IS NULL", conversationId).
+		Update("rating_requested_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindByUser returns the conversations the user is a member of, either the archived
+// or the unarchived ones, with the user's pinned conversations first and the rest
+// ordered by most recent activity
+func (r *ConversationRepository) FindByUser(userId uint, archived bool) ([]*model.Conversation, error) {
+	var conversations []*model.Conversation
+	query := r.db.
+		Joins("JOIN conversation_members ON conversation_members.conversation_id = conversations.id").
+		Where("conversation_members.user_id = ?", userId)
+	if archived {
+		query = query.Where("conversation_members.archived_at IS NOT NULL")
+	} else {
+		query = query.Where("conversation_members.archived_at IS NULL")
+	}
+	err := query.
+		Order("conversation_members.pinned_at IS NULL").
+		Order("conversation_members.pinned_at DESC").
+		Order("conversations.updated_at DESC").
+		Find(&conversations).Error
+	return conversations, err
+}
+
+// FindMembershipsByUser returns every membership row of the user
+func (r *ConversationRepository) FindMembershipsByUser(userId uint) ([]*model.ConversationMember, error) {
+	var members []*model.ConversationMember
+	err := r.db.Where("user_id = ?", userId).Find(&members).Error
+	return members, err
+}
+
+func (r *ConversationRepository) Update(conversationId int64, fields map[string]interface{}) error {
+	return r.db.Model(&model.Conversation{}).Where("id = ?", conversationId).Updates(fields).Error
+}
+
+// Touch bumps updated_at so the conversation moves to the top of member lists
+func (r *ConversationRepository) Touch(conversationId int64, now time.Time) error {
+	return r.db.Model(&model.Conversation{}).Where("id = ?", conversationId).Update("updated_at", now).Error
+}
+
+func (r *ConversationRepository) FindMember(conversationId int64, userId uint) (*model.ConversationMember, error) {
+	var member model.ConversationMember
+	err := r.db.Where("conversation_id = ? AND user_id = ?", conversationId, userId).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *ConversationRepository) FindMembers(conversationId int64) ([]*model.ConversationMember, error) {
+	var members []*model.ConversationMember
+	err := r.db.Where("conversation_id = ?", conversationId).Order("joined_at ASC").Find(&members).Error
+	return members, err
+}
+
+func (r *ConversationRepository) MemberIds(conversationId int64) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.ConversationMember{}).Where("conversation_id = ?", conversationId).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *ConversationRepository) AddMembers(members []*model.ConversationMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+	return r.db.Create(&members).Error
+}
+
+func (r *ConversationRepository) UpdateMemberRole(conversationId int64, userId uint, role string) error {
+	return r.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationId, userId).
+		Update("role", role).Error
+}
+
+// UpdateMemberSettings updates the caller-controlled settings (mute, pin, archive) of a membership
+func (r *ConversationRepository) UpdateMemberSettings(conversationId int64, userId uint, fields map[string]interface{}) error {
+	return r.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationId, userId).
+		Updates(fields).Error
+}
+
+// UnarchiveForActivity brings the conversation back out of the archive for every
+// member who has not muted it
+func (r *ConversationRepository) UnarchiveForActivity(conversationId int64, now time.Time) error {
+	return r.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND archived_at IS NOT NULL", conversationId).
+		Where("muted = ? OR (muted_until IS NOT NULL AND muted_until <= ?)", false, now).
+		Update("archived_at", nil).Error
+}
+
+func (r *ConversationRepository) RemoveMember(conversationId int64, userId uint) error {
+	return r.db.Where("conversation_id = ? AND user_id = ?", conversationId, userId).Delete(&model.ConversationMember{}).Error
+}
+
+// TransferOwnership demotes the current owner to admin and promotes the new owner atomically
+func (r *ConversationRepository) TransferOwnership(conversationId int64, fromUserId, toUserId uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", conversationId, fromUserId).
+			Update("role", model.MemberRoleAdmin).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", conversationId, toUserId).
+			Update("role", model.MemberRoleOwner).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Conversation{}).Where("id = ?", conversationId).Update("owner_id", toUserId).Error
+	})
+}
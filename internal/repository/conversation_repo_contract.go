@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IConversationRepository interface {
+	Create(conversation *model.Conversation, members []*model.ConversationMember) error
+	FindById(conversationId int64) (*model.Conversation, error)
+	FindDirect(userId, otherUserId uint) (*model.Conversation, error)
+	FindOpenSupport(visitorId uint) (*model.Conversation, error)
+	FindSupportByVisitor(visitorId uint) ([]*model.Conversation, error)
+	Close(conversationId int64, now time.Time) (bool, error)
+	Enqueue(conversationId int64, now time.Time) (bool, error)
+	FindQueued(limit int) ([]*model.Conversation, error)
+	QueuePosition(conversation *model.Conversation) (int64, error)
+	FindOverflowDue(now time.Time, limit int) ([]*model.Conversation, error)
+	MoveDepartment(conversationId int64, fromDepartmentId uint, toDepartmentId *uint, now time.Time) (bool, error)
+	FindActiveByAgent(agentId uint) ([]*model.Conversation, error)
+	FindLiveSupport() ([]*model.Conversation, error)
+	Assign(conversationId int64, agentId uint, now time.Time) (bool, error)
+	Reassign(conversationId int64, fromAgentId, toAgentId uint, now time.Time) (bool, error)
+	TransferToDepartment(conversationId int64, fromAgentId, departmentId uint, now time.Time) (bool, error)
+	Requeue(conversationId int64, agentId uint) (bool, error)
+	FindRatingRequestsDue(since, closedBefore time.Time, limit int) ([]*model.Conversation, error)
+	MarkRatingRequested(conversationId int64, now time.Time) (bool, error)
+	FindByUser(userId uint, archived bool) ([]*model.Conversation, error)
+	FindMembershipsByUser(userId uint) ([]*model.ConversationMember, error)
+	Update(conversationId int64, fields map[string]interface{}) error
+	Touch(conversationId int64, now time.Time) error
+	FindMember(conversationId int64, userId uint) (*model.ConversationMember, error)
+	FindMembers(conversationId int64) ([]*model.ConversationMember, error)
+	MemberIds(conversationId int64) ([]uint, error)
+	AddMembers(members []*model.ConversationMember) error
+	UpdateMemberRole(conversationId int64, userId uint, role string) error
+	UpdateMemberSettings(conversationId int64, userId uint, fields map[string]interface{}) error
+	UnarchiveForActivity(conversationId int64, now time.Time) error
+	RemoveMember(conversationId int64, userId uint) error
+	TransferOwnership(conversationId int64, fromUserId, toUserId uint) error
+}
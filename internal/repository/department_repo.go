@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DepartmentRepository struct {
+	db *gorm.DB
+}
+
+func NewDepartmentRepository(db *gorm.DB) *DepartmentRepository {
+	return &DepartmentRepository{db: db}
+}
+
+func (r *DepartmentRepository) Create(department *model.Department) error {
+	return r.db.Create(department).Error
+}
+
+// FindById returns the department with its routing rules and business hours
+func (r *DepartmentRepository) FindById(departmentId int64) (*model.Department, error) {
+	var department model.Department
+	err := r.db.Scopes(withSchedule).Preload("Rules", func(db *gorm.DB) *gorm.DB {
+		return db.Order("priority DESC, id ASC")
+	}).First(&department, departmentId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &department, nil
+}
+
+func (r *DepartmentRepository) FindByName(name string) (*model.Department, error) {
+	var department model.Department
+	err := r.db.Where("name = ?", name).First(&department).Error
+	if err != nil {
+		return nil, err
+	}
+	return &department, nil
+}
+
+// FindAll returns every department with its routing rules and business hours, ordered by name
+func (r *DepartmentRepository) FindAll() ([]*model.Department, error) {
+	var departments []*model.Department
+	err := r.db.Scopes(withSchedule).Preload("Rules", func(db *gorm.DB) *gorm.DB {
+		return db.Order("priority DESC, id ASC")
+	}).Order("name ASC").Find(&departments).Error
+	return departments, err
+}
+
+func (r *DepartmentRepository) Update(departmentId int64, fields map[string]interface{}) error {
+	return r.db.Model(&model.Department{}).Where("id = ?", departmentId).Updates(fields).Error
+}
+
+// Delete removes the department with its agents and rules. Departments that fell
+// back to it lose their fallback and chats still open in it return to the general queue.
+func (r *DepartmentRepository) Delete(departmentId int64, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("department_id = ?", departmentId).Delete(&model.DepartmentAgent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("department_id = ?", departmentId).Delete(&model.DepartmentRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("department_id = ?", departmentId).Delete(&model.BusinessHours{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("department_id = ?", departmentId).Delete(&model.Holiday{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Department{}).
+			Where("fallback_department_id = ?", departmentId).
+			Updates(map[string]interface{}{"fallback_department_id": nil, "overflow_after_seconds": 0}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Conversation{}).
+			Where("department_id = ? AND closed_at IS NULL", departmentId).
+			Updates(map[string]interface{}{"department_id": nil, "routed_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Department{}, departmentId).Error
+	})
+}
+
+func (r *DepartmentRepository) AddAgent(agent *model.DepartmentAgent) error {
+	return r.db.Create(agent).Error
+}
+
+// RemoveAgent reports whether the agent was a member of the department
+func (r *DepartmentRepository) RemoveAgent(departmentId int64, userId uint) (bool, error) {
+	result := r.db.Where("department_id = ? AND user_id = ?", departmentId, userId).Delete(&model.DepartmentAgent{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *DepartmentRepository) IsAgent(departmentId int64, userId uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.DepartmentAgent{}).
+		Where("department_id = ? AND user_id = ?", departmentId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *DepartmentRepository) AgentIds(departmentId int64) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.DepartmentAgent{}).Where("department_id = ?", departmentId).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// RemoveAgentEverywhere drops the user from every department, e.g. once they stop being an agent
+func (r *DepartmentRepository) RemoveAgentEverywhere(userId uint) error {
+	return r.db.Where("user_id = ?", userId).Delete(&model.DepartmentAgent{}).Error
+}
+
+func (r *DepartmentRepository) CreateRule(rule *model.DepartmentRule) error {
+	return r.db.Create(rule).Error
+}
+
+// DeleteRule reports whether the rule existed in the department
+func (r *DepartmentRepository) DeleteRule(departmentId, ruleId int64) (bool, error) {
+	result := r.db.Where("id = ? AND department_id = ?", ruleId, departmentId).Delete(&model.DepartmentRule{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindRules returns every routing rule, highest priority first
+func (r *DepartmentRepository) FindRules() ([]*model.DepartmentRule, error) {
+	var rules []*model.DepartmentRule
+	err := r.db.Order("priority DESC, id ASC").Find(&rules).Error
+	return rules, err
+}
+
+// ReplaceHours sets the department's timezone and replaces its weekly business hours
+func (r *DepartmentRepository) ReplaceHours(departmentId int64, timezone string, hours []*model.BusinessHours) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Department{}).Where("id = ?", departmentId).Update("timezone", timezone).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("department_id = ?", departmentId).Delete(&model.BusinessHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+}
+
+func (r *DepartmentRepository) CreateHoliday(holiday *model.Holiday) error {
+	return r.db.Create(holiday).Error
+}
+
+func (r *DepartmentRepository) HasHoliday(departmentId int64, date string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Holiday{}).
+		Where("department_id = ? AND date = ?", departmentId, date).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteHoliday reports whether the holiday existed in the department
+func (r *DepartmentRepository) DeleteHoliday(departmentId, holidayId int64) (bool, error) {
+	result := r.db.Where("id = ? AND department_id = ?", holidayId, departmentId).Delete(&model.Holiday{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// withSchedule preloads the business hours and holidays of departments
+func withSchedule(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Hours", func(db *gorm.DB) *gorm.DB {
+			return db.Order("weekday ASC, opens_at ASC")
+		}).
+		Preload("Holidays", func(db *gorm.DB) *gorm.DB {
+			return db.Order("date ASC")
+		})
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IDepartmentRepository interface {
+	Create(department *model.Department) error
+	FindById(departmentId int64) (*model.Department, error)
+	FindByName(name string) (*model.Department, error)
+	FindAll() ([]*model.Department, error)
+	Update(departmentId int64, fields map[string]interface{}) error
+	Delete(departmentId int64, now time.Time) error
+	AddAgent(agent *model.DepartmentAgent) error
+	RemoveAgent(departmentId int64, userId uint) (bool, error)
+	IsAgent(departmentId int64, userId uint) (bool, error)
+	AgentIds(departmentId int64) ([]uint, error)
+	RemoveAgentEverywhere(userId uint) error
+	CreateRule(rule *model.DepartmentRule) error
+	DeleteRule(departmentId, ruleId int64) (bool, error)
+	FindRules() ([]*model.DepartmentRule, error)
+	ReplaceHours(departmentId int64, timezone string, hours []*model.BusinessHours) error
+	CreateHoliday(holiday *model.Holiday) error
+	HasHoliday(departmentId int64, date string) (bool, error)
+	DeleteHoliday(departmentId, holidayId int64) (bool, error)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IncomingWebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewIncomingWebhookRepository(db *gorm.DB) *IncomingWebhookRepository {
+	return &IncomingWebhookRepository{db: db}
+}
+
+func (r *IncomingWebhookRepository) Create(hook *model.IncomingWebhook) error {
+	return r.db.Create(hook).Error
+}
+
+func (r *IncomingWebhookRepository) FindById(id int64) (*model.IncomingWebhook, error) {
+	var hook model.IncomingWebhook
+	err := r.db.First(&hook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (r *IncomingWebhookRepository) FindByTokenHash(hash string) (*model.IncomingWebhook, error) {
+	var hook model.IncomingWebhook
+	err := r.db.Where("token_hash = ?", hash).First(&hook).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// FindByConversation returns the conversation's webhooks, newest first, revoked ones included
+func (r *IncomingWebhookRepository) FindByConversation(conversationId int64) ([]*model.IncomingWebhook, error) {
+	var hooks []*model.IncomingWebhook
+	err := r.db.Where("conversation_id = ?", conversationId).Order("id DESC").Find(&hooks).Error
+	return hooks, err
+}
+
+// ReplaceToken swaps the webhook's token, which invalidates its old URL at once.
+// It reports false when the webhook has been revoked.
+func (r *IncomingWebhookRepository) ReplaceToken(id int64, hash string) (bool, error) {
+	result := r.db.Model(&model.IncomingWebhook{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("token_hash", hash)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Revoke disables the webhook. It reports false when it was already revoked.
+func (r *IncomingWebhookRepository) Revoke(id int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.IncomingWebhook{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// TouchLastUsed records that the webhook was used, at most once per interval so
+// busy webhooks do not write on every post
+func (r *IncomingWebhookRepository) TouchLastUsed(id uint, now time.Time, interval time.Duration) error {
+	return r.db.Model(&model.IncomingWebhook{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-interval)).
+		UpdateColumn("last_used_at", now).Error
+}
+
+// Hit counts a post of the webhook in the window and returns the window's total so far
+func (r *IncomingWebhookRepository) Hit(hookId uint, window time.Time) (int64, error) {
+	usage := &model.IncomingWebhookUsage{HookID: hookId, WindowStart: window, Count: 1}
+	err := r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("count + 1")}),
+	}).Create(usage).Error
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = r.db.Model(&model.IncomingWebhookUsage{}).
+		Where("hook_id = ? AND window_start = ?", hookId, window).
+		Pluck("count", &count).Error
+	return count, err
+}
+
+// PurgeUsage deletes the post counts of windows that started before the time
+func (r *IncomingWebhookRepository) PurgeUsage(before time.Time) error {
+	return r.db.Where("window_start < ?", before).Delete(&model.IncomingWebhookUsage{}).Error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IIncomingWebhookRepository interface {
+	Create(hook *model.IncomingWebhook) error
+	FindById(id int64) (*model.IncomingWebhook, error)
+	FindByTokenHash(hash string) (*model.IncomingWebhook, error)
+	FindByConversation(conversationId int64) ([]*model.IncomingWebhook, error)
+	ReplaceToken(id int64, hash string) (bool, error)
+	Revoke(id int64, now time.Time) (bool, error)
+	TouchLastUsed(id uint, now time.Time, interval time.Duration) error
+	Hit(hookId uint, window time.Time) (int64, error)
+	PurgeUsage(before time.Time) error
+}
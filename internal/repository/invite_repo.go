@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+func (r *InviteRepository) Create(invite *model.ConversationInvite) error {
+	return r.db.Create(invite).Error
+}
+
+func (r *InviteRepository) FindById(inviteId int64) (*model.ConversationInvite, error) {
+	var invite model.ConversationInvite
+	err := r.db.First(&invite, inviteId).Error
+	return &invite, err
+}
+
+func (r *InviteRepository) FindByCode(code string) (*model.ConversationInvite, error) {
+	var invite model.ConversationInvite
+	err := r.db.Where("code = ?", code).First(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepository) FindByConversation(conversationId int64) ([]*model.ConversationInvite, error) {
+	var invites []*model.ConversationInvite
+	err := r.db.Where("conversation_id = ?", conversationId).Order("created_at DESC").Find(&invites).Error
+	return invites, err
+}
+
+func (r *InviteRepository) Revoke(inviteId int64) error {
+	return r.db.Model(&model.ConversationInvite{}).
+		Where("id = ? AND revoked_at IS NULL", inviteId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Redeem uses up one use of the invite, if it is still usable, and stores record
+// (the new member or join request) in the same transaction, so a failed insert
+// gives the use back. It reports false when the invite is revoked, expired or
+// exhausted.
+func (r *InviteRepository) Redeem(inviteId uint, record interface{}) (bool, error) {
+	redeemed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ConversationInvite{}).
+			Where("id = ? AND revoked_at IS NULL", inviteId).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Where("max_uses = 0 OR uses < max_uses").
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		redeemed = true
+		return nil
+	})
+	return redeemed, err
+}
+
+func (r *InviteRepository) CreateJoinRequest(request *model.JoinRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *InviteRepository) FindJoinRequest(requestId int64) (*model.JoinRequest, error) {
+	var request model.JoinRequest
+	err := r.db.First(&request, requestId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *InviteRepository) FindPendingJoinRequest(conversationId int64, userId uint) (*model.JoinRequest, error) {
+	var request model.JoinRequest
+	err := r.db.Where("conversation_id = ? AND user_id = ? AND status = ?", conversationId, userId, model.JoinRequestPending).
+		First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *InviteRepository) FindJoinRequestsByStatus(conversationId int64, status string) ([]*model.JoinRequest, error) {
+	var requests []*model.JoinRequest
+	err := r.db.Where("conversation_id = ? AND status = ?", conversationId, status).Order("created_at ASC").Find(&requests).Error
+	return requests, err
+}
+
+// ReviewJoinRequest moves a pending request to its final status. It reports false
+// when the request was already reviewed by someone else.
+func (r *InviteRepository) ReviewJoinRequest(requestId int64, status string, reviewerId uint) (bool, error) {
+	result := r.db.Model(&model.JoinRequest{}).
+		Where("id = ? AND status = ?", requestId, model.JoinRequestPending).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewerId})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *InviteRepository) CreateEmailInvite(invite *model.EmailInvite) error {
+	return r.db.Create(invite).Error
+}
+
+func (r *InviteRepository) FindEmailInviteByTokenHash(tokenHash string) (*model.EmailInvite, error) {
+	var invite model.EmailInvite
+	err := r.db.Where("token_hash = ?", tokenHash).First(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// MarkEmailInviteAccepted records that the invite was redeemed. It reports false
+// when it already was, so each emailed link works once.
+func (r *InviteRepository) MarkEmailInviteAccepted(inviteId uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.EmailInvite{}).
+		Where("id = ? AND accepted_at IS NULL", inviteId).
+		Update("accepted_at", now)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IInviteRepository interface {
+	Create(invite *model.ConversationInvite) error
+	FindById(inviteId int64) (*model.ConversationInvite, error)
+	FindByCode(code string) (*model.ConversationInvite, error)
+	FindByConversation(conversationId int64) ([]*model.ConversationInvite, error)
+	Revoke(inviteId int64) error
+	Redeem(inviteId uint, record interface{}) (bool, error)
+	CreateJoinRequest(request *model.JoinRequest) error
+	FindJoinRequest(requestId int64) (*model.JoinRequest, error)
+	FindPendingJoinRequest(conversationId int64, userId uint) (*model.JoinRequest, error)
+	FindJoinRequestsByStatus(conversationId int64, status string) ([]*model.JoinRequest, error)
+	ReviewJoinRequest(requestId int64, status string, reviewerId uint) (bool, error)
+	CreateEmailInvite(invite *model.EmailInvite) error
+	FindEmailInviteByTokenHash(tokenHash string) (*model.EmailInvite, error)
+	MarkEmailInviteAccepted(inviteId uint, now time.Time) (bool, error)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MessageRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageRepository(db *gorm.DB) *MessageRepository {
+	return &MessageRepository{db: db}
+}
+
+// Create stores the message, set to expire when its conversation has disappearing messages on
+func (r *MessageRepository) Create(message *model.Message) error {
+	return createMessage(r.db, message)
+}
+
+func (r *MessageRepository) FindById(messageId int64) (*model.Message, error) {
+	var message model.Message
+	err := r.db.First(&message, messageId).Error
+	return &message, err
+}
+
+// FindByConversation returns up to limit messages older than beforeId (0 for the newest),
+// newest first, skipping messages sent by any of excludeSenderIds and, unless
+// includeInternal is set, agent-only messages
+func (r *MessageRepository) FindByConversation(conversationId int64, beforeId int64, limit int, excludeSenderIds []uint, includeInternal bool) ([]*model.Message, error) {
+	var messages []*model.Message
+	query := r.db.Where("conversation_id = ?", conversationId).Scopes(notExpired)
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+	if !includeInternal {
+		query = query.Where("internal = ?", false)
+	}
+	if len(excludeSenderIds) > 0 {
+		query = query.Where("sender_id NOT IN ?", excludeSenderIds)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// FindAfter returns up to limit messages newer than afterId, oldest first, for
+// reading a whole conversation page by page
+func (r *MessageRepository) FindAfter(conversationId int64, afterId int64, limit int, includeInternal bool) ([]*model.Message, error) {
+	var messages []*model.Message
+	query := r.db.Where("conversation_id = ? AND id > ?", conversationId, afterId).Scopes(notExpired)
+	if !includeInternal {
+		query = query.Where("internal = ?", false)
+	}
+	err := query.Order("id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *MessageRepository) CountByConversation(conversationId int64, includeInternal bool) (int64, error) {
+	var count int64
+	query := r.db.Model(&model.Message{}).Where("conversation_id = ?", conversationId).Scopes(notExpired)
+	if !includeInternal {
+		query = query.Where("internal = ?", false)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// FindFirstReply returns the first message a user posted to the visitor, ignoring
+// system messages and agent-only notes
+func (r *MessageRepository) FindFirstReply(conversationId int64) (*model.Message, error) {
+	var message model.Message
+	err := r.db.Where("conversation_id = ? AND sender_id <> 0 AND type = ? AND internal = ?", conversationId, model.MessageTypeText, false).
+		Order("id ASC").
+		First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// createMessage stores the message with the expiry its conversation's
+// disappearing message timer gives it
+func createMessage(db *gorm.DB, message *model.Message) error {
+	var ttl int
+	err := db.Model(&model.Conversation{}).Where("id = ?", message.ConversationID).Pluck("message_ttl", &ttl).Error
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(time.Duration(ttl) * time.Second)
+		message.ExpiresAt = &expiresAt
+	}
+	return db.Create(message).Error
+}
+
+// notExpired hides disappearing messages whose time is up but that the purge
+// job has not deleted yet
+func notExpired(db *gorm.DB) *gorm.DB {
+	return db.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
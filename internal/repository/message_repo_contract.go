@@ -0,0 +1,12 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IMessageRepository interface {
+	Create(message *model.Message) error
+	FindById(messageId int64) (*model.Message, error)
+	FindByConversation(conversationId int64, beforeId int64, limit int, excludeSenderIds []uint, includeInternal bool) ([]*model.Message, error)
+	FindAfter(conversationId int64, afterId int64, limit int, includeInternal bool) ([]*model.Message, error)
+	CountByConversation(conversationId int64, includeInternal bool) (int64, error)
+	FindFirstReply(conversationId int64) (*model.Message, error)
+}
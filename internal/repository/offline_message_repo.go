@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type OfflineMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewOfflineMessageRepository(db *gorm.DB) *OfflineMessageRepository {
+	return &OfflineMessageRepository{db: db}
+}
+
+func (r *OfflineMessageRepository) Create(message *model.OfflineMessage) error {
+	return r.db.Create(message).Error
+}
+
+func (r *OfflineMessageRepository) FindById(id int64) (*model.OfflineMessage, error) {
+	var message model.OfflineMessage
+	err := r.db.First(&message, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Find returns a page of offline messages, newest first, optionally filtered by
+// status and department. A beforeId of 0 starts at the newest message.
+func (r *OfflineMessageRepository) Find(status string, departmentId *uint, beforeId int64, limit int) ([]*model.OfflineMessage, error) {
+	var messages []*model.OfflineMessage
+	query := r.db.Model(&model.OfflineMessage{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if departmentId != nil {
+		query = query.Where("department_id = ?", *departmentId)
+	}
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// UpdateStatus changes the status of a message and reports false when it already had it
+func (r *OfflineMessageRepository) UpdateStatus(id int64, status string, fields map[string]interface{}) (bool, error) {
+	fields["status"] = status
+	result := r.db.Model(&model.OfflineMessage{}).
+		Where("id = ? AND status <> ?", id, status).
+		Updates(fields)
+	return result.RowsAffected == 1, result.Error
+}
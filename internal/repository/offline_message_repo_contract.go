@@ -0,0 +1,10 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IOfflineMessageRepository interface {
+	Create(message *model.OfflineMessage) error
+	FindById(id int64) (*model.OfflineMessage, error)
+	Find(status string, departmentId *uint, beforeId int64, limit int) ([]*model.OfflineMessage, error)
+	UpdateStatus(id int64, status string, fields map[string]interface{}) (bool, error)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type PinRepository struct {
+	db *gorm.DB
+}
+
+func NewPinRepository(db *gorm.DB) *PinRepository {
+	return &PinRepository{db: db}
+}
+
+func (r *PinRepository) Create(pin *model.PinnedMessage) error {
+	return r.db.Create(pin).Error
+}
+
+func (r *PinRepository) FindByMessage(messageId int64) (*model.PinnedMessage, error) {
+	var pin model.PinnedMessage
+	err := r.db.Where("message_id = ?", messageId).First(&pin).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+// FindByConversation returns the conversation's pins with their messages, newest pin first
+func (r *PinRepository) FindByConversation(conversationId int64) ([]*model.PinnedMessage, error) {
+	var pins []*model.PinnedMessage
+	err := r.db.Preload("Message").Where("conversation_id = ?", conversationId).Order("created_at DESC").Find(&pins).Error
+	return pins, err
+}
+
+func (r *PinRepository) CountByConversation(conversationId int64) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PinnedMessage{}).Where("conversation_id = ?", conversationId).Count(&count).Error
+	return count, err
+}
+
+// DeleteByMessage removes the pin and reports whether one existed
+func (r *PinRepository) DeleteByMessage(messageId int64) (bool, error) {
+	result := r.db.Where("message_id = ?", messageId).Delete(&model.PinnedMessage{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IPinRepository interface {
+	Create(pin *model.PinnedMessage) error
+	FindByMessage(messageId int64) (*model.PinnedMessage, error)
+	FindByConversation(conversationId int64) ([]*model.PinnedMessage, error)
+	CountByConversation(conversationId int64) (int64, error)
+	DeleteByMessage(messageId int64) (bool, error)
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PollRepository struct {
+	db *gorm.DB
+}
+
+func NewPollRepository(db *gorm.DB) *PollRepository {
+	return &PollRepository{db: db}
+}
+
+// Create stores the poll, its options and the message that carries it in a single transaction
+func (r *PollRepository) Create(poll *model.Poll, options []*model.PollOption, message *model.Message) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(poll).Error; err != nil {
+			return err
+		}
+		for _, option := range options {
+			option.PollID = poll.ID
+		}
+		if err := tx.Create(&options).Error; err != nil {
+			return err
+		}
+		message.PollID = &poll.ID
+		return createMessage(tx, message)
+	})
+}
+
+func (r *PollRepository) FindById(id int64) (*model.Poll, error) {
+	var poll model.Poll
+	err := r.db.First(&poll, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+func (r *PollRepository) FindOptions(pollId uint) ([]*model.PollOption, error) {
+	var options []*model.PollOption
+	err := r.db.Where("poll_id = ?", pollId).Order("position").Find(&options).Error
+	return options, err
+}
+
+// FindVotes returns every vote of the poll, oldest first
+func (r *PollRepository) FindVotes(pollId uint) ([]*model.PollVote, error) {
+	var votes []*model.PollVote
+	err := r.db.Where("poll_id = ?", pollId).Order("id").Find(&votes).Error
+	return votes, err
+}
+
+// CountVotes returns the number of votes of each option that has any
+func (r *PollRepository) CountVotes(pollId uint) (map[uint]int64, error) {
+	var rows []struct {
+		OptionID uint
+		Votes    int64
+	}
+	err := r.db.Model(&model.PollVote{}).
+		Select("option_id, COUNT(*) AS votes").
+		Where("poll_id = ?", pollId).
+		Group("option_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.OptionID] = row.Votes
+	}
+	return counts, nil
+}
+
+// CountVoters returns how many members have a vote in the poll
+func (r *PollRepository) CountVoters(pollId uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PollVote{}).Where("poll_id = ?", pollId).Distinct("user_id").Count(&count).Error
+	return count, err
+}
+
+// FindUserVotes returns the options the user voted for
+func (r *PollRepository) FindUserVotes(pollId, userId uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.PollVote{}).
+		Where("poll_id = ? AND user_id = ?", pollId, userId).
+		Order("option_id").
+		Pluck("option_id", &ids).Error
+	return ids, err
+}
+
+// Vote replaces the user's votes in the poll with optionIds; an empty list
+// withdraws them. It reports false when the poll is closed.
+//
+// The poll row is read with a shared lock, so voters do not block each other
+// but Close waits for votes in flight and no vote lands after it. Upserting the
+// user's ballot row serializes the user's own concurrent votes, so a single
+// choice poll never ends up with two votes from one user. The old votes are
+// read after the ballot is locked, so the read sees what the user's previous
+// vote committed, and deleted by primary key: a delete by poll and user that
+// matches nothing takes gap locks, and two first-time voters holding those
+// would deadlock on insert.
+func (r *PollRepository) Vote(pollId, userId uint, optionIds []uint, now time.Time) (bool, error) {
+	open := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var poll model.Poll
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&poll, pollId).Error; err != nil {
+			return err
+		}
+		if !poll.IsOpen(now) {
+			return nil
+		}
+		open = true
+
+		ballot := &model.PollBallot{PollID: pollId, UserID: userId, UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"updated_at"})}).Create(ballot).Error; err != nil {
+			return err
+		}
+		var oldIds []uint
+		if err := tx.Model(&model.PollVote{}).Where("poll_id = ? AND user_id = ?", pollId, userId).Pluck("id", &oldIds).Error; err != nil {
+			return err
+		}
+		if len(oldIds) > 0 {
+			if err := tx.Where("id IN ?", oldIds).Delete(&model.PollVote{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(optionIds) == 0 {
+			return nil
+		}
+		votes := make([]*model.PollVote, 0, len(optionIds))
+		for _, optionId := range optionIds {
+			votes = append(votes, &model.PollVote{PollID: pollId, UserID: userId, OptionID: optionId, CreatedAt: now})
+		}
+		return tx.Create(&votes).Error
+	})
+	return open, err
+}
+
+// Close stops the poll from taking votes. It reports false when it was already closed.
+func (r *PollRepository) Close(id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Poll{}).
+		Where("id = ? AND closed_at IS NULL", id).
+		Update("closed_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindDueToClose returns up to limit open polls whose close time has passed
+func (r *PollRepository) FindDueToClose(now time.Time, limit int) ([]*model.Poll, error) {
+	var polls []*model.Poll
+	err := r.db.Where("closed_at IS NULL AND closes_at <= ?", now).
+		Order("closes_at").
+		Limit(limit).
+		Find(&polls).Error
+	return polls, err
+}
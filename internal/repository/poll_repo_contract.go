@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IPollRepository interface {
+	Create(poll *model.Poll, options []*model.PollOption, message *model.Message) error
+	FindById(id int64) (*model.Poll, error)
+	FindOptions(pollId uint) ([]*model.PollOption, error)
+	FindVotes(pollId uint) ([]*model.PollVote, error)
+	CountVotes(pollId uint) (map[uint]int64, error)
+	CountVoters(pollId uint) (int64, error)
+	FindUserVotes(pollId, userId uint) ([]uint, error)
+	Vote(pollId, userId uint, optionIds []uint, now time.Time) (bool, error)
+	Close(id uint, now time.Time) (bool, error)
+	FindDueToClose(now time.Time, limit int) ([]*model.Poll, error)
+}
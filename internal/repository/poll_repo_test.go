@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_starter/internal/model"
+)
+
+// pollScript answers the poll and vote queries of Vote
+func pollScript(closed bool, voteIds ...int64) func(query string) *scriptedRows {
+	return func(query string) *scriptedRows {
+		switch {
+		case strings.Contains(query, "FROM `polls`"):
+			var closedAt driver.Value
+			if closed {
+				closedAt = time.Now().Add(-time.Hour)
+			}
+			return &scriptedRows{columns: []string{"id", "closed_at"}, values: [][]driver.Value{{int64(1), closedAt}}}
+		case strings.Contains(query, "FROM `poll_votes`"):
+			rows := &scriptedRows{columns: []string{"id"}}
+			for _, id := range voteIds {
+				rows.values = append(rows.values, []driver.Value{id})
+			}
+			return rows
+		}
+		return nil
+	}
+}
+
+func TestVoteFirstTimeDeletesNothing(t *testing.T) {
+	conn := &scriptedConn{answer: pollScript(false)}
+	repo := NewPollRepository(openScripted(t, conn))
+
+	open, err := repo.Vote(1, 2, []uint{3}, time.Now())
+	if err != nil || !open {
+		t.Fatalf("Vote = %v, %v; want true, nil", open, err)
+	}
+	writes := conn.writes()
+	if len(writes) != 2 || !strings.HasPrefix(writes[0], "INSERT INTO `poll_ballots`") || !strings.HasPrefix(writes[1], "INSERT INTO `poll_votes`") {
+		t.Errorf("a first vote should upsert the ballot and insert the vote, got %q", writes)
+	}
+}
+
+func TestVoteAgainDeletesOldVotesByID(t *testing.T) {
+	conn := &scriptedConn{answer: pollScript(false, 7, 8)}
+	repo := NewPollRepository(openScripted(t, conn))
+
+	if _, err := repo.Vote(1, 2, []uint{4}, time.Now()); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	writes := conn.writes()
+	if len(writes) != 3 || !strings.HasPrefix(writes[1], "DELETE FROM `poll_votes` WHERE id IN (?,?)") {
+		t.Fatalf("a changed vote should delete the old votes by ID, got %q", writes)
+	}
+
+	// The ballot is locked before the old votes are read, and those are read
+	// before they are deleted
+	order := map[string]int{}
+	for i, statement := range conn.statements {
+		for _, prefix := range []string{"INSERT INTO `poll_ballots`", "SELECT `id` FROM `poll_votes`", "DELETE FROM `poll_votes`"} {
+			if strings.HasPrefix(statement, prefix) {
+				order[prefix] = i
+			}
+		}
+	}
+	if !(order["INSERT INTO `poll_ballots`"] < order["SELECT `id` FROM `poll_votes`"] &&
+		order["SELECT `id` FROM `poll_votes`"] < order["DELETE FROM `poll_votes`"]) {
+		t.Errorf("statements ran out of order: %q", conn.statements)
+	}
+}
+
+func TestVoteWithdrawDeletesWithoutInserting(t *testing.T) {
+	conn := &scriptedConn{answer: pollScript(false, 7)}
+	repo := NewPollRepository(openScripted(t, conn))
+
+	if _, err := repo.Vote(1, 2, nil, time.Now()); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	writes := conn.writes()
+	if len(writes) != 2 || !strings.HasPrefix(writes[1], "DELETE FROM `poll_votes`") {
+		t.Errorf("withdrawing should only delete the old votes, got %q", writes)
+	}
+}
+
+func TestVoteOnClosedPollWritesNothing(t *testing.T) {
+	conn := &scriptedConn{answer: pollScript(true, 7)}
+	repo := NewPollRepository(openScripted(t, conn))
+
+	open, err := repo.Vote(1, 2, []uint{3}, time.Now())
+	if err != nil || open {
+		t.Fatalf("Vote = %v, %v; want false, nil", open, err)
+	}
+	if writes := conn.writes(); len(writes) != 0 {
+		t.Errorf("a closed poll should not be written to, got %q", writes)
+	}
+}
+
+// TestVoteConcurrently runs against the MySQL database of TEST_MYSQL_DSN. Many
+// first-time voters and one member changing their vote race each other; none
+// may deadlock and the member must end up with a single vote.
+func TestVoteConcurrently(t *testing.T) {
+	db := openTestMySQL(t)
+	if err := db.AutoMigrate(&model.Poll{}, &model.PollOption{}, &model.PollVote{}, &model.PollBallot{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	poll := &model.Poll{ConversationID: 1, CreatorID: 1, Question: "concurrent votes"}
+	if err := db.Create(poll).Error; err != nil {
+		t.Fatalf("create poll: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("poll_id = ?", poll.ID).Delete(&model.PollVote{})
+		db.Where("poll_id = ?", poll.ID).Delete(&model.PollBallot{})
+		db.Delete(poll)
+	})
+	repo := NewPollRepository(db)
+
+	const voters = 20
+	const repeatVoter = voters + 1
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*voters)
+	for i := 1; i <= voters; i++ {
+		wg.Add(2)
+		go func(userId uint) {
+			defer wg.Done()
+			_, err := repo.Vote(poll.ID, userId, []uint{1}, time.Now())
+			errs <- err
+		}(uint(i))
+		go func(optionId uint) {
+			defer wg.Done()
+			_, err := repo.Vote(poll.ID, repeatVoter, []uint{optionId}, time.Now())
+			errs <- err
+		}(uint(i%3 + 1))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Vote: %v", err)
+		}
+	}
+
+	voterCount, err := repo.CountVoters(poll.ID)
+	if err != nil {
+		t.Fatalf("CountVoters: %v", err)
+	}
+	if voterCount != voters+1 {
+		t.Errorf("%d members voted, want %d", voterCount, voters+1)
+	}
+	votes, err := repo.FindUserVotes(poll.ID, repeatVoter)
+	if err != nil {
+		t.Fatalf("FindUserVotes: %v", err)
+	}
+	if len(votes) != 1 {
+		t.Errorf("the member who changed their vote has %d votes, want 1", len(votes))
+	}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RatingAggregate summarizes the ratings of one agent or department. GroupID is
+// nil for ratings without an agent or department.
+type RatingAggregate struct {
+	GroupID  *uint
+	Count    int64
+	Average  float64
+	Positive int64 // ratings of 4 or 5
+}
+
+// RatingFilter narrows ratings down to a period and optionally an agent or department
+type RatingFilter struct {
+	From         time.Time
+	To           time.Time
+	AgentID      *uint
+	DepartmentID *uint
+}
+
+type RatingRepository struct {
+	db *gorm.DB
+}
+
+func NewRatingRepository(db *gorm.DB) *RatingRepository {
+	return &RatingRepository{db: db}
+}
+
+func (r *RatingRepository) Create(rating *model.ChatRating) error {
+	return r.db.Create(rating).Error
+}
+
+func (r *RatingRepository) FindByConversation(conversationId int64) (*model.ChatRating, error) {
+	var rating model.ChatRating
+	err := r.db.Where("conversation_id = ?", conversationId).First(&rating).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// Find returns a page of ratings matching the filter, newest first
+func (r *RatingRepository) Find(filter RatingFilter, beforeId int64, limit int) ([]*model.ChatRating, error) {
+	var ratings []*model.ChatRating
+	query := r.filtered(filter)
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&ratings).Error
+	return ratings, err
+}
+
+// AggregateByAgent returns the rating count, average and positive count of every agent
+func (r *RatingRepository) AggregateByAgent(filter RatingFilter) ([]*RatingAggregate, error) {
+	return r.aggregate(filter, "agent_id")
+}
+
+// AggregateByDepartment returns the rating count, average and positive count of every department
+func (r *RatingRepository) AggregateByDepartment(filter RatingFilter) ([]*RatingAggregate, error) {
+	return r.aggregate(filter, "department_id")
+}
+
+func (r *RatingRepository) aggregate(filter RatingFilter, column string) ([]*RatingAggregate, error) {
+	var rows []*RatingAggregate
+	err := r.filtered(filter).
+		Select(column + " AS group_id, COUNT(*) AS count, AVG(score) AS average, SUM(CASE WHEN score >= 4 THEN 1 ELSE 0 END) AS positive").
+		Group(column).
+		Order("count DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *RatingRepository) filtered(filter RatingFilter) *gorm.DB {
+	query := r.db.Model(&model.ChatRating{}).Where("created_at >= ? AND created_at < ?", filter.From, filter.To)
+	if filter.AgentID != nil {
+		query = query.Where("agent_id = ?", *filter.AgentID)
+	}
+	if filter.DepartmentID != nil {
+		query = query.Where("department_id = ?", *filter.DepartmentID)
+	}
+	return query
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "go_starter/internal/model"
+
+type IRatingRepository interface {
+	Create(rating *model.ChatRating) error
+	FindByConversation(conversationId int64) (*model.ChatRating, error)
+	Find(filter RatingFilter, beforeId int64, limit int) ([]*model.ChatRating, error)
+	AggregateByAgent(filter RatingFilter) ([]*RatingAggregate, error)
+	AggregateByDepartment(filter RatingFilter) ([]*RatingAggregate, error)
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PurgeCounts is what deleting a batch of messages removed
+type PurgeCounts struct {
+	Messages    int64
+	Attachments int64
+	Pins        int64
+	Bookmarks   int64
+	Polls       int64
+}
+
+// purgeColumns are the message columns the purge needs to clean up after a message
+var purgeColumns = []string{"id", "conversation_id", "poll_id", "attachments"}
+
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+func (r *RetentionRepository) CreatePolicy(policy *model.RetentionPolicy) error {
+	return r.db.Create(policy).Error
+}
+
+func (r *RetentionRepository) FindPolicyById(id int64) (*model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+	err := r.db.First(&policy, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *RetentionRepository) FindPolicies() ([]*model.RetentionPolicy, error) {
+	var policies []*model.RetentionPolicy
+	err := r.db.Order("id").Find(&policies).Error
+	return policies, err
+}
+
+func (r *RetentionRepository) FindEnabledPolicies() ([]*model.RetentionPolicy, error) {
+	var policies []*model.RetentionPolicy
+	err := r.db.Where("enabled = ?", true).Order("id").Find(&policies).Error
+	return policies, err
+}
+
+// SavePolicy writes the policy's editable fields
+func (r *RetentionRepository) SavePolicy(policy *model.RetentionPolicy) error {
+	return r.db.Model(policy).Select("name", "conversation_type", "max_age_days", "keep_pinned", "enabled").Updates(policy).Error
+}
+
+func (r *RetentionRepository) DeletePolicy(id int64) error {
+	return r.db.Delete(&model.RetentionPolicy{}, id).Error
+}
+
+// FindExpired returns up to limit disappearing messages whose time is up, oldest first
+func (r *RetentionRepository) FindExpired(now time.Time, limit int) ([]*model.Message, error) {
+	var messages []*model.Message
+	err := r.db.Select(purgeColumns).
+		Where("expires_at <= ?", now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// FindPastRetention returns up to limit messages created before cutoff that the
+// policy applies to, oldest first
+func (r *RetentionRepository) FindPastRetention(policy *model.RetentionPolicy, cutoff time.Time, limit int) ([]*model.Message, error) {
+	query := r.db.Select(purgeColumns).Where("created_at < ?", cutoff)
+	if policy.ConversationType != "" {
+		query = query.Where("conversation_id IN (?)", r.db.Model(&model.Conversation{}).Select("id").Where("type = ?", policy.ConversationType))
+	}
+	if policy.KeepPinned {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM pinned_messages WHERE pinned_messages.message_id = messages.id)")
+	}
+	var messages []*model.Message
+	err := query.Order("created_at").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// transcriptPurgedReason is recorded on exports whose content was dropped
+// because messages in the transcript were purged
+const transcriptPurgedReason = "removed because messages in the transcript were deleted"
+
+// DeleteMessages deletes the messages together with their pins, bookmarks and
+// polls in one short transaction. Attachments are stored on the message row and
+// go with it. Copies of the content kept elsewhere go too: webhook deliveries of
+// the messages are deleted, the content of the scheduled messages they were sent
+// from is cleared, and transcripts of their conversations are failed and emptied.
+func (r *RetentionRepository) DeleteMessages(messages []*model.Message) (PurgeCounts, error) {
+	var counts PurgeCounts
+	if len(messages) == 0 {
+		return counts, nil
+	}
+	ids := make([]uint, 0, len(messages))
+	var pollIds []uint
+	conversationIds := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+		conversationIds = append(conversationIds, message.ConversationID)
+		if message.PollID != nil {
+			pollIds = append(pollIds, *message.PollID)
+		}
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("message_id IN ?", ids).Delete(&model.PinnedMessage{})
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Pins = result.RowsAffected
+
+		result = tx.Where("message_id IN ?", ids).Delete(&model.Bookmark{})
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Bookmarks = result.RowsAffected
+
+		if len(pollIds) > 0 {
+			for _, table := range []interface{}{&model.PollVote{}, &model.PollBallot{}, &model.PollOption{}} {
+				if err := tx.Where("poll_id IN ?", pollIds).Delete(table).Error; err != nil {
+					return err
+				}
+			}
+			result = tx.Where("id IN ?", pollIds).Delete(&model.Poll{})
+			if result.Error != nil {
+				return result.Error
+			}
+			counts.Polls = result.RowsAffected
+		}
+
+		deliveries := tx.Model(&model.WebhookDelivery{}).Select("id").Where("message_id IN ?", ids)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&model.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ScheduledMessage{}).Where("message_id IN ?", ids).Update("content", "").Error; err != nil {
+			return err
+		}
+		// An export being generated may already hold the messages; failing it
+		// keeps its worker from storing the transcript
+		err := tx.Model(&model.TranscriptExport{}).
+			Where("conversation_id IN ? AND status IN ?", conversationIds, []string{model.ExportReady, model.ExportProcessing}).
+			Updates(map[string]interface{}{
+				"status":  model.ExportFailed,
+				"content": "",
+				"size":    0,
+				"error":   transcriptPurgedReason,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		result = tx.Where("id IN ?", ids).Delete(&model.Message{})
+		if result.Error != nil {
+			return result.Error
+		}
+		counts.Messages = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return PurgeCounts{}, err
+	}
+	// Another replica may have deleted some of the batch first; only count
+	// attachments when every message went in this transaction
+	if counts.Messages == int64(len(messages)) {
+		for _, message := range messages {
+			counts.Attachments += int64(len(message.Attachments))
+		}
+	}
+	return counts, nil
+}
+
+func (r *RetentionRepository) CreateRun(run *model.PurgeRun) error {
+	run.Error = truncate(run.Error, 255)
+	return r.db.Create(run).Error
+}
+
+// FindRuns returns up to limit purge runs older than beforeId (0 for the newest), newest first
+func (r *RetentionRepository) FindRuns(beforeId int64, limit int) ([]*model.PurgeRun, error) {
+	query := r.db.Model(&model.PurgeRun{})
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+	var runs []*model.PurgeRun
+	err := query.Order("id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
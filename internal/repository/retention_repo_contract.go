@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IRetentionRepository interface {
+	CreatePolicy(policy *model.RetentionPolicy) error
+	FindPolicyById(id int64) (*model.RetentionPolicy, error)
+	FindPolicies() ([]*model.RetentionPolicy, error)
+	FindEnabledPolicies() ([]*model.RetentionPolicy, error)
+	SavePolicy(policy *model.RetentionPolicy) error
+	DeletePolicy(id int64) error
+	FindExpired(now time.Time, limit int) ([]*model.Message, error)
+	FindPastRetention(policy *model.RetentionPolicy, cutoff time.Time, limit int) ([]*model.Message, error)
+	DeleteMessages(messages []*model.Message) (PurgeCounts, error)
+	CreateRun(run *model.PurgeRun) error
+	FindRuns(beforeId int64, limit int) ([]*model.PurgeRun, error)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_starter/internal/model"
+)
+
+func TestDeleteMessagesRemovesCopiesOfTheContent(t *testing.T) {
+	conn := &scriptedConn{}
+	repo := NewRetentionRepository(openScripted(t, conn))
+
+	if _, err := repo.DeleteMessages([]*model.Message{{ID: 5, ConversationID: 2}}); err != nil {
+		t.Fatalf("DeleteMessages: %v", err)
+	}
+	writes := conn.writes()
+	for _, want := range []string{
+		"DELETE FROM `webhook_attempts` WHERE delivery_id IN (SELECT `id` FROM `webhook_deliveries` WHERE message_id IN (?))",
+		"DELETE FROM `webhook_deliveries` WHERE message_id IN (?)",
+		"UPDATE `scheduled_messages` SET `content`=?",
+		"UPDATE `transcript_exports` SET `content`=?",
+	} {
+		found := false
+		for _, statement := range writes {
+			found = found || strings.HasPrefix(statement, want)
+		}
+		if !found {
+			t.Errorf("no statement starting with %q in %q", want, writes)
+		}
+	}
+	if last := writes[len(writes)-1]; !strings.HasPrefix(last, "DELETE FROM `messages`") {
+		t.Errorf("the messages should go last, after their copies, got %q", last)
+	}
+}
+
+// TestDeleteMessagesLeavesNothingReadable runs against the MySQL database of
+// TEST_MYSQL_DSN. It stores a message and every copy of its content, purges the
+// message and looks for the content everywhere it was.
+func TestDeleteMessagesLeavesNothingReadable(t *testing.T) {
+	db := openTestMySQL(t)
+	err := db.AutoMigrate(&model.Message{}, &model.PinnedMessage{}, &model.Bookmark{},
+		&model.WebhookDelivery{}, &model.WebhookAttempt{}, &model.ScheduledMessage{}, &model.TranscriptExport{})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	secret := "purge test " + time.Now().Format(time.RFC3339Nano)
+	message := &model.Message{ConversationID: 1, SenderID: 1, Type: "text", Content: secret}
+	if err := db.Create(message).Error; err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+	delivery := &model.WebhookDelivery{
+		EndpointID: 1, EventID: "evt_purge", EventType: model.WebhookMessageCreated,
+		Payload: `{"data":{"content":"` + secret + `"}}`, Status: model.WebhookDeliveryDelivered,
+		NextAttemptAt: time.Now(), MessageID: &message.ID,
+	}
+	scheduled := &model.ScheduledMessage{
+		ConversationID: 1, SenderID: 1, Content: secret, SendAt: time.Now(),
+		Status: model.ScheduledSent, MessageID: &message.ID,
+	}
+	export := &model.TranscriptExport{
+		ConversationID: 1, Format: model.TranscriptText, Status: model.ExportReady,
+		Content: secret, Size: len(secret), ExpiresAt: time.Now().Add(time.Hour),
+	}
+	for _, record := range []interface{}{delivery, scheduled, export} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("create %T: %v", record, err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Delete(&model.WebhookDelivery{}, delivery.ID)
+		db.Delete(&model.ScheduledMessage{}, scheduled.ID)
+		db.Delete(&model.TranscriptExport{}, export.ID)
+	})
+
+	repo := NewRetentionRepository(db)
+	if _, err := repo.DeleteMessages([]*model.Message{message}); err != nil {
+		t.Fatalf("DeleteMessages: %v", err)
+	}
+
+	for table, column := range map[string]string{
+		"messages":           "content",
+		"webhook_deliveries": "payload",
+		"scheduled_messages": "content",
+		"transcript_exports": "content",
+	} {
+		var count int64
+		if err := db.Table(table).Where(column+" LIKE ?", "%"+secret+"%").Count(&count).Error; err != nil {
+			t.Fatalf("search %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("%s still has %d rows with the purged content", table, count)
+		}
+	}
+	var purged model.TranscriptExport
+	if err := db.First(&purged, export.ID).Error; err != nil {
+		t.Fatalf("find export: %v", err)
+	}
+	if purged.Status != model.ExportFailed {
+		t.Errorf("export has status %q, want failed", purged.Status)
+	}
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageRepository(db *gorm.DB) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{db: db}
+}
+
+func (r *ScheduledMessageRepository) Create(message *model.ScheduledMessage) error {
+	return r.db.Create(message).Error
+}
+
+func (r *ScheduledMessageRepository) FindById(id int64) (*model.ScheduledMessage, error) {
+	var message model.ScheduledMessage
+	err := r.db.First(&message, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindPendingBySender returns the sender's pending messages, soonest first,
+// optionally limited to one conversation
+func (r *ScheduledMessageRepository) FindPendingBySender(senderId uint, conversationId int64) ([]*model.ScheduledMessage, error) {
+	var messages []*model.ScheduledMessage
+	query := r.db.Where("sender_id = ? AND status = ?", senderId, model.ScheduledPending)
+	if conversationId > 0 {
+		query = query.Where("conversation_id = ?", conversationId)
+	}
+	err := query.Order("send_at ASC").Find(&messages).Error
+	return messages, err
+}
+
+// UpdatePending updates a message that is still pending and reports false when it
+// has already been sent, cancelled or is being dispatched
+func (r *ScheduledMessageRepository) UpdatePending(id int64, fields map[string]interface{}) (bool, error) {
+	result := r.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledPending).
+		Updates(fields)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ClaimNext locks the pending message due at now that is due soonest with SKIP
+// LOCKED, so concurrent server replicas never pick the same row, and marks it
+// sending in the same transaction. It returns nil when nothing is due. A claimed
+// row is never claimed again, so delivery is at most once.
+func (r *ScheduledMessageRepository) ClaimNext(now time.Time) (*model.ScheduledMessage, error) {
+	var claimed *model.ScheduledMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var due []*model.ScheduledMessage
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND send_at <= ?", model.ScheduledPending, now).
+			Order("send_at ASC").
+			Limit(1).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		err = tx.Model(&model.ScheduledMessage{}).
+			Where("id = ?", due[0].ID).
+			Updates(map[string]interface{}{"status": model.ScheduledSending, "claimed_at": now}).Error
+		if err != nil {
+			return err
+		}
+		claimed = due[0]
+		claimed.Status = model.ScheduledSending
+		claimed.ClaimedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// FailStale marks messages failed that were claimed before claimedBefore and never
+// finished, because their dispatcher stopped mid-send. They may or may not have
+// been delivered, so they are not sent again.
+func (r *ScheduledMessageRepository) FailStale(claimedBefore time.Time, reason string) (int64, error) {
+	result := r.db.Model(&model.ScheduledMessage{}).
+		Where("status = ? AND claimed_at < ?", model.ScheduledSending, claimedBefore).
+		Updates(map[string]interface{}{"status": model.ScheduledFailed, "error": truncate(reason, 255)})
+	return result.RowsAffected, result.Error
+}
+
+// MarkSent records the delivered message of a claimed scheduled message
+func (r *ScheduledMessageRepository) MarkSent(id uint, messageId uint) error {
+	return r.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledSending).
+		Updates(map[string]interface{}{"status": model.ScheduledSent, "message_id": messageId}).Error
+}
+
+// MarkFailed records why a claimed scheduled message could not be delivered
+func (r *ScheduledMessageRepository) MarkFailed(id uint, reason string) error {
+	return r.db.Model(&model.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, model.ScheduledSending).
+		Updates(map[string]interface{}{"status": model.ScheduledFailed, "error": truncate(reason, 255)}).Error
+}
+
+// truncate cuts s to at most n bytes so it fits a sized column
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
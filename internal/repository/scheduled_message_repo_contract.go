@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IScheduledMessageRepository interface {
+	Create(message *model.ScheduledMessage) error
+	FindById(id int64) (*model.ScheduledMessage, error)
+	FindPendingBySender(senderId uint, conversationId int64) ([]*model.ScheduledMessage, error)
+	UpdatePending(id int64, fields map[string]interface{}) (bool, error)
+	ClaimNext(now time.Time) (*model.ScheduledMessage, error)
+	FailStale(claimedBefore time.Time, reason string) (int64, error)
+	MarkSent(id uint, messageId uint) error
+	MarkFailed(id uint, reason string) error
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scriptedConn is a database/sql connection that records every statement and
+// answers queries from a script instead of a server. Queries the script does
+// not answer return no rows, and writes affect one row unless affected says
+// otherwise.
+type scriptedConn struct {
+	mu         sync.Mutex
+	statements []string
+	answer     func(query string) *scriptedRows
+	affected   func(query string) int64
+}
+
+func (c *scriptedConn) record(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, query)
+}
+
+func (c *scriptedConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not scripted")
+}
+func (c *scriptedConn) Close() error              { return nil }
+func (c *scriptedConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *scriptedConn) Commit() error             { return nil }
+func (c *scriptedConn) Rollback() error           { return nil }
+
+func (c *scriptedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query)
+	if c.affected != nil {
+		return scriptedResult(c.affected(query)), nil
+	}
+	return scriptedResult(1), nil
+}
+
+func (c *scriptedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query)
+	if c.answer != nil {
+		if rows := c.answer(query); rows != nil {
+			return rows, nil
+		}
+	}
+	return &scriptedRows{}, nil
+}
+
+// writes returns the statements that change data
+func (c *scriptedConn) writes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var writes []string
+	for _, statement := range c.statements {
+		if !strings.HasPrefix(statement, "SELECT") {
+			writes = append(writes, statement)
+		}
+	}
+	return writes
+}
+
+// scriptedResult is the number of rows a write affected
+type scriptedResult int64
+
+func (scriptedResult) LastInsertId() (int64, error)   { return 100, nil }
+func (r scriptedResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+type scriptedRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *scriptedRows) Columns() []string { return r.columns }
+func (r *scriptedRows) Close() error      { return nil }
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type scriptedConnector struct{ conn *scriptedConn }
+
+func (c scriptedConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c scriptedConnector) Driver() driver.Driver                        { return nil }
+
+// openScripted opens a MySQL flavoured gorm.DB backed by the scripted connection
+func openScripted(t *testing.T, conn *scriptedConn) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(scriptedConnector{conn: conn}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db
+}
+
+// openTestMySQL connects to the database of TEST_MYSQL_DSN, e.g.
+// "user:pass@tcp(localhost:3306)/test?parseTime=True", and skips the test
+// when it is not set
+func openTestMySQL(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db
+}
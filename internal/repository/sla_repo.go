@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SLABreachFilter narrows breaches down to a period and optionally a department, agent or metric
+type SLABreachFilter struct {
+	From         time.Time
+	To           time.Time
+	DepartmentID *uint
+	AgentID      *uint
+	Metric       string
+}
+
+// SLABreachCount is the number of breaches of one metric in one department.
+// DepartmentID is nil for the general queue.
+type SLABreachCount struct {
+	DepartmentID *uint
+	Metric       string
+	Count        int64
+}
+
+type SLARepository struct {
+	db *gorm.DB
+}
+
+func NewSLARepository(db *gorm.DB) *SLARepository {
+	return &SLARepository{db: db}
+}
+
+func (r *SLARepository) CreatePolicy(policy *model.SLAPolicy) error {
+	return r.db.Create(policy).Error
+}
+
+func (r *SLARepository) FindPolicyById(id int64) (*model.SLAPolicy, error) {
+	var policy model.SLAPolicy
+	err := r.db.First(&policy, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// FindPolicies returns every policy, default policies first
+func (r *SLARepository) FindPolicies() ([]*model.SLAPolicy, error) {
+	var policies []*model.SLAPolicy
+	err := r.db.Order("department_id IS NOT NULL, department_id ASC, id ASC").Find(&policies).Error
+	return policies, err
+}
+
+// FindPolicy returns the policy for the priority in the department, or the
+// default policy for the priority when nil
+func (r *SLARepository) FindPolicy(departmentId *uint, priority string) (*model.SLAPolicy, error) {
+	var policy model.SLAPolicy
+	query := r.db.Where("priority = ?", priority)
+	if departmentId != nil {
+		query = query.Where("department_id = ?", *departmentId)
+	} else {
+		query = query.Where("department_id IS NULL")
+	}
+	err := query.First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *SLARepository) UpdatePolicy(id int64, fields map[string]interface{}) error {
+	return r.db.Model(&model.SLAPolicy{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *SLARepository) DeletePolicy(id int64) error {
+	return r.db.Delete(&model.SLAPolicy{}, id).Error
+}
+
+func (r *SLARepository) CreateTimers(timers []*model.SLATimer) error {
+	return r.db.Create(timers).Error
+}
+
+func (r *SLARepository) FindTimers(conversationId int64) ([]*model.SLATimer, error) {
+	var timers []*model.SLATimer
+	err := r.db.Where("conversation_id = ?", conversationId).Order("id ASC").Find(&timers).Error
+	return timers, err
+}
+
+// FindOpenTimers returns up to limit timers after afterId whose chat has not yet
+// got its reply or been closed, including breached ones
+func (r *SLARepository) FindOpenTimers(afterId int64, limit int) ([]*model.SLATimer, error) {
+	var timers []*model.SLATimer
+	err := r.db.Where("completed_at IS NULL AND status IN ? AND id > ?", []string{model.SLATimerRunning, model.SLATimerBreached}, afterId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&timers).Error
+	return timers, err
+}
+
+// MarkWarned records that agents were warned about a running timer. It reports
+// false when another replica warned them first.
+func (r *SLARepository) MarkWarned(id int64, now time.Time) (bool, error) {
+	result := r.db.Model(&model.SLATimer{}).
+		Where("id = ? AND status = ? AND warned_at IS NULL", id, model.SLATimerRunning).
+		Update("warned_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Breach marks a running timer breached and records the breach. It reports
+// false when another replica did it first.
+func (r *SLARepository) Breach(timer *model.SLATimer, breach *model.SLABreach) (bool, error) {
+	breached := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.SLATimer{}).
+			Where("id = ? AND status = ?", timer.ID, model.SLATimerRunning).
+			Updates(map[string]interface{}{"status": model.SLATimerBreached, "breached_at": breach.BreachedAt})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		breached = true
+		return tx.Create(breach).Error
+	})
+	return breached, err
+}
+
+// Complete stops a timer once its chat got its reply or was closed. A running
+// timer takes the given status; a breached one stays breached and its breach
+// record is completed too. It reports false when the timer was already complete.
+func (r *SLARepository) Complete(id int64, status string, completedAt time.Time) (bool, error) {
+	completed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.SLATimer{}).
+			Where("id = ? AND completed_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"status":       gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", model.SLATimerRunning, status),
+				"completed_at": completedAt,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		completed = true
+		return tx.Model(&model.SLABreach{}).Where("timer_id = ?", id).Update("completed_at", completedAt).Error
+	})
+	return completed, err
+}
+
+// Reschedule moves a running timer to another policy's target
+func (r *SLARepository) Reschedule(id int64, policyId uint, targetSeconds int, warnAt, dueAt time.Time) (bool, error) {
+	result := r.db.Model(&model.SLATimer{}).
+		Where("id = ? AND status = ?", id, model.SLATimerRunning).
+		Updates(map[string]interface{}{
+			"policy_id":      policyId,
+			"target_seconds": targetSeconds,
+			"warn_at":        warnAt,
+			"due_at":         dueAt,
+			"warned_at":      nil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// FindBreaches returns a page of breaches matching the filter, newest first
+func (r *SLARepository) FindBreaches(filter SLABreachFilter, beforeId int64, limit int) ([]*model.SLABreach, error) {
+	var breaches []*model.SLABreach
+	query := r.filteredBreaches(filter)
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&breaches).Error
+	return breaches, err
+}
+
+// CountBreaches returns the number of breaches matching the filter per department and metric
+func (r *SLARepository) CountBreaches(filter SLABreachFilter) ([]*SLABreachCount, error) {
+	var rows []*SLABreachCount
+	err := r.filteredBreaches(filter).
+		Select("department_id, metric, COUNT(*) AS count").
+		Group("department_id, metric").
+		Order("count DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *SLARepository) filteredBreaches(filter SLABreachFilter) *gorm.DB {
+	query := r.db.Model(&model.SLABreach{}).Where("breached_at >= ? AND breached_at < ?", filter.From, filter.To)
+	if filter.DepartmentID != nil {
+		query = query.Where("department_id = ?", *filter.DepartmentID)
+	}
+	if filter.AgentID != nil {
+		query = query.Where("agent_id = ?", *filter.AgentID)
+	}
+	if filter.Metric != "" {
+		query = query.Where("metric = ?", filter.Metric)
+	}
+	return query
+}
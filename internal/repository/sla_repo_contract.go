@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type ISLARepository interface {
+	CreatePolicy(policy *model.SLAPolicy) error
+	FindPolicyById(id int64) (*model.SLAPolicy, error)
+	FindPolicies() ([]*model.SLAPolicy, error)
+	FindPolicy(departmentId *uint, priority string) (*model.SLAPolicy, error)
+	UpdatePolicy(id int64, fields map[string]interface{}) error
+	DeletePolicy(id int64) error
+	CreateTimers(timers []*model.SLATimer) error
+	FindTimers(conversationId int64) ([]*model.SLATimer, error)
+	FindOpenTimers(afterId int64, limit int) ([]*model.SLATimer, error)
+	MarkWarned(id int64, now time.Time) (bool, error)
+	Breach(timer *model.SLATimer, breach *model.SLABreach) (bool, error)
+	Complete(id int64, status string, completedAt time.Time) (bool, error)
+	Reschedule(id int64, policyId uint, targetSeconds int, warnAt, dueAt time.Time) (bool, error)
+	FindBreaches(filter SLABreachFilter, beforeId int64, limit int) ([]*model.SLABreach, error)
+	CountBreaches(filter SLABreachFilter) ([]*SLABreachCount, error)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_starter/internal/model"
+)
+
+// lostRace makes the conditional update of SLA timers match nothing, as when
+// another replica changed the timer first
+func lostRace(query string) int64 {
+	if strings.HasPrefix(query, "UPDATE `sla_timers`") {
+		return 0
+	}
+	return 1
+}
+
+func TestBreachRecordsTheBreachOnce(t *testing.T) {
+	now := time.Now()
+	timer := &model.SLATimer{ID: 3}
+
+	conn := &scriptedConn{}
+	breached, err := NewSLARepository(openScripted(t, conn)).Breach(timer, &model.SLABreach{TimerID: 3, BreachedAt: now})
+	if err != nil || !breached {
+		t.Fatalf("Breach = %v, %v; want true, nil", breached, err)
+	}
+	writes := conn.writes()
+	if len(writes) != 2 || !strings.HasPrefix(writes[1], "INSERT INTO `sla_breaches`") {
+		t.Errorf("the replica that breaches the timer should record the breach, got %q", writes)
+	}
+
+	conn = &scriptedConn{affected: lostRace}
+	breached, err = NewSLARepository(openScripted(t, conn)).Breach(timer, &model.SLABreach{TimerID: 3, BreachedAt: now})
+	if err != nil || breached {
+		t.Fatalf("Breach = %v, %v; want false, nil", breached, err)
+	}
+	if writes := conn.writes(); len(writes) != 1 {
+		t.Errorf("a replica that lost the race should not record a breach, got %q", writes)
+	}
+}
+
+func TestCompleteOnlyOnce(t *testing.T) {
+	now := time.Now()
+
+	conn := &scriptedConn{}
+	completed, err := NewSLARepository(openScripted(t, conn)).Complete(3, model.SLATimerMet, now)
+	if err != nil || !completed {
+		t.Fatalf("Complete = %v, %v; want true, nil", completed, err)
+	}
+	writes := conn.writes()
+	if len(writes) != 2 || !strings.Contains(writes[0], "completed_at IS NULL") || !strings.HasPrefix(writes[1], "UPDATE `sla_breaches`") {
+		t.Errorf("completing should stop the timer only when it runs and complete its breach, got %q", writes)
+	}
+
+	conn = &scriptedConn{affected: lostRace}
+	completed, err = NewSLARepository(openScripted(t, conn)).Complete(3, model.SLATimerMet, now)
+	if err != nil || completed {
+		t.Fatalf("Complete = %v, %v; want false, nil", completed, err)
+	}
+	if writes := conn.writes(); len(writes) != 1 {
+		t.Errorf("an already complete timer should be left alone, got %q", writes)
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SlashCommandRepository struct {
+	db *gorm.DB
+}
+
+func NewSlashCommandRepository(db *gorm.DB) *SlashCommandRepository {
+	return &SlashCommandRepository{db: db}
+}
+
+func (r *SlashCommandRepository) Create(command *model.SlashCommand) error {
+	return r.db.Create(command).Error
+}
+
+func (r *SlashCommandRepository) FindById(id int64) (*model.SlashCommand, error) {
+	var command model.SlashCommand
+	err := r.db.First(&command, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (r *SlashCommandRepository) FindByName(name string) (*model.SlashCommand, error) {
+	var command model.SlashCommand
+	err := r.db.Where("name = ?", name).First(&command).Error
+	if err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (r *SlashCommandRepository) FindAll() ([]*model.SlashCommand, error) {
+	var commands []*model.SlashCommand
+	err := r.db.Order("name").Find(&commands).Error
+	return commands, err
+}
+
+func (r *SlashCommandRepository) FindEnabled() ([]*model.SlashCommand, error) {
+	var commands []*model.SlashCommand
+	err := r.db.Where("enabled = ?", true).Order("name").Find(&commands).Error
+	return commands, err
+}
+
+// Save writes the command's editable fields
+func (r *SlashCommandRepository) Save(command *model.SlashCommand) error {
+	return r.db.Model(command).Select("name", "description", "usage_hint", "url", "secret", "enabled").Updates(command).Error
+}
+
+func (r *SlashCommandRepository) Delete(id int64) error {
+	return r.db.Delete(&model.SlashCommand{}, id).Error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "go_starter/internal/model"
+
+type ISlashCommandRepository interface {
+	Create(command *model.SlashCommand) error
+	FindById(id int64) (*model.SlashCommand, error)
+	FindByName(name string) (*model.SlashCommand, error)
+	FindAll() ([]*model.SlashCommand, error)
+	FindEnabled() ([]*model.SlashCommand, error)
+	Save(command *model.SlashCommand) error
+	Delete(id int64) error
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TranscriptRepository struct {
+	db *gorm.DB
+}
+
+func NewTranscriptRepository(db *gorm.DB) *TranscriptRepository {
+	return &TranscriptRepository{db: db}
+}
+
+func (r *TranscriptRepository) Create(export *model.TranscriptExport) error {
+	return r.db.Create(export).Error
+}
+
+// FindById returns the export without its content
+func (r *TranscriptRepository) FindById(id int64) (*model.TranscriptExport, error) {
+	var export model.TranscriptExport
+	err := r.db.Omit("content").First(&export, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindWithContent returns the export including the generated transcript
+func (r *TranscriptRepository) FindWithContent(id int64) (*model.TranscriptExport, error) {
+	var export model.TranscriptExport
+	err := r.db.First(&export, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindWaiting returns exports still to be generated: pending ones and those whose
+// processing started before staleBefore, presumably on a replica that stopped
+func (r *TranscriptRepository) FindWaiting(staleBefore time.Time, limit int) ([]*model.TranscriptExport, error) {
+	var exports []*model.TranscriptExport
+	err := r.db.Omit("content").
+		Where("status = ? OR (status = ? AND started_at < ?)", model.ExportPending, model.ExportProcessing, staleBefore).
+		Order("id ASC").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// Claim marks a waiting export as processing. It reports false when another
+// replica claimed it first.
+func (r *TranscriptRepository) Claim(id int64, now, staleBefore time.Time) (bool, error) {
+	result := r.db.Model(&model.TranscriptExport{}).
+		Where("id = ? AND (status = ? OR (status = ? AND started_at < ?))", id, model.ExportPending, model.ExportProcessing, staleBefore).
+		Updates(map[string]interface{}{"status": model.ExportProcessing, "started_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Complete stores the generated transcript and marks the export ready, unless
+// the purge failed it while it was being generated
+func (r *TranscriptRepository) Complete(id int64, content string, now time.Time) error {
+	return r.db.Model(&model.TranscriptExport{}).Where("id = ? AND status = ?", id, model.ExportProcessing).Updates(map[string]interface{}{
+		"status":       model.ExportReady,
+		"content":      content,
+		"size":         len(content),
+		"completed_at": now,
+	}).Error
+}
+
+// Fail marks the export failed with the reason
+func (r *TranscriptRepository) Fail(id int64, reason string, now time.Time) error {
+	return r.db.Model(&model.TranscriptExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.ExportFailed,
+		"error":        truncate(reason, 255),
+		"completed_at": now,
+	}).Error
+}
+
+// DeleteExpired removes exports whose download links have expired
+func (r *TranscriptRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", now).Delete(&model.TranscriptExport{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type ITranscriptRepository interface {
+	Create(export *model.TranscriptExport) error
+	FindById(id int64) (*model.TranscriptExport, error)
+	FindWithContent(id int64) (*model.TranscriptExport, error)
+	FindWaiting(staleBefore time.Time, limit int) ([]*model.TranscriptExport, error)
+	Claim(id int64, now, staleBefore time.Time) (bool, error)
+	Complete(id int64, content string, now time.Time) error
+	Fail(id int64, reason string, now time.Time) error
+	DeleteExpired(now time.Time) (int64, error)
+}
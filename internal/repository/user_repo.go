@@ -30,6 +30,37 @@ func (r *UserRepository) FindById(userId int64) (*model.User, error) {
 	return &user, err
 }
 
+func (r *UserRepository) FindByIds(userIds []uint) ([]*model.User, error) {
+	var users []*model.User
+	if len(userIds) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", userIds).Find(&users).Error
+	return users, err
+}
+
+// FindByType returns every user of the given type, oldest first
+func (r *UserRepository) FindByType(userType string) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.Where("type = ?", userType).Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+// IdsByRole returns the IDs of every user with the given role
+func (r *UserRepository) IdsByRole(role string) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.User{}).Where("role = ?", role).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SetRoleByEmails gives the role to every user whose email is listed
+func (r *UserRepository) SetRoleByEmails(emails []string, role string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.User{}).Where("email IN ?", emails).Update("role", role).Error
+}
+
 func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 	var user model.User
 	err := r.db.Where("email = ?", email).First(&user).Error
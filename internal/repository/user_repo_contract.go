@@ -6,6 +6,10 @@ type IUserRepository interface {
 	Create(user *model.User) error
 	FindAll() ([]*model.User, error)
 	FindById(userId int64) (*model.User, error)
+	FindByIds(userIds []uint) ([]*model.User, error)
+	FindByType(userType string) ([]*model.User, error)
+	IdsByRole(role string) ([]uint, error)
+	SetRoleByEmails(emails []string, role string) error
 	FindByEmail(email string) (*model.User, error)
 	UpdateById(userId int64, user *model.User) error
 	DeleteById(userId int64) error
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type VisitorRepository struct {
+	db *gorm.DB
+}
+
+func NewVisitorRepository(db *gorm.DB) *VisitorRepository {
+	return &VisitorRepository{db: db}
+}
+
+func (r *VisitorRepository) Create(visitor *model.Visitor) error {
+	return r.db.Create(visitor).Error
+}
+
+func (r *VisitorRepository) FindById(visitorId uint) (*model.Visitor, error) {
+	var visitor model.Visitor
+	err := r.db.First(&visitor, visitorId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &visitor, nil
+}
+
+func (r *VisitorRepository) Update(visitorId uint, fields map[string]interface{}) error {
+	return r.db.Model(&model.Visitor{}).Where("id = ?", visitorId).Updates(fields).Error
+}
+
+// TouchLastSeen records that the visitor is still active on the site
+func (r *VisitorRepository) TouchLastSeen(visitorId uint, now time.Time) error {
+	return r.db.Model(&model.Visitor{}).Where("id = ?", visitorId).Update("last_seen_at", now).Error
+}
+
+// LinkUser attaches the visitor to a registered account. It only succeeds while the
+// visitor is still unlinked, so two accounts cannot claim the same visitor.
+func (r *VisitorRepository) LinkUser(visitorId, userId uint) (bool, error) {
+	result := r.db.Model(&model.Visitor{}).
+		Where("id = ? AND user_id IS NULL", visitorId).
+		Update("user_id", userId)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IVisitorRepository interface {
+	Create(visitor *model.Visitor) error
+	FindById(visitorId uint) (*model.Visitor, error)
+	Update(visitorId uint, fields map[string]interface{}) error
+	TouchLastSeen(visitorId uint, now time.Time) error
+	LinkUser(visitorId, userId uint) (bool, error)
+}
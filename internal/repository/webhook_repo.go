@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateEndpoint(endpoint *model.WebhookEndpoint) error {
+	return r.db.Create(endpoint).Error
+}
+
+func (r *WebhookRepository) FindEndpointById(id int64) (*model.WebhookEndpoint, error) {
+	var endpoint model.WebhookEndpoint
+	err := r.db.First(&endpoint, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *WebhookRepository) FindEndpoints() ([]*model.WebhookEndpoint, error) {
+	var endpoints []*model.WebhookEndpoint
+	err := r.db.Order("id ASC").Find(&endpoints).Error
+	return endpoints, err
+}
+
+func (r *WebhookRepository) FindEnabledEndpoints() ([]*model.WebhookEndpoint, error) {
+	var endpoints []*model.WebhookEndpoint
+	err := r.db.Where("enabled = ?", true).Find(&endpoints).Error
+	return endpoints, err
+}
+
+// SaveEndpoint writes the endpoint's editable fields
+func (r *WebhookRepository) SaveEndpoint(endpoint *model.WebhookEndpoint) error {
+	return r.db.Model(endpoint).Select("url", "description", "secret", "events", "enabled").Updates(endpoint).Error
+}
+
+// DeleteEndpoint removes the endpoint with its deliveries and their attempts
+func (r *WebhookRepository) DeleteEndpoint(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&model.WebhookDelivery{}).Select("id").Where("endpoint_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&model.WebhookAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("endpoint_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.WebhookEndpoint{}, id).Error
+	})
+}
+
+func (r *WebhookRepository) CreateDeliveries(deliveries []*model.WebhookDelivery) error {
+	return r.db.Create(deliveries).Error
+}
+
+func (r *WebhookRepository) FindDelivery(id int64) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// FindDeliveries returns a page of the endpoint's deliveries, newest first, older
+// than beforeId when set and only those with the status when given
+func (r *WebhookRepository) FindDeliveries(endpointId int64, status string, beforeId int64, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	query := r.db.Omit("payload").Where("endpoint_id = ?", endpointId)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if beforeId > 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindDue returns up to limit pending deliveries whose next attempt is due, oldest first
+func (r *WebhookRepository) FindDue(now time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Claim starts the next attempt of a due delivery and leases it until leaseUntil,
+// after which it is due again if the attempt never reported back. It reports false
+// when another replica claimed the attempt first.
+func (r *WebhookRepository) Claim(delivery *model.WebhookDelivery, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ? AND next_attempt_at <= ?", delivery.ID, model.WebhookDeliveryPending, delivery.Attempts, now).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+			"last_attempt_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordAttempt logs the attempt and stores its outcome on the delivery
+func (r *WebhookRepository) RecordAttempt(attempt *model.WebhookAttempt, fields map[string]interface{}) error {
+	attempt.ResponseBody = truncate(attempt.ResponseBody, 1000)
+	attempt.Error = truncate(attempt.Error, 255)
+	if reason, ok := fields["error"].(string); ok {
+		fields["error"] = truncate(reason, 255)
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.WebhookDelivery{}).Where("id = ?", attempt.DeliveryID).Updates(fields).Error
+	})
+}
+
+// FindAttempts returns the delivery's attempts, first one first
+func (r *WebhookRepository) FindAttempts(deliveryId int64) ([]*model.WebhookAttempt, error) {
+	var attempts []*model.WebhookAttempt
+	err := r.db.Where("delivery_id = ?", deliveryId).Order("id ASC").Find(&attempts).Error
+	return attempts, err
+}
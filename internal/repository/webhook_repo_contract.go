@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"go_starter/internal/model"
+	"time"
+)
+
+type IWebhookRepository interface {
+	CreateEndpoint(endpoint *model.WebhookEndpoint) error
+	FindEndpointById(id int64) (*model.WebhookEndpoint, error)
+	FindEndpoints() ([]*model.WebhookEndpoint, error)
+	FindEnabledEndpoints() ([]*model.WebhookEndpoint, error)
+	SaveEndpoint(endpoint *model.WebhookEndpoint) error
+	DeleteEndpoint(id int64) error
+	CreateDeliveries(deliveries []*model.WebhookDelivery) error
+	FindDelivery(id int64) (*model.WebhookDelivery, error)
+	FindDeliveries(endpointId int64, status string, beforeId int64, limit int) ([]*model.WebhookDelivery, error)
+	FindDue(now time.Time, limit int) ([]*model.WebhookDelivery, error)
+	Claim(delivery *model.WebhookDelivery, now, leaseUntil time.Time) (bool, error)
+	RecordAttempt(attempt *model.WebhookAttempt, fields map[string]interface{}) error
+	FindAttempts(deliveryId int64) ([]*model.WebhookAttempt, error)
+}
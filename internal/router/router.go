@@ -3,24 +3,166 @@ package router
 import (
 	"go_starter/internal/handler"
 	"go_starter/internal/middleware"
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
 	"go_starter/internal/repository"
 	"go_starter/internal/service"
+	"go_starter/internal/worker"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg interface{}, logger *zap.Logger) {
+// botRouteScopes lists the routes bot API keys may call and the scope each needs
+var botRouteScopes = map[string]string{
+	"GET /api/conversations":               model.ScopeConversationsRead,
+	"GET /api/conversations/:id":           model.ScopeConversationsRead,
+	"GET /api/conversations/:id/members":   model.ScopeConversationsRead,
+	"GET /api/conversations/:id/messages":  model.ScopeMessagesRead,
+	"POST /api/conversations/:id/messages": model.ScopeMessagesWrite,
+	"POST /api/conversations/:id/typing":   model.ScopeMessagesWrite,
+}
+
+// SetupRoutes wires every module and registers its routes. It returns the runner
+// holding the background jobs those modules need; the caller starts it.
+func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg interface{}, logger *zap.Logger) *worker.Runner {
 	api := r.Group("/api")
+	jobs := worker.NewRunner(logger)
 
 	// User module
 	userRepo := repository.NewUserRepository(db)
-	userSvc := service.NewUserService(userRepo)
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookSvc := service.NewWebhookService(webhookRepo, userRepo, &http.Client{Timeout: 10 * time.Second}, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookSvc, logger)
+	jobs.Add(worker.Job{Name: "webhook-deliveries", Interval: 5 * time.Second, Run: webhookSvc.DeliverDue})
+	userSvc := service.NewUserService(userRepo, webhookSvc)
 	userHandler := handler.NewUserHandler(userSvc, logger)
-	authHandler := handler.NewAuthHandler(userSvc, logger)
 	emailHandler := handler.NewEmailHandler()
 
+	// Bot accounts: integrations call the routes in botRouteScopes with API keys
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	botAccountSvc := service.NewBotAccountService(apiKeyRepo, userRepo)
+	botAccountHandler := handler.NewBotAccountHandler(botAccountSvc, logger)
+	jobs.Add(worker.Job{Name: "api-key-usage", Interval: 10 * time.Minute, Run: botAccountSvc.PurgeUsage})
+	authMiddleware := middleware.AuthMiddleware(&middleware.BotAuth{Lookup: botAccountSvc.Authenticate, Count: botAccountSvc.CountRequest, Scopes: botRouteScopes})
+
+	// Real-time events
+	hub := realtime.NewHub()
+	eventHandler := handler.NewEventHandler(hub, logger)
+
+	// Blocking, presence and contacts
+	blockRepo := repository.NewBlockRepository(db)
+	contactRepo := repository.NewContactRepository(db)
+	blockSvc := service.NewBlockService(blockRepo, userRepo, contactRepo)
+	blockHandler := handler.NewBlockHandler(blockSvc, logger)
+	presenceSvc := service.NewPresenceService(hub, blockRepo)
+	presenceHandler := handler.NewPresenceHandler(presenceSvc, logger)
+	contactSvc := service.NewContactService(contactRepo, userRepo, blockRepo, presenceSvc, hub)
+	contactHandler := handler.NewContactHandler(contactSvc, logger)
+
+	// Conversation module
+	conversationRepo := repository.NewConversationRepository(db)
+	messageRepo := repository.NewMessageRepository(db)
+	conversationSvc := service.NewConversationService(conversationRepo, messageRepo, userRepo, blockRepo, contactRepo, webhookSvc, hub)
+	commandRepo := repository.NewSlashCommandRepository(db)
+	commandSvc := service.NewCommandService(commandRepo, conversationSvc, userRepo, &http.Client{Timeout: 5 * time.Second}, hub)
+	commandHandler := handler.NewCommandHandler(commandSvc, logger)
+	conversationHandler := handler.NewConversationHandler(conversationSvc, commandSvc, logger)
+	incomingWebhookRepo := repository.NewIncomingWebhookRepository(db)
+	incomingWebhookSvc := service.NewIncomingWebhookService(incomingWebhookRepo, conversationSvc)
+	incomingWebhookHandler := handler.NewIncomingWebhookHandler(incomingWebhookSvc, logger)
+	jobs.Add(worker.Job{Name: "incoming-webhook-usage", Interval: 10 * time.Minute, Run: incomingWebhookSvc.PurgeUsage})
+
+	// Invite module
+	inviteRepo := repository.NewInviteRepository(db)
+	inviteSvc := service.NewInviteService(inviteRepo, conversationSvc, userRepo, hub)
+	inviteHandler := handler.NewInviteHandler(inviteSvc, logger)
+
+	// Support agents, departments and chat routing
+	agentRepo := repository.NewAgentRepository(db)
+	departmentRepo := repository.NewDepartmentRepository(db)
+	routingSvc := service.NewRoutingService(agentRepo, departmentRepo, conversationSvc, hub)
+	agentSvc := service.NewAgentService(agentRepo, departmentRepo, userRepo, routingSvc, hub)
+	agentHandler := handler.NewAgentHandler(agentSvc, routingSvc, logger)
+	departmentSvc := service.NewDepartmentService(departmentRepo, userRepo, routingSvc)
+	departmentHandler := handler.NewDepartmentHandler(departmentSvc, logger)
+	collaborationSvc := service.NewCollaborationService(conversationSvc, agentRepo, departmentRepo, routingSvc, hub)
+	collaborationHandler := handler.NewCollaborationHandler(collaborationSvc, logger)
+	jobs.Add(worker.Job{Name: "support-routing", Interval: 5 * time.Second, Run: routingSvc.RouteQueue})
+	jobs.Add(worker.Job{Name: "agent-heartbeats", Interval: 30 * time.Second, Run: agentSvc.ExpireStale})
+
+	// Website visitors
+	visitorRepo := repository.NewVisitorRepository(db)
+	transcriptRepo := repository.NewTranscriptRepository(db)
+	transcriptSvc := service.NewTranscriptService(transcriptRepo, conversationSvc, visitorRepo)
+	transcriptHandler := handler.NewTranscriptHandler(transcriptSvc, logger)
+	jobs.Add(worker.Job{Name: "transcript-exports", Interval: 10 * time.Second, Run: transcriptSvc.ProcessExports})
+	slaRepo := repository.NewSLARepository(db)
+	slaSvc := service.NewSLAService(slaRepo, conversationSvc, departmentRepo, hub)
+	slaHandler := handler.NewSLAHandler(slaSvc, logger)
+	jobs.Add(worker.Job{Name: "sla-timers", Interval: 15 * time.Second, Run: slaSvc.EvaluateTimers})
+	supervisorSvc := service.NewSupervisorService(conversationSvc, agentRepo, departmentRepo)
+	supervisorHandler := handler.NewSupervisorHandler(supervisorSvc, hub, logger)
+
+	// Analytics rollups and reports
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	analyticsSvc := service.NewAnalyticsService(analyticsRepo)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsSvc, logger)
+	jobs.Add(worker.Job{Name: "analytics-rollup", Interval: time.Minute, Run: analyticsSvc.Rollup})
+
+	// Chatbot auto-responder
+	botRepo := repository.NewBotFlowRepository(db)
+	botSvc := service.NewBotService(botRepo, conversationSvc, visitorRepo, departmentRepo, routingSvc, slaSvc)
+	botHandler := handler.NewBotHandler(botSvc, logger)
+	visitorSvc := service.NewVisitorService(visitorRepo, conversationSvc, departmentSvc, routingSvc, transcriptSvc, slaSvc, botSvc, hub)
+	visitorHandler := handler.NewVisitorHandler(visitorSvc, hub, logger)
+
+	offlineMessageRepo := repository.NewOfflineMessageRepository(db)
+	offlineMessageSvc := service.NewOfflineMessageService(offlineMessageRepo, departmentRepo, visitorRepo, userRepo, departmentSvc, hub)
+	offlineMessageHandler := handler.NewOfflineMessageHandler(offlineMessageSvc, logger)
+
+	// Canned responses
+	cannedResponseRepo := repository.NewCannedResponseRepository(db)
+	cannedResponseSvc := service.NewCannedResponseService(cannedResponseRepo, conversationSvc, visitorRepo, departmentRepo)
+	cannedResponseHandler := handler.NewCannedResponseHandler(cannedResponseSvc, logger)
+
+	// Post-chat ratings
+	ratingRepo := repository.NewRatingRepository(db)
+	ratingSvc := service.NewRatingService(ratingRepo, conversationSvc, visitorRepo, departmentRepo)
+	ratingHandler := handler.NewRatingHandler(ratingSvc, logger)
+	jobs.Add(worker.Job{Name: "rating-requests", Interval: time.Minute, Run: ratingSvc.SendRatingRequests})
+
+	authHandler := handler.NewAuthHandler(userSvc, inviteSvc, visitorSvc, logger)
+
+	// Pinned messages and bookmarks
+	pinRepo := repository.NewPinRepository(db)
+	pinSvc := service.NewPinService(pinRepo, conversationSvc, hub)
+	pinHandler := handler.NewPinHandler(pinSvc, logger)
+	bookmarkRepo := repository.NewBookmarkRepository(db)
+	bookmarkSvc := service.NewBookmarkService(bookmarkRepo, conversationSvc)
+	bookmarkHandler := handler.NewBookmarkHandler(bookmarkSvc, logger)
+
+	// Polls
+	pollRepo := repository.NewPollRepository(db)
+	pollSvc := service.NewPollService(pollRepo, conversationSvc)
+	pollHandler := handler.NewPollHandler(pollSvc, logger)
+	jobs.Add(worker.Job{Name: "poll-close", Interval: 15 * time.Second, Run: pollSvc.CloseDue})
+
+	// Disappearing messages and retention policies
+	retentionRepo := repository.NewRetentionRepository(db)
+	retentionSvc := service.NewRetentionService(retentionRepo, conversationSvc, userRepo)
+	retentionHandler := handler.NewRetentionHandler(retentionSvc, logger)
+	jobs.Add(worker.Job{Name: "message-purge", Interval: time.Minute, Run: retentionSvc.Purge})
+
+	// Scheduled messages
+	scheduledRepo := repository.NewScheduledMessageRepository(db)
+	scheduledSvc := service.NewScheduledMessageService(scheduledRepo, conversationSvc, logger)
+	scheduledHandler := handler.NewScheduledMessageHandler(scheduledSvc, logger)
+	jobs.Add(worker.Job{Name: "scheduled-messages", Interval: 5 * time.Second, Run: scheduledSvc.DispatchDue})
+
 	userGroup := api.Group("/users")
 	{
 		userGroup.POST("", userHandler.Create)
@@ -29,6 +171,7 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg interface{}, logger *zap.Logger
 		userGroup.GET("/:id", userHandler.GetById)
 		userGroup.PUT("/:id", userHandler.Update)
 		userGroup.DELETE("/:id", userHandler.Delete)
+		userGroup.PUT("/:id/role", authMiddleware, agentHandler.SetRole)
 	}
 
 	// Auth module
@@ -36,9 +179,286 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg interface{}, logger *zap.Logger
 	{
 		authGroup.POST("/register", authHandler.Register)
 		authGroup.POST("/login", authHandler.Login)
-		authGroup.GET("/profile", middleware.AuthMiddleware(), authHandler.GetProfile)
+		authGroup.GET("/profile", authMiddleware, authHandler.GetProfile)
+		authGroup.PUT("/privacy", authMiddleware, authHandler.UpdatePrivacy)
+		authGroup.POST("/link-visitor", authMiddleware, visitorHandler.Link)
 	}
 
 	api.POST("/email/test", emailHandler.SendTestEmail)
 
+	api.GET("/events", authMiddleware, eventHandler.Stream)
+	api.GET("/presence", authMiddleware, presenceHandler.Get)
+
+	// Blocking
+	blockGroup := api.Group("/blocks", authMiddleware)
+	{
+		blockGroup.GET("", blockHandler.List)
+		blockGroup.POST("", blockHandler.Block)
+		blockGroup.DELETE("/:userId", blockHandler.Unblock)
+	}
+
+	// Contacts
+	contactGroup := api.Group("/contacts", authMiddleware)
+	{
+		contactGroup.GET("", contactHandler.List)
+		contactGroup.DELETE("/:userId", contactHandler.Remove)
+		contactGroup.GET("/:userId/mutual", contactHandler.Mutual)
+		contactGroup.GET("/requests", contactHandler.ListRequests)
+		contactGroup.POST("/requests", contactHandler.SendRequest)
+		contactGroup.POST("/requests/:requestId/accept", contactHandler.AcceptRequest)
+		contactGroup.POST("/requests/:requestId/decline", contactHandler.DeclineRequest)
+		contactGroup.DELETE("/requests/:requestId", contactHandler.CancelRequest)
+	}
+
+	// Conversation module
+	conversationGroup := api.Group("/conversations", authMiddleware)
+	{
+		conversationGroup.GET("", conversationHandler.List)
+		conversationGroup.POST("/direct", conversationHandler.CreateDirect)
+		conversationGroup.POST("/group", conversationHandler.CreateGroup)
+		conversationGroup.GET("/:id", conversationHandler.GetById)
+		conversationGroup.PUT("/:id", conversationHandler.Update)
+		conversationGroup.PUT("/:id/disappearing", conversationHandler.SetDisappearing)
+		conversationGroup.GET("/:id/messages", conversationHandler.ListMessages)
+		conversationGroup.POST("/:id/messages", conversationHandler.SendMessage)
+		conversationGroup.POST("/:id/typing", conversationHandler.Typing)
+		conversationGroup.GET("/:id/members", conversationHandler.ListMembers)
+		conversationGroup.POST("/:id/members", conversationHandler.AddMembers)
+		conversationGroup.DELETE("/:id/members/:userId", conversationHandler.RemoveMember)
+		conversationGroup.PUT("/:id/members/:userId/role", conversationHandler.ChangeRole)
+		conversationGroup.POST("/:id/leave", conversationHandler.Leave)
+		conversationGroup.POST("/:id/mute", conversationHandler.Mute)
+		conversationGroup.DELETE("/:id/mute", conversationHandler.Unmute)
+		conversationGroup.POST("/:id/pin", conversationHandler.Pin)
+		conversationGroup.DELETE("/:id/pin", conversationHandler.Unpin)
+		conversationGroup.POST("/:id/archive", conversationHandler.Archive)
+		conversationGroup.DELETE("/:id/archive", conversationHandler.Unarchive)
+		conversationGroup.POST("/:id/transfer", conversationHandler.TransferOwnership)
+		conversationGroup.POST("/:id/close", visitorHandler.CloseChat)
+		conversationGroup.POST("/:id/handoff", collaborationHandler.Handoff)
+		conversationGroup.POST("/:id/collaborators", collaborationHandler.InviteCollaborator)
+		conversationGroup.DELETE("/:id/collaborators/:userId", collaborationHandler.RemoveCollaborator)
+		conversationGroup.POST("/:id/whispers", collaborationHandler.Whisper)
+		conversationGroup.POST("/:id/canned-responses", cannedResponseHandler.Send)
+		conversationGroup.GET("/:id/transcript", transcriptHandler.Export)
+		conversationGroup.GET("/:id/sla", slaHandler.Timers)
+		conversationGroup.PUT("/:id/priority", slaHandler.SetPriority)
+		conversationGroup.POST("/:id/transcript/email", transcriptHandler.RequestEmail)
+		conversationGroup.POST("/:id/scheduled", scheduledHandler.Create)
+		conversationGroup.GET("/:id/pins", pinHandler.List)
+		conversationGroup.POST("/:id/pins", pinHandler.Pin)
+		conversationGroup.DELETE("/:id/pins/:messageId", pinHandler.Unpin)
+		conversationGroup.POST("/:id/polls", pollHandler.Create)
+		conversationGroup.GET("/:id/polls/:pollId", pollHandler.Get)
+		conversationGroup.PUT("/:id/polls/:pollId/votes", pollHandler.Vote)
+		conversationGroup.DELETE("/:id/polls/:pollId/votes", pollHandler.Retract)
+		conversationGroup.POST("/:id/polls/:pollId/close", pollHandler.Close)
+		conversationGroup.GET("/:id/invites", inviteHandler.List)
+		conversationGroup.POST("/:id/invites", inviteHandler.Create)
+		conversationGroup.POST("/:id/invites/email", inviteHandler.InviteByEmail)
+		conversationGroup.DELETE("/:id/invites/:inviteId", inviteHandler.Revoke)
+		conversationGroup.GET("/:id/incoming-webhooks", incomingWebhookHandler.List)
+		conversationGroup.POST("/:id/incoming-webhooks", incomingWebhookHandler.Create)
+		conversationGroup.POST("/:id/incoming-webhooks/:hookId/token", incomingWebhookHandler.RegenerateToken)
+		conversationGroup.DELETE("/:id/incoming-webhooks/:hookId", incomingWebhookHandler.Revoke)
+		conversationGroup.GET("/:id/join-requests", inviteHandler.ListJoinRequests)
+		conversationGroup.POST("/:id/join-requests/:requestId/approve", inviteHandler.ApproveJoinRequest)
+		conversationGroup.POST("/:id/join-requests/:requestId/decline", inviteHandler.DeclineJoinRequest)
+	}
+
+	// Bookmarks
+	bookmarkGroup := api.Group("/bookmarks", authMiddleware)
+	{
+		bookmarkGroup.GET("", bookmarkHandler.List)
+		bookmarkGroup.POST("", bookmarkHandler.Add)
+		bookmarkGroup.DELETE("/:messageId", bookmarkHandler.Remove)
+	}
+
+	// Scheduled messages
+	scheduledGroup := api.Group("/scheduled-messages", authMiddleware)
+	{
+		scheduledGroup.GET("", scheduledHandler.List)
+		scheduledGroup.PUT("/:id", scheduledHandler.Update)
+		scheduledGroup.DELETE("/:id", scheduledHandler.Cancel)
+	}
+
+	// Support agents
+	agentGroup := api.Group("/agents", authMiddleware)
+	{
+		agentGroup.GET("", agentHandler.List)
+		agentGroup.GET("/me", agentHandler.Me)
+		agentGroup.PUT("/me/status", agentHandler.SetStatus)
+		agentGroup.POST("/me/heartbeat", agentHandler.Heartbeat)
+		agentGroup.PUT("/:userId", agentHandler.UpdateCapacity)
+	}
+	api.GET("/support/queue", authMiddleware, agentHandler.Queue)
+
+	// Support departments
+	departmentGroup := api.Group("/departments", authMiddleware)
+	{
+		departmentGroup.GET("", departmentHandler.List)
+		departmentGroup.POST("", departmentHandler.Create)
+		departmentGroup.GET("/:id", departmentHandler.GetById)
+		departmentGroup.PUT("/:id", departmentHandler.Update)
+		departmentGroup.DELETE("/:id", departmentHandler.Delete)
+		departmentGroup.POST("/:id/agents", departmentHandler.AddAgent)
+		departmentGroup.DELETE("/:id/agents/:userId", departmentHandler.RemoveAgent)
+		departmentGroup.POST("/:id/rules", departmentHandler.AddRule)
+		departmentGroup.DELETE("/:id/rules/:ruleId", departmentHandler.DeleteRule)
+		departmentGroup.PUT("/:id/hours", departmentHandler.SetHours)
+		departmentGroup.POST("/:id/holidays", departmentHandler.AddHoliday)
+		departmentGroup.DELETE("/:id/holidays/:holidayId", departmentHandler.DeleteHoliday)
+	}
+
+	// Website visitor widget
+	api.POST("/visitor/sessions", visitorHandler.StartSession)
+	visitorGroup := api.Group("/visitor", middleware.VisitorMiddleware())
+	{
+		visitorGroup.GET("", visitorHandler.Get)
+		visitorGroup.PUT("", visitorHandler.Update)
+		visitorGroup.GET("/events", visitorHandler.Stream)
+		visitorGroup.GET("/departments", departmentHandler.ListPublic)
+		visitorGroup.GET("/status", departmentHandler.Availability)
+		visitorGroup.POST("/offline-messages", offlineMessageHandler.Submit)
+		visitorGroup.GET("/chat", visitorHandler.GetChat)
+		visitorGroup.POST("/chat", visitorHandler.OpenChat)
+		visitorGroup.POST("/chat/end", visitorHandler.EndChat)
+		visitorGroup.POST("/chat/handoff", visitorHandler.RequestAgent)
+		visitorGroup.GET("/chat/queue", visitorHandler.QueuePosition)
+		visitorGroup.GET("/chat/messages", visitorHandler.ListMessages)
+		visitorGroup.POST("/chat/messages", visitorHandler.SendMessage)
+		visitorGroup.POST("/chat/typing", visitorHandler.Typing)
+		visitorGroup.POST("/chat/transcript", transcriptHandler.VisitorRequestEmail)
+		visitorGroup.POST("/chats/:id/rating", ratingHandler.Rate)
+		visitorGroup.GET("/chats/:id/transcript", transcriptHandler.VisitorExport)
+		visitorGroup.GET("/transcripts/:id", transcriptHandler.GetVisitorExport)
+	}
+
+	// Canned responses
+	cannedResponseGroup := api.Group("/canned-responses", authMiddleware)
+	{
+		cannedResponseGroup.GET("", cannedResponseHandler.List)
+		cannedResponseGroup.POST("", cannedResponseHandler.Create)
+		cannedResponseGroup.GET("/:id", cannedResponseHandler.GetById)
+		cannedResponseGroup.PUT("/:id", cannedResponseHandler.Update)
+		cannedResponseGroup.DELETE("/:id", cannedResponseHandler.Delete)
+		cannedResponseGroup.GET("/:id/preview", cannedResponseHandler.Preview)
+	}
+
+	// Chat ratings: emailed rating links work without a session
+	api.GET("/ratings/link", ratingHandler.GetLink)
+	api.POST("/ratings/link", ratingHandler.RateWithLink)
+	ratingGroup := api.Group("/ratings", authMiddleware)
+	{
+		ratingGroup.GET("", ratingHandler.List)
+		ratingGroup.GET("/agents", ratingHandler.AgentStats)
+		ratingGroup.GET("/departments", ratingHandler.DepartmentStats)
+	}
+
+	// Transcript exports: signed download links work without a session
+	api.GET("/transcripts/download", transcriptHandler.Download)
+	api.GET("/transcripts/:id", authMiddleware, transcriptHandler.GetExport)
+
+	// Live monitoring dashboard for supervisors and admins
+	supervisorGroup := api.Group("/supervisor", authMiddleware, middleware.SupervisorMiddleware(userSvc.Role))
+	{
+		supervisorGroup.GET("/overview", supervisorHandler.Overview)
+		supervisorGroup.GET("/agents", supervisorHandler.Agents)
+		supervisorGroup.GET("/queue", supervisorHandler.Queue)
+		supervisorGroup.GET("/stream", supervisorHandler.Stream)
+	}
+
+	// Activity reports for supervisors and admins
+	analyticsGroup := api.Group("/analytics", authMiddleware, middleware.SupervisorMiddleware(userSvc.Role))
+	{
+		analyticsGroup.GET("/report", analyticsHandler.Report)
+	}
+
+	// SLA policies and breach reports
+	slaGroup := api.Group("/sla", authMiddleware)
+	{
+		slaGroup.GET("/policies", slaHandler.ListPolicies)
+		slaGroup.POST("/policies", slaHandler.CreatePolicy)
+		slaGroup.PUT("/policies/:id", slaHandler.UpdatePolicy)
+		slaGroup.DELETE("/policies/:id", slaHandler.DeletePolicy)
+		slaGroup.GET("/breaches", slaHandler.Breaches)
+		slaGroup.GET("/breaches/stats", slaHandler.BreachStats)
+	}
+
+	// Chatbot flows
+	botFlowGroup := api.Group("/bot-flows", authMiddleware)
+	{
+		botFlowGroup.GET("", botHandler.ListFlows)
+		botFlowGroup.POST("", botHandler.CreateFlow)
+		botFlowGroup.GET("/:id", botHandler.GetFlow)
+		botFlowGroup.PUT("/:id", botHandler.UpdateFlow)
+		botFlowGroup.DELETE("/:id", botHandler.DeleteFlow)
+	}
+
+	// Bot accounts and their API keys
+	botAccountGroup := api.Group("/bots", authMiddleware)
+	{
+		botAccountGroup.GET("", botAccountHandler.ListBots)
+		botAccountGroup.POST("", botAccountHandler.CreateBot)
+		botAccountGroup.GET("/:id/keys", botAccountHandler.ListKeys)
+		botAccountGroup.POST("/:id/keys", botAccountHandler.CreateKey)
+		botAccountGroup.DELETE("/:id/keys/:keyId", botAccountHandler.RevokeKey)
+	}
+
+	// Slash commands
+	api.GET("/commands", authMiddleware, commandHandler.Available)
+	commandGroup := api.Group("/slash-commands", authMiddleware)
+	{
+		commandGroup.GET("", commandHandler.List)
+		commandGroup.POST("", commandHandler.Create)
+		commandGroup.PUT("/:id", commandHandler.Update)
+		commandGroup.DELETE("/:id", commandHandler.Delete)
+		commandGroup.POST("/:id/secret", commandHandler.RotateSecret)
+	}
+
+	// Message retention
+	retentionGroup := api.Group("/retention", authMiddleware)
+	{
+		retentionGroup.GET("/policies", retentionHandler.ListPolicies)
+		retentionGroup.POST("/policies", retentionHandler.CreatePolicy)
+		retentionGroup.PUT("/policies/:id", retentionHandler.UpdatePolicy)
+		retentionGroup.DELETE("/policies/:id", retentionHandler.DeletePolicy)
+		retentionGroup.GET("/purges", retentionHandler.Purges)
+	}
+
+	// Outgoing webhooks
+	webhookGroup := api.Group("/webhooks", authMiddleware)
+	{
+		webhookGroup.GET("", webhookHandler.List)
+		webhookGroup.POST("", webhookHandler.Create)
+		webhookGroup.GET("/:id", webhookHandler.GetById)
+		webhookGroup.PUT("/:id", webhookHandler.Update)
+		webhookGroup.DELETE("/:id", webhookHandler.Delete)
+		webhookGroup.POST("/:id/secret", webhookHandler.RotateSecret)
+		webhookGroup.POST("/:id/ping", webhookHandler.Ping)
+		webhookGroup.GET("/:id/deliveries", webhookHandler.Deliveries)
+		webhookGroup.GET("/:id/deliveries/:deliveryId", webhookHandler.Delivery)
+		webhookGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+	}
+
+	// Incoming webhooks: the secret token in the URL is the only credential
+	api.POST("/hooks/:token", incomingWebhookHandler.Post)
+
+	// Offline messages left by visitors outside business hours
+	offlineMessageGroup := api.Group("/offline-messages", authMiddleware)
+	{
+		offlineMessageGroup.GET("", offlineMessageHandler.List)
+		offlineMessageGroup.GET("/:id", offlineMessageHandler.GetById)
+		offlineMessageGroup.PUT("/:id/status", offlineMessageHandler.SetStatus)
+	}
+
+	// Invite links
+	inviteGroup := api.Group("/invites", authMiddleware)
+	{
+		inviteGroup.GET("/:code", inviteHandler.Preview)
+		inviteGroup.POST("/:code/join", inviteHandler.Join)
+		inviteGroup.POST("/email/accept", inviteHandler.AcceptEmail)
+	}
+
+	return jobs
 }
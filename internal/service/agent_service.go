@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotAgent           = errors.New("user is not a support agent")
+	ErrInvalidAgentStatus = errors.New("status must be online, away or offline")
+	ErrInvalidMaxChats    = errors.New("max chats must be between 1 and 50")
+	ErrInvalidUserRole    = errors.New("role must be user, agent, supervisor or admin")
+)
+
+const (
+	maxAgentChats = 50
+
+	// Agents that stop sending heartbeats for this long are taken offline
+	agentHeartbeatTimeout = 90 * time.Second
+)
+
+// AgentView is an agent together with their routing state
+type AgentView struct {
+	User    UserSummary         `json:"user"`
+	Profile *model.AgentProfile `json:"profile"`
+}
+
+type AgentService struct {
+	repo     *repository.AgentRepository
+	deptRepo *repository.DepartmentRepository
+	userRepo *repository.UserRepository
+	routing  *RoutingService
+	hub      *realtime.Hub
+}
+
+func NewAgentService(repo *repository.AgentRepository, deptRepo *repository.DepartmentRepository, userRepo *repository.UserRepository, routing *RoutingService, hub *realtime.Hub) *AgentService {
+	return &AgentService{
+		repo:     repo,
+		deptRepo: deptRepo,
+		userRepo: userRepo,
+		routing:  routing,
+		hub:      hub,
+	}
+}
+
+// ListAgents returns every agent with their status and load. Support staff only.
+func (s *AgentService) ListAgents(actorId uint) ([]*AgentView, error) {
+	actor, err := s.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsStaff() {
+		return nil, ErrForbidden
+	}
+
+	profiles, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(profiles))
+	for _, profile := range profiles {
+		ids = append(ids, profile.UserID)
+	}
+	users, err := s.userRepo.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	usersById := make(map[uint]*model.User, len(users))
+	for _, user := range users {
+		usersById[user.ID] = user
+	}
+
+	views := make([]*AgentView, 0, len(profiles))
+	for _, profile := range profiles {
+		user, ok := usersById[profile.UserID]
+		if !ok || !user.IsAgent() {
+			continue
+		}
+		views = append(views, &AgentView{User: newUserSummary(user), Profile: profile})
+	}
+	return views, nil
+}
+
+// GetProfile returns the routing profile of the calling agent
+func (s *AgentService) GetProfile(agentId uint) (*model.AgentProfile, error) {
+	if _, err := s.findAgent(agentId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Ensure(agentId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByUser(agentId)
+}
+
+// SetStatus changes the caller's availability. Going online makes the agent eligible
+// for queued chats; going offline hands their open chats to other agents. Away
+// agents keep their chats but receive no new ones.
+func (s *AgentService) SetStatus(agentId uint, status string) (*model.AgentProfile, error) {
+	if status != model.AgentStatusOnline && status != model.AgentStatusAway && status != model.AgentStatusOffline {
+		return nil, ErrInvalidAgentStatus
+	}
+	if _, err := s.findAgent(agentId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Ensure(agentId); err != nil {
+		return nil, err
+	}
+
+	if status == model.AgentStatusOffline {
+		if err := s.routing.AgentOffline(agentId); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.repo.Update(agentId, map[string]interface{}{"status": status, "last_seen_at": time.Now()}); err != nil {
+			return nil, err
+		}
+		if status == model.AgentStatusOnline {
+			if err := s.routing.RouteQueue(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	profile, err := s.repo.FindByUser(agentId)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.PublishToUsers([]uint{agentId}, realtime.Event{Type: realtime.EventAgentStatus, Data: profile})
+	return profile, nil
+}
+
+// Heartbeat keeps an available agent from being timed out
+func (s *AgentService) Heartbeat(agentId uint) error {
+	if _, err := s.findAgent(agentId); err != nil {
+		return err
+	}
+	return s.repo.Update(agentId, map[string]interface{}{"last_seen_at": time.Now()})
+}
+
+// UpdateCapacity sets how many chats an agent handles at once. Admin only.
+func (s *AgentService) UpdateCapacity(adminId, agentId uint, maxChats int) (*model.AgentProfile, error) {
+	if maxChats < 1 || maxChats > maxAgentChats {
+		return nil, ErrInvalidMaxChats
+	}
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findAgent(agentId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Ensure(agentId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(agentId, map[string]interface{}{"max_chats": maxChats}); err != nil {
+		return nil, err
+	}
+	if err := s.routing.RouteQueue(context.Background()); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByUser(agentId)
+}
+
+// SetRole changes a user's role. Admin only, and admins cannot change their own role.
+// Users who stop being agents hand their open chats back to the queue and leave
+// their departments.
+func (s *AgentService) SetRole(adminId, userId uint, role string) (*model.User, error) {
+	if role != model.UserRoleUser && role != model.UserRoleAgent && role != model.UserRoleSupervisor && role != model.UserRoleAdmin {
+		return nil, ErrInvalidUserRole
+	}
+	if adminId == userId {
+		return nil, ErrCannotTargetSelf
+	}
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsBot() {
+		return nil, ErrBotAccount
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	wasAgent := user.IsAgent()
+	if err := s.userRepo.UpdateById(int64(userId), &model.User{Role: role}); err != nil {
+		return nil, err
+	}
+	user.Role = role
+
+	if role == model.UserRoleAgent {
+		if err := s.repo.Ensure(userId); err != nil {
+			return nil, err
+		}
+	} else if wasAgent {
+		if err := s.routing.AgentOffline(userId); err != nil {
+			return nil, err
+		}
+		if err := s.repo.Delete(userId); err != nil {
+			return nil, err
+		}
+		if err := s.deptRepo.RemoveAgentEverywhere(userId); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// ExpireStale takes agents offline whose clients stopped sending heartbeats and
+// reassigns their chats. It runs as a background job.
+func (s *AgentService) ExpireStale(ctx context.Context) error {
+	ids, err := s.repo.StaleIds(time.Now().Add(-agentHeartbeatTimeout))
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.routing.AgentOffline(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AgentService) requireAdmin(userId uint) error {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *AgentService) findAgent(userId uint) (*model.User, error) {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAgent() {
+		return nil, ErrNotAgent
+	}
+	return user, nil
+}
+
+func (s *AgentService) findUser(userId uint) (*model.User, error) {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
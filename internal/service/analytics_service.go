@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+)
+
+var (
+	ErrInvalidInterval    = errors.New("interval must be hour, day or week")
+	ErrHourlyRangeTooLong = errors.New("hourly reports cover at most 31 days")
+)
+
+// Report intervals
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+const (
+	maxHourlyReportRange = 31 * 24 * time.Hour
+
+	// Hours become final this long after they end, once in-flight writes have landed
+	analyticsFinalDelay = 5 * time.Minute
+	// Hours rolled up per run while catching up on history
+	analyticsBackfillBatch = 48
+)
+
+// ReportQuery selects the period, bucket size and timezone of a report
+type ReportQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Location *time.Location
+}
+
+// ReportBucket is the activity of one hour, day or week of a report. Days and
+// weeks start at midnight in the report's timezone, weeks on Monday.
+type ReportBucket struct {
+	Start                       time.Time `json:"start"`
+	Messages                    int64     `json:"messages"`
+	VisitorMessages             int64     `json:"visitor_messages"`
+	ActiveUsers                 int64     `json:"active_users"`
+	ConversationsStarted        int64     `json:"conversations_started"`
+	SupportChatsStarted         int64     `json:"support_chats_started"`
+	SupportChatsClosed          int64     `json:"support_chats_closed"`
+	FirstResponses              int64     `json:"first_responses"`
+	AverageFirstResponseSeconds float64   `json:"average_first_response_seconds"`
+	Ratings                     int64     `json:"ratings"`
+	AverageRating               float64   `json:"average_rating"`
+	CSAT                        float64   `json:"csat"` // share of ratings of 4 or 5
+}
+
+// AnalyticsService reports on activity from hourly rollups kept up to date by a
+// background job. Access is checked by middleware.SupervisorMiddleware on its routes.
+type AnalyticsService struct {
+	repo *repository.AnalyticsRepository
+}
+
+func NewAnalyticsService(repo *repository.AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{repo: repo}
+}
+
+// Report returns the activity of the period bucketed by hour, day or week in the
+// query's timezone, including empty buckets. Rollups cover whole UTC hours, so in
+// timezones offset by a fraction of an hour each hour counts toward the local
+// bucket it starts in.
+func (s *AnalyticsService) Report(query ReportQuery) ([]*ReportBucket, error) {
+	if query.Interval != IntervalHour && query.Interval != IntervalDay && query.Interval != IntervalWeek {
+		return nil, ErrInvalidInterval
+	}
+	if err := validateDateRange(query.From, query.To); err != nil {
+		return nil, err
+	}
+	if query.Interval == IntervalHour && query.To.Sub(query.From) > maxHourlyReportRange {
+		return nil, ErrHourlyRangeTooLong
+	}
+	if query.Location == nil {
+		query.Location = time.UTC
+	}
+
+	// Widen the period to whole buckets so the first and last are complete
+	from := bucketStart(query.From, query.Interval, query.Location)
+	to := query.To
+	if start := bucketStart(to, query.Interval, query.Location); start.Before(to) {
+		to = nextBucket(start, query.Interval)
+	}
+
+	var buckets []*ReportBucket
+	index := map[int64]*ReportBucket{}
+	for start := from; start.Before(to); start = nextBucket(start, query.Interval) {
+		bucket := &ReportBucket{Start: start}
+		buckets = append(buckets, bucket)
+		index[start.Unix()] = bucket
+	}
+	find := func(hour time.Time) *ReportBucket {
+		return index[bucketStart(hour, query.Interval, query.Location).Unix()]
+	}
+
+	hours, err := s.repo.FindHours(from, to)
+	if err != nil {
+		return nil, err
+	}
+	sums := map[*ReportBucket]*model.AnalyticsHour{}
+	for _, hour := range hours {
+		bucket := find(hour.Hour)
+		if bucket == nil {
+			continue
+		}
+		bucket.Messages += hour.Messages
+		bucket.VisitorMessages += hour.VisitorMessages
+		bucket.ConversationsStarted += hour.ConversationsStarted
+		bucket.SupportChatsStarted += hour.SupportChatsStarted
+		bucket.SupportChatsClosed += hour.SupportChatsClosed
+		bucket.FirstResponses += hour.FirstResponses
+		bucket.Ratings += hour.Ratings
+
+		sum, ok := sums[bucket]
+		if !ok {
+			sum = &model.AnalyticsHour{}
+			sums[bucket] = sum
+		}
+		sum.FirstResponseSeconds += hour.FirstResponseSeconds
+		sum.RatingScoreSum += hour.RatingScoreSum
+		sum.PositiveRatings += hour.PositiveRatings
+	}
+	for bucket, sum := range sums {
+		if bucket.FirstResponses > 0 {
+			bucket.AverageFirstResponseSeconds = float64(sum.FirstResponseSeconds) / float64(bucket.FirstResponses)
+		}
+		if bucket.Ratings > 0 {
+			bucket.AverageRating = float64(sum.RatingScoreSum) / float64(bucket.Ratings)
+			bucket.CSAT = float64(sum.PositiveRatings) / float64(bucket.Ratings)
+		}
+	}
+
+	// Users active in several hours of a bucket count once
+	seen := map[*ReportBucket]map[uint]bool{}
+	err = s.repo.EachUserHour(from, to, func(hour time.Time, userId uint) {
+		bucket := find(hour)
+		if bucket == nil {
+			return
+		}
+		if seen[bucket] == nil {
+			seen[bucket] = map[uint]bool{}
+		}
+		if !seen[bucket][userId] {
+			seen[bucket][userId] = true
+			bucket.ActiveUsers++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// Rollup brings the hourly rollups up to date. Hours that ended are rolled up
+// once more and marked final; on a new install history is rolled up a batch per
+// run. Once caught up, the hours not final yet are refreshed so reports include
+// the current hour. Rollups are idempotent, so it is safe on several replicas.
+// It runs as a background job.
+func (s *AnalyticsService) Rollup(ctx context.Context) error {
+	now := time.Now()
+	current := now.Truncate(time.Hour)
+	next, err := s.nextHour(current)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < analyticsBackfillBatch && !next.Add(time.Hour+analyticsFinalDelay).After(now); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.repo.RollupHour(next, true, now); err != nil {
+			return err
+		}
+		next = next.Add(time.Hour)
+	}
+	if current.Sub(next) > time.Hour {
+		return nil
+	}
+
+	for hour := next; !hour.After(current); hour = hour.Add(time.Hour) {
+		if err := s.repo.RollupHour(hour, false, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextHour returns the first hour whose rollup is not final yet
+func (s *AnalyticsService) nextHour(current time.Time) (time.Time, error) {
+	last, err := s.repo.LastFinalHour()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if last != nil {
+		return last.Add(time.Hour), nil
+	}
+	first, err := s.repo.FirstActivity()
+	if err != nil || first == nil {
+		return current, err
+	}
+	return first.Truncate(time.Hour), nil
+}
+
+// bucketStart returns the start of the hour, day or week containing t in the location
+func bucketStart(t time.Time, interval string, location *time.Location) time.Time {
+	local := t.In(location)
+	switch interval {
+	case IntervalHour:
+		// Truncating with the zone offset applied keeps both hours of a DST fall-back apart
+		_, offset := local.Zone()
+		shift := time.Duration(offset) * time.Second
+		return t.Add(shift).Truncate(time.Hour).Add(-shift).In(location)
+	case IntervalWeek:
+		// Weeks start on Monday
+		offset := (int(local.Weekday()) + 6) % 7
+		return time.Date(local.Year(), local.Month(), local.Day()-offset, 0, 0, 0, 0, location)
+	default:
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	}
+}
+
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
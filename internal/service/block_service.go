@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlreadyBlocked = errors.New("user is already blocked")
+	ErrNotBlocked     = errors.New("user is not blocked")
+)
+
+type BlockService struct {
+	repo        *repository.BlockRepository
+	userRepo    *repository.UserRepository
+	contactRepo *repository.ContactRepository
+}
+
+func NewBlockService(repo *repository.BlockRepository, userRepo *repository.UserRepository, contactRepo *repository.ContactRepository) *BlockService {
+	return &BlockService{
+		repo:        repo,
+		userRepo:    userRepo,
+		contactRepo: contactRepo,
+	}
+}
+
+func (s *BlockService) Block(userId, targetId uint) (*model.UserBlock, error) {
+	if userId == targetId {
+		return nil, ErrCannotTargetSelf
+	}
+	if _, err := s.userRepo.FindById(int64(targetId)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	exists, err := s.repo.Exists(userId, targetId)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAlreadyBlocked
+	}
+
+	block := &model.UserBlock{BlockerID: userId, BlockedID: targetId}
+	if err := s.repo.Create(block); err != nil {
+		return nil, err
+	}
+
+	// Blocking ends any contact relationship between the two users
+	if _, err := s.contactRepo.DeleteContact(userId, targetId); err != nil {
+		return nil, err
+	}
+	if err := s.contactRepo.CancelPendingBetween(userId, targetId); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (s *BlockService) Unblock(userId, targetId uint) error {
+	removed, err := s.repo.Delete(userId, targetId)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotBlocked
+	}
+	return nil
+}
+
+func (s *BlockService) ListBlocked(userId uint) ([]*model.UserBlock, error) {
+	return s.repo.FindByBlocker(userId)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+)
+
+var (
+	ErrAlreadyBookmarked = errors.New("message is already bookmarked")
+	ErrNotBookmarked     = errors.New("message is not bookmarked")
+)
+
+type BookmarkService struct {
+	repo          *repository.BookmarkRepository
+	conversations *ConversationService
+}
+
+func NewBookmarkService(repo *repository.BookmarkRepository, conversations *ConversationService) *BookmarkService {
+	return &BookmarkService{
+		repo:          repo,
+		conversations: conversations,
+	}
+}
+
+// Add saves a message from any conversation the user belongs to
+func (s *BookmarkService) Add(userId uint, messageId int64, note string) (*model.Bookmark, error) {
+	message, err := s.conversations.findMessage(0, messageId)
+	if err != nil {
+		return nil, err
+	}
+	conversation, _, err := s.conversations.authorize(userId, int64(message.ConversationID))
+	if err != nil {
+		return nil, err
+	}
+	if message.Internal {
+		canSee, err := s.conversations.canSeeInternal(userId, conversation)
+		if err != nil {
+			return nil, err
+		}
+		if !canSee {
+			return nil, ErrMessageNotFound
+		}
+	}
+
+	exists, err := s.repo.Exists(userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAlreadyBookmarked
+	}
+
+	bookmark := &model.Bookmark{
+		UserID:    userId,
+		MessageID: message.ID,
+		Note:      note,
+	}
+	if err := s.repo.Create(bookmark); err != nil {
+		return nil, err
+	}
+	bookmark.Message = message
+	return bookmark, nil
+}
+
+func (s *BookmarkService) Remove(userId uint, messageId int64) error {
+	removed, err := s.repo.Delete(userId, messageId)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotBookmarked
+	}
+	return nil
+}
+
+func (s *BookmarkService) List(userId uint, page int32, pageSize int32) ([]*model.Bookmark, error) {
+	return s.repo.FindByUser(userId, page, pageSize)
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBotNotFound       = errors.New("bot not found")
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrInvalidBotName    = errors.New("bot name must be 1 to 100 characters")
+	ErrInvalidScopes     = errors.New("scopes must be a non-empty list of known scopes")
+	ErrInvalidRateLimit  = errors.New("rate limit must be between 1 and 6000 requests per minute")
+	ErrInvalidKeyExpiry  = errors.New("expiry must be in the future")
+	ErrAPIKeyRevoked     = errors.New("API key is already revoked")
+	ErrBotAccount        = errors.New("this action is not available for bot accounts")
+	ErrInvalidAPIKeyName = errors.New("key name must be 1 to 100 characters")
+)
+
+const (
+	apiKeyPrefix         = "bk_"
+	defaultAPIKeyLimit   = 60
+	maxAPIKeyLimit       = 6000
+	apiKeyWindow         = time.Minute
+	apiKeyUsageRetention = time.Hour
+	apiKeyTouchInterval  = time.Minute
+	maxBotNameLength     = 100
+)
+
+// APIKeyInput holds the settings of a new API key
+type APIKeyInput struct {
+	Name      string
+	Scopes    []string
+	RateLimit int        // requests per minute, 0 for the default
+	ExpiresAt *time.Time // nil for a key that never expires
+}
+
+// BotAccountService manages the bot users integrations post as and the API keys
+// they authenticate with
+type BotAccountService struct {
+	repo     *repository.APIKeyRepository
+	userRepo *repository.UserRepository
+}
+
+func NewBotAccountService(repo *repository.APIKeyRepository, userRepo *repository.UserRepository) *BotAccountService {
+	return &BotAccountService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// ListBots returns every bot user. Admins only.
+func (s *BotAccountService) ListBots(actorId uint) ([]*model.User, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.userRepo.FindByType(model.UserTypeBot)
+}
+
+// CreateBot adds a bot user. Bots have no password and only sign in with API keys.
+// Admins only.
+func (s *BotAccountService) CreateBot(actorId uint, name string) (*model.User, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxBotNameLength {
+		return nil, ErrInvalidBotName
+	}
+	handle, err := util.GenerateRandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+
+	bot := &model.User{
+		Name:  name,
+		Email: fmt.Sprintf("bot-%s@bots.invalid", strings.ToLower(handle)),
+		Role:  model.UserRoleUser,
+		Type:  model.UserTypeBot,
+	}
+	if err := s.userRepo.Create(bot); err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+// ListKeys returns the bot's API keys, revoked ones included. Admins only.
+func (s *BotAccountService) ListKeys(actorId uint, botId int64) ([]*model.APIKey, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	bot, err := s.findBot(botId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByBot(bot.ID)
+}
+
+// CreateKey issues an API key for the bot. The returned secret is shown once;
+// only its hash is stored. Admins only.
+func (s *BotAccountService) CreateKey(actorId uint, botId int64, input APIKeyInput) (*model.APIKey, string, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, "", err
+	}
+	bot, err := s.findBot(botId)
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := validateAPIKey(input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	prefix, err := util.GenerateRandomToken(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	key.BotID = bot.ID
+	key.Prefix = apiKeyPrefix + prefix
+	key.Hash = hashAPIKeySecret(secret)
+	if err := s.repo.Create(key); err != nil {
+		return nil, "", err
+	}
+	return key, key.Prefix + "." + secret, nil
+}
+
+// RevokeKey disables one of the bot's API keys at once. Admins only.
+func (s *BotAccountService) RevokeKey(actorId uint, botId, keyId int64) (*model.APIKey, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	key, err := s.repo.FindById(keyId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	if int64(key.BotID) != botId {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	now := time.Now()
+	revoked, err := s.repo.Revoke(keyId, now)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrAPIKeyRevoked
+	}
+	key.RevokedAt = &now
+	return key, nil
+}
+
+// Authenticate checks a raw API key. It returns the key, or nil when the key is
+// malformed, unknown, revoked or expired.
+func (s *BotAccountService) Authenticate(raw string, now time.Time) (*model.APIKey, error) {
+	prefix, secret, found := strings.Cut(raw, ".")
+	if !found || !strings.HasPrefix(prefix, apiKeyPrefix) || secret == "" {
+		return nil, nil
+	}
+	key, err := s.repo.FindByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKeySecret(secret))) != 1 || !key.IsActive(now) {
+		return nil, nil
+	}
+	return key, nil
+}
+
+// CountRequest counts a request the key is allowed to make against its rate limit
+// and returns how many requests it made in the current minute
+func (s *BotAccountService) CountRequest(key *model.APIKey, now time.Time) (int64, error) {
+	count, err := s.repo.Hit(key.ID, now.Truncate(apiKeyWindow))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.repo.TouchLastUsed(key.ID, now, apiKeyTouchInterval); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// PurgeUsage deletes request counts of past rate limit windows. It runs
+// periodically as a background job.
+func (s *BotAccountService) PurgeUsage(ctx context.Context) error {
+	return s.repo.PurgeUsage(time.Now().Add(-apiKeyUsageRetention))
+}
+
+func (s *BotAccountService) findBot(botId int64) (*model.User, error) {
+	bot, err := s.userRepo.FindById(botId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBotNotFound
+		}
+		return nil, err
+	}
+	if !bot.IsBot() {
+		return nil, ErrBotNotFound
+	}
+	return bot, nil
+}
+
+func (s *BotAccountService) requireAdmin(userId uint) error {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// validateAPIKey checks the input and returns the key it describes
+func validateAPIKey(input APIKeyInput) (*model.APIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxBotNameLength {
+		return nil, ErrInvalidAPIKeyName
+	}
+	if len(input.Scopes) == 0 {
+		return nil, ErrInvalidScopes
+	}
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !model.IsValidScope(scope) {
+			return nil, ErrInvalidScopes
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	limit := input.RateLimit
+	if limit == 0 {
+		limit = defaultAPIKeyLimit
+	}
+	if limit < 1 || limit > maxAPIKeyLimit {
+		return nil, ErrInvalidRateLimit
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidKeyExpiry
+	}
+	return &model.APIKey{
+		Name:      name,
+		Scopes:    scopes,
+		RateLimit: limit,
+		ExpiresAt: input.ExpiresAt,
+	}, nil
+}
+
+// hashAPIKeySecret returns the hex SHA-256 of the secret. Keys are long random
+// strings, so a fast hash is enough and keeps authentication cheap.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,585 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBotFlowNotFound   = errors.New("bot flow not found")
+	ErrInvalidBotFlow    = errors.New("invalid bot flow")
+	ErrBotFlowEnabled    = errors.New("another bot flow is already enabled for this department")
+	ErrNotWithBot        = errors.New("this chat is not with the bot")
+	ErrInvalidBotPattern = errors.New("invalid regular expression in bot flow")
+)
+
+const (
+	maxBotFlowNameLength = 100
+	maxBotIntents        = 100
+	maxBotFields         = 10
+	maxBotReplyLength    = 2000
+	maxBotFieldLength    = 255
+	defaultBotFallbacks  = 2
+	maxBotFallbacks      = 10
+
+	defaultBotHandoffReply = "Let me connect you with an agent."
+	defaultBotFallback     = "Sorry, I didn't get that. Could you rephrase?"
+	defaultBotInvalidField = "That doesn't look right, please try again."
+)
+
+// BotFlowInput holds the editable fields of a bot flow
+type BotFlowInput struct {
+	Name         string
+	DepartmentID *uint
+	Enabled      bool
+	Definition   model.BotFlowDefinition
+}
+
+// BotService runs the auto-responder that greets visitors, answers common
+// questions and collects details before handing the chat to the agent queue
+type BotService struct {
+	repo          *repository.BotFlowRepository
+	conversations *ConversationService
+	visitorRepo   *repository.VisitorRepository
+	deptRepo      *repository.DepartmentRepository
+	routing       *RoutingService
+	sla           *SLAService
+}
+
+func NewBotService(repo *repository.BotFlowRepository, conversations *ConversationService, visitorRepo *repository.VisitorRepository, deptRepo *repository.DepartmentRepository, routing *RoutingService, sla *SLAService) *BotService {
+	return &BotService{
+		repo:          repo,
+		conversations: conversations,
+		visitorRepo:   visitorRepo,
+		deptRepo:      deptRepo,
+		routing:       routing,
+		sla:           sla,
+	}
+}
+
+// ListFlows returns every bot flow. Admins only.
+func (s *BotService) ListFlows(actorId uint) ([]*model.BotFlow, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAll()
+}
+
+func (s *BotService) GetFlow(actorId uint, flowId int64) (*model.BotFlow, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.findFlow(flowId)
+}
+
+// CreateFlow saves a flow for a department, or the default flow when no
+// department is given. Only one flow may be enabled per department. Admins only.
+func (s *BotService) CreateFlow(actorId uint, input BotFlowInput) (*model.BotFlow, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	flow := &model.BotFlow{}
+	if err := s.apply(flow, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(flow); err != nil {
+		return nil, err
+	}
+	return flow, nil
+}
+
+// UpdateFlow replaces the flow's settings and definition. Chats already with the
+// bot continue with the new definition from their next message. Admins only.
+func (s *BotService) UpdateFlow(actorId uint, flowId int64, input BotFlowInput) (*model.BotFlow, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	flow, err := s.findFlow(flowId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(flow, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(flow); err != nil {
+		return nil, err
+	}
+	return flow, nil
+}
+
+// DeleteFlow removes the flow. Chats still with it go to the queue with their
+// next message. Admins only.
+func (s *BotService) DeleteFlow(actorId uint, flowId int64) error {
+	if err := s.requireAdmin(actorId); err != nil {
+		return err
+	}
+	if _, err := s.findFlow(flowId); err != nil {
+		return err
+	}
+	return s.repo.Delete(flowId)
+}
+
+// FlowFor returns the enabled flow greeting new chats of the department, falling
+// back to the enabled default flow. It returns nil when neither exists.
+func (s *BotService) FlowFor(departmentId *uint) (*model.BotFlow, error) {
+	if departmentId != nil {
+		flow, err := s.repo.FindEnabled(departmentId)
+		if err == nil {
+			return flow, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	flow, err := s.repo.FindEnabled(nil)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return flow, err
+}
+
+// Start hands a new chat to the flow and greets the visitor
+func (s *BotService) Start(conversation *model.Conversation, flow *model.BotFlow) error {
+	session := &model.BotSession{
+		ConversationID: conversation.ID,
+		FlowID:         flow.ID,
+		Status:         model.BotSessionActive,
+		Data:           map[string]string{},
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return err
+	}
+	return s.reply(conversation, flow.Definition.Greeting)
+}
+
+// Respond answers a visitor message in a chat still with the bot: it collects the
+// field being asked for, replies to the matching intent, or hands the chat off
+// when the visitor asks for an agent or the bot keeps failing to understand.
+func (s *BotService) Respond(conversation *model.Conversation, text string) error {
+	session, err := s.repo.FindSession(int64(conversation.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.Handoff(conversation)
+	}
+	if err != nil {
+		return err
+	}
+	if session.Status != model.BotSessionActive {
+		return nil
+	}
+	flow, err := s.repo.FindById(int64(session.FlowID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.handoff(conversation, session, defaultBotHandoffReply)
+	}
+	if err != nil {
+		return err
+	}
+	definition := &flow.Definition
+
+	if definition.WantsHandoff(text) {
+		return s.handoff(conversation, session, handoffReply(definition))
+	}
+	if intent := definition.Intent(session.Intent); intent != nil && session.FieldIndex < len(intent.Collect) {
+		return s.collect(conversation, session, definition, intent, text)
+	}
+
+	intent := definition.Match(text)
+	if intent == nil {
+		session.Fallbacks++
+		if session.Fallbacks > maxFallbacks(definition) {
+			return s.handoff(conversation, session, handoffReply(definition))
+		}
+		return s.advance(conversation, session, fallbackReply(definition))
+	}
+
+	session.Fallbacks = 0
+	session.Intent = ""
+	session.FieldIndex = 0
+	if len(intent.Collect) > 0 {
+		session.Intent = intent.Name
+		return s.advance(conversation, session, intent.Reply, intent.Collect[0].Prompt)
+	}
+	if intent.Handoff {
+		return s.handoff(conversation, session, intent.Reply, handoffReply(definition))
+	}
+	return s.advance(conversation, session, intent.Reply)
+}
+
+// Handoff puts a chat still with the bot in the agent queue, for the visitor who
+// asks for an agent
+func (s *BotService) Handoff(conversation *model.Conversation) error {
+	session, err := s.repo.FindSession(int64(conversation.ID))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if session != nil && session.Status != model.BotSessionActive {
+		session = nil
+	}
+	reply := defaultBotHandoffReply
+	if session != nil {
+		if flow, err := s.repo.FindById(int64(session.FlowID)); err == nil {
+			reply = handoffReply(&flow.Definition)
+		}
+	}
+	return s.handoff(conversation, session, reply)
+}
+
+// collect stores the visitor's answer to the field being asked for and asks for
+// the next one, or finishes the intent once every field is in
+func (s *BotService) collect(conversation *model.Conversation, session *model.BotSession, definition *model.BotFlowDefinition, intent *model.BotIntent, text string) error {
+	field := intent.Collect[session.FieldIndex]
+	value, ok := acceptField(field, text)
+	if !ok {
+		session.Fallbacks++
+		if session.Fallbacks > maxFallbacks(definition) {
+			return s.handoff(conversation, session, handoffReply(definition))
+		}
+		invalid := field.Invalid
+		if invalid == "" {
+			invalid = defaultBotInvalidField
+		}
+		return s.advance(conversation, session, invalid)
+	}
+
+	if session.Data == nil {
+		session.Data = map[string]string{}
+	}
+	session.Data[field.Name] = value
+	session.Fallbacks = 0
+	session.FieldIndex++
+	if session.FieldIndex < len(intent.Collect) {
+		return s.advance(conversation, session, intent.Collect[session.FieldIndex].Prompt)
+	}
+
+	session.Intent = ""
+	session.FieldIndex = 0
+	if err := s.saveVisitorDetails(conversation, session.Data); err != nil {
+		return err
+	}
+	if intent.Handoff {
+		return s.handoff(conversation, session, intent.Done, handoffReply(definition))
+	}
+	return s.advance(conversation, session, intent.Done)
+}
+
+// advance saves the session and sends the replies, unless another message moved
+// the session on in the meantime
+func (s *BotService) advance(conversation *model.Conversation, session *model.BotSession, replies ...string) error {
+	advanced, err := s.repo.AdvanceSession(session)
+	if err != nil || !advanced {
+		return err
+	}
+	for _, reply := range replies {
+		if err := s.reply(conversation, reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handoff ends the bot session, sends the replies, queues the chat for an agent
+// with a note of what the bot collected and starts its SLA timers
+func (s *BotService) handoff(conversation *model.Conversation, session *model.BotSession, replies ...string) error {
+	if session != nil {
+		session.Status = model.BotSessionHandedOff
+		advanced, err := s.repo.AdvanceSession(session)
+		if err != nil || !advanced {
+			return err
+		}
+	}
+	conversationId := int64(conversation.ID)
+	now := time.Now()
+	queued, err := s.conversations.repo.Enqueue(conversationId, now)
+	if err != nil {
+		return err
+	}
+	if !queued {
+		if session == nil {
+			return ErrNotWithBot
+		}
+		return nil
+	}
+	conversation.Status = model.SupportStatusQueued
+	conversation.QueuedAt = &now
+	conversation.RoutedAt = &now
+
+	for _, reply := range replies {
+		if err := s.reply(conversation, reply); err != nil {
+			return err
+		}
+	}
+	if session != nil && len(session.Data) > 0 {
+		if _, err := s.conversations.postInternalMessage(conversationId, 0, model.MessageTypeBot, collectedSummary(session.Data)); err != nil {
+			return err
+		}
+	}
+	if err := s.sla.ChatOpened(conversation); err != nil {
+		return err
+	}
+	return s.routing.RouteQueue(context.Background())
+}
+
+func (s *BotService) reply(conversation *model.Conversation, content string) error {
+	if content = strings.TrimSpace(content); content == "" {
+		return nil
+	}
+	_, err := s.conversations.postAutomatedMessage(int64(conversation.ID), model.MessageTypeBot, content)
+	return err
+}
+
+// saveVisitorDetails copies a collected name or email to the visitor's profile
+func (s *BotService) saveVisitorDetails(conversation *model.Conversation, data map[string]string) error {
+	if conversation.VisitorID == nil {
+		return nil
+	}
+	fields := map[string]interface{}{}
+	if name, ok := data["name"]; ok {
+		fields["name"] = name
+	}
+	if email, ok := data["email"]; ok {
+		fields["email"] = email
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := s.visitorRepo.Update(*conversation.VisitorID, fields); err != nil {
+		return err
+	}
+	if name, ok := data["name"]; ok {
+		return s.conversations.repo.Update(int64(conversation.ID), map[string]interface{}{"name": name})
+	}
+	return nil
+}
+
+// apply validates the input and copies it to the flow
+func (s *BotService) apply(flow *model.BotFlow, input BotFlowInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxBotFlowNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidBotFlow, maxBotFlowNameLength)
+	}
+	if input.DepartmentID != nil {
+		if _, err := s.deptRepo.FindById(int64(*input.DepartmentID)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrDepartmentNotFound
+			}
+			return err
+		}
+	}
+	definition, err := normalizeBotDefinition(input.Definition)
+	if err != nil {
+		return err
+	}
+	if input.Enabled {
+		taken, err := s.repo.EnabledTaken(input.DepartmentID, int64(flow.ID))
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrBotFlowEnabled
+		}
+	}
+
+	flow.Name = name
+	flow.DepartmentID = input.DepartmentID
+	flow.Enabled = input.Enabled
+	flow.Definition = definition
+	return nil
+}
+
+func (s *BotService) findFlow(flowId int64) (*model.BotFlow, error) {
+	flow, err := s.repo.FindById(flowId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBotFlowNotFound
+		}
+		return nil, err
+	}
+	return flow, nil
+}
+
+func (s *BotService) requireAdmin(userId uint) error {
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// normalizeBotDefinition trims the definition and checks every intent can match,
+// has something to do and that all its patterns compile
+func normalizeBotDefinition(definition model.BotFlowDefinition) (model.BotFlowDefinition, error) {
+	if len(definition.Intents) > maxBotIntents {
+		return definition, fmt.Errorf("%w: at most %d intents", ErrInvalidBotFlow, maxBotIntents)
+	}
+	if definition.MaxFallbacks == 0 {
+		definition.MaxFallbacks = defaultBotFallbacks
+	}
+	if definition.MaxFallbacks < 0 || definition.MaxFallbacks > maxBotFallbacks {
+		return definition, fmt.Errorf("%w: max_fallbacks must be 1 to %d", ErrInvalidBotFlow, maxBotFallbacks)
+	}
+	definition.Greeting = strings.TrimSpace(definition.Greeting)
+	definition.HandoffReply = strings.TrimSpace(definition.HandoffReply)
+	definition.FallbackReply = strings.TrimSpace(definition.FallbackReply)
+	definition.HandoffKeywords = trimKeywords(definition.HandoffKeywords)
+	if !validReplies(definition.Greeting, definition.HandoffReply, definition.FallbackReply) {
+		return definition, fmt.Errorf("%w: replies must be at most %d characters", ErrInvalidBotFlow, maxBotReplyLength)
+	}
+
+	names := map[string]bool{}
+	intents := make([]model.BotIntent, 0, len(definition.Intents))
+	for _, intent := range definition.Intents {
+		intent.Name = strings.TrimSpace(intent.Name)
+		intent.Keywords = trimKeywords(intent.Keywords)
+		intent.Pattern = strings.TrimSpace(intent.Pattern)
+		intent.Reply = strings.TrimSpace(intent.Reply)
+		intent.Done = strings.TrimSpace(intent.Done)
+		if intent.Name == "" || len(intent.Name) > maxBotFlowNameLength || names[intent.Name] {
+			return definition, fmt.Errorf("%w: intents need unique names of at most %d characters", ErrInvalidBotFlow, maxBotFlowNameLength)
+		}
+		names[intent.Name] = true
+		if len(intent.Keywords) == 0 && intent.Pattern == "" {
+			return definition, fmt.Errorf("%w: intent %q needs keywords or a pattern", ErrInvalidBotFlow, intent.Name)
+		}
+		if intent.Pattern != "" {
+			if _, err := regexp.Compile("(?i)" + intent.Pattern); err != nil {
+				return definition, fmt.Errorf("%w: intent %q", ErrInvalidBotPattern, intent.Name)
+			}
+		}
+		if intent.Reply == "" && len(intent.Collect) == 0 && !intent.Handoff {
+			return definition, fmt.Errorf("%w: intent %q needs a reply, fields to collect or a handoff", ErrInvalidBotFlow, intent.Name)
+		}
+		if !validReplies(intent.Reply, intent.Done) {
+			return definition, fmt.Errorf("%w: replies must be at most %d characters", ErrInvalidBotFlow, maxBotReplyLength)
+		}
+		fields, err := normalizeBotFields(intent)
+		if err != nil {
+			return definition, err
+		}
+		intent.Collect = fields
+		intents = append(intents, intent)
+	}
+	definition.Intents = intents
+	return definition, nil
+}
+
+func normalizeBotFields(intent model.BotIntent) ([]model.BotField, error) {
+	if len(intent.Collect) > maxBotFields {
+		return nil, fmt.Errorf("%w: intent %q collects more than %d fields", ErrInvalidBotFlow, intent.Name, maxBotFields)
+	}
+	names := map[string]bool{}
+	fields := make([]model.BotField, 0, len(intent.Collect))
+	for _, field := range intent.Collect {
+		field.Name = strings.TrimSpace(field.Name)
+		field.Prompt = strings.TrimSpace(field.Prompt)
+		field.Pattern = strings.TrimSpace(field.Pattern)
+		field.Invalid = strings.TrimSpace(field.Invalid)
+		if field.Type == "" {
+			field.Type = model.BotFieldText
+		}
+		if field.Name == "" || len(field.Name) > maxBotFlowNameLength || names[field.Name] || field.Prompt == "" {
+			return nil, fmt.Errorf("%w: fields of intent %q need unique names and a prompt", ErrInvalidBotFlow, intent.Name)
+		}
+		names[field.Name] = true
+		if field.Type != model.BotFieldText && field.Type != model.BotFieldEmail {
+			return nil, fmt.Errorf("%w: field %q must be of type text or email", ErrInvalidBotFlow, field.Name)
+		}
+		if field.Pattern != "" {
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return nil, fmt.Errorf("%w: field %q", ErrInvalidBotPattern, field.Name)
+			}
+		}
+		if !validReplies(field.Prompt, field.Invalid) {
+			return nil, fmt.Errorf("%w: replies must be at most %d characters", ErrInvalidBotFlow, maxBotReplyLength)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// acceptField returns the trimmed answer when it is valid for the field
+func acceptField(field model.BotField, text string) (string, bool) {
+	value := strings.TrimSpace(text)
+	if value == "" || len(value) > maxBotFieldLength {
+		return "", false
+	}
+	if field.Type == model.BotFieldEmail {
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return "", false
+		}
+	}
+	if field.Pattern != "" {
+		pattern, err := regexp.Compile("^(?:" + field.Pattern + ")$")
+		if err != nil || !pattern.MatchString(value) {
+			return "", false
+		}
+	}
+	return value, true
+}
+
+// collectedSummary formats the collected fields for the agent picking up the chat
+func collectedSummary(data map[string]string) string {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{"Collected by the bot:"}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s: %s", name, data[name]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func maxFallbacks(definition *model.BotFlowDefinition) int {
+	if definition.MaxFallbacks <= 0 {
+		return defaultBotFallbacks
+	}
+	return definition.MaxFallbacks
+}
+
+func handoffReply(definition *model.BotFlowDefinition) string {
+	if definition.HandoffReply == "" {
+		return defaultBotHandoffReply
+	}
+	return definition.HandoffReply
+}
+
+func fallbackReply(definition *model.BotFlowDefinition) string {
+	if definition.FallbackReply == "" {
+		return defaultBotFallback
+	}
+	return definition.FallbackReply
+}
+
+func trimKeywords(keywords []string) []string {
+	trimmed := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			trimmed = append(trimmed, keyword)
+		}
+	}
+	return trimmed
+}
+
+func validReplies(replies ...string) bool {
+	for _, reply := range replies {
+		if len(reply) > maxBotReplyLength {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCannedResponseNotFound = errors.New("canned response not found")
+	ErrInvalidShortcut        = errors.New("shortcut must be 1 to 50 lowercase letters, digits, dashes or underscores")
+	ErrShortcutTaken          = errors.New("a canned response with this shortcut already exists")
+	ErrEmptyCannedResponse    = errors.New("canned response content cannot be empty")
+)
+
+const (
+	maxShortcutLength        = 50
+	maxCannedResponseResults = 50
+	shortcutChars            = "abcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
+// CannedResponseInput holds the editable fields of a canned response
+type CannedResponseInput struct {
+	Shortcut string
+	Title    string
+	Content  string
+	Shared   bool // only admins create shared responses; ignored on update
+}
+
+// CannedResponseService manages the saved replies agents send with a shortcut
+type CannedResponseService struct {
+	repo          *repository.CannedResponseRepository
+	conversations *ConversationService
+	visitorRepo   *repository.VisitorRepository
+	deptRepo      *repository.DepartmentRepository
+}
+
+func NewCannedResponseService(repo *repository.CannedResponseRepository, conversations *ConversationService, visitorRepo *repository.VisitorRepository, deptRepo *repository.DepartmentRepository) *CannedResponseService {
+	return &CannedResponseService{
+		repo:          repo,
+		conversations: conversations,
+		visitorRepo:   visitorRepo,
+		deptRepo:      deptRepo,
+	}
+}
+
+// List returns the caller's personal responses and the shared ones, optionally only
+// those whose shortcut starts with query. A leading slash in query is ignored.
+func (s *CannedResponseService) List(actorId uint, query string) ([]*model.CannedResponse, error) {
+	if _, err := s.findStaff(actorId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindVisible(actorId, normalizeShortcut(query), maxCannedResponseResults)
+}
+
+func (s *CannedResponseService) Get(actorId uint, id int64) (*model.CannedResponse, error) {
+	if _, err := s.findStaff(actorId); err != nil {
+		return nil, err
+	}
+	return s.findVisible(actorId, id)
+}
+
+// Create saves a personal response, or a shared one when an admin asks for it
+func (s *CannedResponseService) Create(actorId uint, input CannedResponseInput) (*model.CannedResponse, error) {
+	actor, err := s.findStaff(actorId)
+	if err != nil {
+		return nil, err
+	}
+	shortcut, title, content, err := validateCannedResponse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var ownerId *uint
+	if input.Shared {
+		if !actor.IsAdmin() {
+			return nil, ErrForbidden
+		}
+	} else {
+		ownerId = &actorId
+	}
+	if err := s.ensureShortcutFree(ownerId, shortcut, 0); err != nil {
+		return nil, err
+	}
+
+	response := &model.CannedResponse{
+		OwnerID:     ownerId,
+		Shortcut:    shortcut,
+		Title:       title,
+		Content:     content,
+		CreatedByID: actorId,
+	}
+	if err := s.repo.Create(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Update edits a response. Agents edit their own; shared responses need an admin.
+func (s *CannedResponseService) Update(actorId uint, id int64, input CannedResponseInput) (*model.CannedResponse, error) {
+	response, err := s.findEditable(actorId, id)
+	if err != nil {
+		return nil, err
+	}
+	shortcut, title, content, err := validateCannedResponse(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureShortcutFree(response.OwnerID, shortcut, id); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Update(id, map[string]interface{}{
+		"shortcut": shortcut,
+		"title":    title,
+		"content":  content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.findResponse(id)
+}
+
+func (s *CannedResponseService) Delete(actorId uint, id int64) error {
+	if _, err := s.findEditable(actorId, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// Preview renders a response for a conversation without sending it
+func (s *CannedResponseService) Preview(actorId uint, id int64, conversationId int64) (string, error) {
+	actor, err := s.findStaff(actorId)
+	if err != nil {
+		return "", err
+	}
+	response, err := s.findVisible(actorId, id)
+	if err != nil {
+		return "", err
+	}
+	conversation, _, err := s.conversations.authorize(actorId, conversationId)
+	if err != nil {
+		return "", err
+	}
+	return s.render(response, actor, conversation)
+}
+
+// Send renders a response, picked by ID or by shortcut, for the conversation and
+// posts it as a message from the agent
+func (s *CannedResponseService) Send(actorId uint, conversationId int64, id int64, shortcut string) (*model.Message, error) {
+	actor, err := s.findStaff(actorId)
+	if err != nil {
+		return nil, err
+	}
+	var response *model.CannedResponse
+	if id > 0 {
+		response, err = s.findVisible(actorId, id)
+	} else {
+		response, err = s.repo.FindByShortcut(actorId, normalizeShortcut(shortcut))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = ErrCannedResponseNotFound
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conversation, _, err := s.conversations.authorize(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.render(response, actor, conversation)
+	if err != nil {
+		return nil, err
+	}
+	return s.conversations.SendMessage(actorId, conversationId, content)
+}
+
+// render fills the placeholders of the response for the conversation: the
+// visitor's name and email, the agent's name and the department of a support chat
+func (s *CannedResponseService) render(response *model.CannedResponse, agent *model.User, conversation *model.Conversation) (string, error) {
+	values := map[string]string{
+		"agent.name":      agent.Name,
+		"visitor.name":    "",
+		"visitor.email":   "",
+		"department.name": "",
+	}
+	if conversation.VisitorID != nil {
+		visitor, err := s.visitorRepo.FindById(*conversation.VisitorID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		if visitor != nil {
+			values["visitor.name"] = visitor.DisplayName()
+			values["visitor.email"] = visitor.Email
+		}
+	}
+	if conversation.DepartmentID != nil {
+		department, err := s.deptRepo.FindById(int64(*conversation.DepartmentID))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		if department != nil {
+			values["department.name"] = department.Name
+		}
+	}
+	return response.Render(values), nil
+}
+
+func (s *CannedResponseService) ensureShortcutFree(ownerId *uint, shortcut string, exceptId int64) error {
+	taken, err := s.repo.ShortcutTaken(ownerId, shortcut, exceptId)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrShortcutTaken
+	}
+	return nil
+}
+
+// findVisible loads a response the user may use: their own or a shared one
+func (s *CannedResponseService) findVisible(userId uint, id int64) (*model.CannedResponse, error) {
+	response, err := s.findResponse(id)
+	if err != nil {
+		return nil, err
+	}
+	if !response.IsShared() && *response.OwnerID != userId {
+		return nil, ErrCannedResponseNotFound
+	}
+	return response, nil
+}
+
+// findEditable loads a response the user may change: their own, or a shared one for admins
+func (s *CannedResponseService) findEditable(userId uint, id int64) (*model.CannedResponse, error) {
+	actor, err := s.findStaff(userId)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.findVisible(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if response.IsShared() && !actor.IsAdmin() {
+		return nil, ErrForbidden
+	}
+	return response, nil
+}
+
+func (s *CannedResponseService) findResponse(id int64) (*model.CannedResponse, error) {
+	response, err := s.repo.FindById(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCannedResponseNotFound
+		}
+		return nil, err
+	}
+	return response, nil
+}
+
+// findStaff loads the user and checks they are an agent or admin
+func (s *CannedResponseService) findStaff(userId uint) (*model.User, error) {
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsStaff() {
+		return nil, ErrForbidden
+	}
+	return user, nil
+}
+
+func validateCannedResponse(input CannedResponseInput) (string, string, string, error) {
+	shortcut := normalizeShortcut(input.Shortcut)
+	if shortcut == "" || len(shortcut) > maxShortcutLength || strings.Trim(shortcut, shortcutChars) != "" {
+		return "", "", "", ErrInvalidShortcut
+	}
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		return "", "", "", ErrEmptyCannedResponse
+	}
+	return shortcut, strings.TrimSpace(input.Title), content, nil
+}
+
+// normalizeShortcut lowercases a shortcut and drops the slash agents type before it
+func normalizeShortcut(shortcut string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(shortcut), "/"))
+}
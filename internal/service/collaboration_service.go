@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChatNotActive           = errors.New("this chat is not assigned to an agent")
+	ErrAgentUnavailable        = errors.New("agent is not online or has no free chat slots")
+	ErrInvalidTransfer         = errors.New("transfer needs either an agent or a department")
+	ErrInvalidCollaboratorMode = errors.New("mode must be observe or join")
+)
+
+// Collaborator modes
+const (
+	CollaboratorObserve = "observe"
+	CollaboratorJoin    = "join"
+)
+
+// TransferRequest names where a live chat goes. Exactly one target must be set.
+type TransferRequest struct {
+	AgentID      *uint
+	DepartmentID *uint
+	Note         string // internal, only agents see it
+}
+
+// CollaborationService lets agents hand over live support chats, bring colleagues
+// in and talk to each other without the visitor seeing it
+type CollaborationService struct {
+	conversations *ConversationService
+	agentRepo     *repository.AgentRepository
+	deptRepo      *repository.DepartmentRepository
+	routing       *RoutingService
+	hub           *realtime.Hub
+}
+
+func NewCollaborationService(conversations *ConversationService, agentRepo *repository.AgentRepository, deptRepo *repository.DepartmentRepository, routing *RoutingService, hub *realtime.Hub) *CollaborationService {
+	return &CollaborationService{
+		conversations: conversations,
+		agentRepo:     agentRepo,
+		deptRepo:      deptRepo,
+		routing:       routing,
+		hub:           hub,
+	}
+}
+
+// Transfer hands a live chat to another agent or department. Only the assigned
+// agent or an admin may transfer. The transfer is recorded in the timeline and
+// the note is kept as a whisper.
+func (s *CollaborationService) Transfer(actorId uint, conversationId int64, request TransferRequest) (*model.Conversation, error) {
+	if (request.AgentID == nil) == (request.DepartmentID == nil) {
+		return nil, ErrInvalidTransfer
+	}
+	conversation, actor, _, err := s.authorizeStaff(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.IsClosed() {
+		return nil, ErrConversationClosed
+	}
+	if conversation.Status != model.SupportStatusActive || conversation.AssignedAgentID == nil {
+		return nil, ErrChatNotActive
+	}
+	fromAgentId := *conversation.AssignedAgentID
+	if fromAgentId != actorId && !actor.IsAdmin() {
+		return nil, ErrForbidden
+	}
+
+	var text string
+	eventData := map[string]interface{}{"conversation_id": conversation.ID, "from_agent_id": fromAgentId}
+	notified := []uint{fromAgentId}
+	if request.AgentID != nil {
+		target, err := s.transferToAgent(conversation, fromAgentId, *request.AgentID)
+		if err != nil {
+			return nil, err
+		}
+		text = fmt.Sprintf("%s transferred the chat to %s", actor.Name, target.Name)
+		eventData["to_agent_id"] = target.ID
+		notified = append(notified, target.ID)
+	} else {
+		department, err := s.transferToDepartment(conversation, fromAgentId, *request.DepartmentID)
+		if err != nil {
+			return nil, err
+		}
+		text = fmt.Sprintf("%s transferred the chat to %s", actor.Name, department.Name)
+		eventData["to_department_id"] = department.ID
+	}
+
+	if err := s.conversations.postSystemMessage(conversationId, text); err != nil {
+		return nil, err
+	}
+	if note := strings.TrimSpace(request.Note); note != "" {
+		if _, err := s.conversations.postInternalMessage(conversationId, actorId, model.MessageTypeText, note); err != nil {
+			return nil, err
+		}
+		eventData["note"] = note
+	}
+	s.hub.PublishToUsers(notified, realtime.Event{Type: realtime.EventChatTransferred, Data: eventData})
+	s.conversations.relayToVisitor(conversation, realtime.Event{Type: realtime.EventChatTransferred, Data: conversation})
+
+	if request.DepartmentID != nil {
+		if err := s.routing.RouteQueue(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return s.conversations.repo.FindById(conversationId)
+}
+
+// InviteCollaborator brings another agent into a live chat, either silently as an
+// observer or as a participant the visitor can see
+func (s *CollaborationService) InviteCollaborator(actorId uint, conversationId int64, userId uint, mode string) (*model.ConversationMember, error) {
+	role := model.MemberRoleObserver
+	if mode == CollaboratorJoin {
+		role = model.MemberRoleMember
+	} else if mode != CollaboratorObserve {
+		return nil, ErrInvalidCollaboratorMode
+	}
+	if actorId == userId {
+		return nil, ErrCannotTargetSelf
+	}
+	conversation, actor, _, err := s.authorizeStaff(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.IsClosed() {
+		return nil, ErrConversationClosed
+	}
+	target, err := s.conversations.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !target.IsStaff() {
+		return nil, ErrNotAgent
+	}
+	if _, err := s.conversations.repo.FindMember(conversationId, userId); err == nil {
+		return nil, ErrAlreadyMember
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	member := &model.ConversationMember{
+		ConversationID: conversation.ID,
+		UserID:         userId,
+		Role:           role,
+		JoinedAt:       time.Now(),
+	}
+	if err := s.conversations.repo.AddMembers([]*model.ConversationMember{member}); err != nil {
+		return nil, err
+	}
+	s.conversations.broadcastToStaff(conversationId, realtime.Event{Type: realtime.EventMemberAdded, Data: []*model.ConversationMember{member}})
+
+	if role == model.MemberRoleObserver {
+		text := fmt.Sprintf("%s invited %s to observe", actor.Name, target.Name)
+		if _, err := s.conversations.postInternalMessage(conversationId, 0, model.MessageTypeSystem, text); err != nil {
+			return nil, err
+		}
+	} else if err := s.conversations.postSystemMessage(conversationId, fmt.Sprintf("%s joined the chat", target.Name)); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveCollaborator takes an observer or participating colleague out of the chat.
+// Collaborators may leave on their own; otherwise the assigned agent or an admin
+// removes them. The assigned agent can only leave through a transfer.
+func (s *CollaborationService) RemoveCollaborator(actorId uint, conversationId int64, userId uint) error {
+	conversation, actor, _, err := s.authorizeStaff(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	isAssigned := conversation.AssignedAgentID != nil && *conversation.AssignedAgentID == userId
+	if isAssigned {
+		return ErrForbidden
+	}
+	if actorId != userId && !actor.IsAdmin() && (conversation.AssignedAgentID == nil || *conversation.AssignedAgentID != actorId) {
+		return ErrForbidden
+	}
+	target, err := s.conversations.findMember(conversationId, userId)
+	if err != nil {
+		return err
+	}
+	targetUser, err := s.conversations.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !targetUser.IsStaff() {
+		// The linked account of the visitor is not a collaborator
+		return ErrForbidden
+	}
+
+	s.conversations.broadcastToStaff(conversationId, realtime.Event{Type: realtime.EventMemberRemoved, Data: target})
+	if err := s.conversations.repo.RemoveMember(conversationId, userId); err != nil {
+		return err
+	}
+	if target.Role == model.MemberRoleObserver {
+		text := fmt.Sprintf("%s stopped observing", targetUser.Name)
+		_, err := s.conversations.postInternalMessage(conversationId, 0, model.MessageTypeSystem, text)
+		return err
+	}
+	return s.conversations.postSystemMessage(conversationId, fmt.Sprintf("%s left the chat", targetUser.Name))
+}
+
+// Whisper posts a note only the agents in the conversation can see
+func (s *CollaborationService) Whisper(actorId uint, conversationId int64, content string) (*model.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	conversation, _, member, err := s.authorizeStaff(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrNotMember
+	}
+	if conversation.IsClosed() {
+		return nil, ErrConversationClosed
+	}
+	return s.conversations.postInternalMessage(conversationId, actorId, model.MessageTypeText, content)
+}
+
+// transferToAgent reserves a chat slot of the target agent and moves the chat to them
+func (s *CollaborationService) transferToAgent(conversation *model.Conversation, fromAgentId, toAgentId uint) (*model.User, error) {
+	if toAgentId == fromAgentId {
+		return nil, ErrCannotTargetSelf
+	}
+	target, err := s.conversations.findUser(toAgentId)
+	if err != nil {
+		return nil, err
+	}
+	if !target.IsAgent() {
+		return nil, ErrNotAgent
+	}
+
+	conversationId := int64(conversation.ID)
+	now := time.Now()
+	claimed, err := s.agentRepo.Claim(toAgentId, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrAgentUnavailable
+	}
+	moved, err := s.conversations.repo.Reassign(conversationId, fromAgentId, toAgentId, now)
+	if err != nil || !moved {
+		if releaseErr := s.agentRepo.Release(toAgentId); releaseErr != nil {
+			return nil, releaseErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrChatNotActive
+	}
+	if err := s.handOver(conversationId, fromAgentId); err != nil {
+		return nil, err
+	}
+
+	// The new agent may already be observing; make them a full participant
+	if existing, err := s.conversations.repo.FindMember(conversationId, toAgentId); err == nil {
+		if existing.Role != model.MemberRoleMember {
+			if err := s.conversations.repo.UpdateMemberRole(conversationId, toAgentId, model.MemberRoleMember); err != nil {
+				return nil, err
+			}
+		}
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		member := &model.ConversationMember{ConversationID: conversation.ID, UserID: toAgentId, Role: model.MemberRoleMember, JoinedAt: now}
+		if err := s.conversations.repo.AddMembers([]*model.ConversationMember{member}); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	conversation.AssignedAgentID = &toAgentId
+	conversation.AssignedAt = &now
+	return target, nil
+}
+
+// transferToDepartment puts the chat back in the queue of the given department
+func (s *CollaborationService) transferToDepartment(conversation *model.Conversation, fromAgentId, departmentId uint) (*model.Department, error) {
+	department, err := s.deptRepo.FindById(int64(departmentId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+
+	conversationId := int64(conversation.ID)
+	now := time.Now()
+	moved, err := s.conversations.repo.TransferToDepartment(conversationId, fromAgentId, department.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !moved {
+		return nil, ErrChatNotActive
+	}
+	if err := s.handOver(conversationId, fromAgentId); err != nil {
+		return nil, err
+	}
+
+	conversation.Status = model.SupportStatusQueued
+	conversation.DepartmentID = &department.ID
+	conversation.RoutedAt = &now
+	conversation.AssignedAgentID = nil
+	conversation.AssignedAt = nil
+	return department, nil
+}
+
+// handOver frees the previous agent's chat slot and takes them out of the conversation
+func (s *CollaborationService) handOver(conversationId int64, fromAgentId uint) error {
+	if err := s.agentRepo.Release(fromAgentId); err != nil {
+		return err
+	}
+	return s.conversations.repo.RemoveMember(conversationId, fromAgentId)
+}
+
+// authorizeStaff loads a support conversation for an agent taking part in it or
+// for any admin. The returned membership is nil for admins who are not members.
+func (s *CollaborationService) authorizeStaff(actorId uint, conversationId int64) (*model.Conversation, *model.User, *model.ConversationMember, error) {
+	conversation, err := s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, ErrConversationNotFound
+		}
+		return nil, nil, nil, err
+	}
+	if !conversation.IsSupport() {
+		return nil, nil, nil, ErrNotSupportChat
+	}
+	actor, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	member, err := s.conversations.repo.FindMember(conversationId, actorId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil, err
+	}
+	if member == nil && !actor.IsAdmin() {
+		return nil, nil, nil, ErrNotMember
+	}
+	if !actor.IsStaff() {
+		return nil, nil, nil, ErrForbidden
+	}
+	return conversation, actor, member, nil
+}
@@ -0,0 +1,605 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownCommand       = errors.New("unknown command; send /help for the list of commands")
+	ErrSlashCommandNotFound = errors.New("slash command not found")
+	ErrInvalidCommandName   = errors.New("command name must be 1 to 32 lowercase letters, digits, dashes or underscores")
+	ErrCommandNameTaken     = errors.New("a command with this name already exists")
+	ErrInvalidCommandURL    = errors.New("command URL must be an absolute http or https URL of at most 500 characters")
+	ErrCommandFailed        = errors.New("the command's endpoint did not answer successfully")
+)
+
+const (
+	commandResponseLimit  = 64 << 10
+	maxCommandURLLength   = 500
+	maxCommandDescription = 255
+	maxCommandUsageHint   = 100
+	giphySearchURL        = "https://giphy.com/search/"
+	shrug                 = `¯\_(ツ)_/¯`
+)
+
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// SlashCommandInput holds the editable fields of an external slash command
+type SlashCommandInput struct {
+	Name        string
+	Description string
+	UsageHint   string
+	URL         string
+	Enabled     bool
+}
+
+// CommandInfo describes a command users can run, for autocompletion and /help
+type CommandInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	UsageHint   string `json:"usage_hint,omitempty"`
+	Builtin     bool   `json:"builtin"`
+}
+
+// EphemeralMessage is a command reply only the user who ran the command sees.
+// It is not stored.
+type EphemeralMessage struct {
+	ConversationID uint                      `json:"conversation_id"`
+	SenderName     string                    `json:"sender_name"`
+	Content        string                    `json:"content"`
+	Attachments    []model.MessageAttachment `json:"attachments,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+}
+
+// CommandResult is the outcome of a slash command: a private reply, a message
+// posted to the conversation, or neither when the command's effect speaks for itself
+type CommandResult struct {
+	Command   string            `json:"command"`
+	Ephemeral *EphemeralMessage `json:"ephemeral,omitempty"`
+	Message   *model.Message    `json:"message,omitempty"`
+}
+
+// CommandRequest is the JSON body POSTed to external command endpoints
+type CommandRequest struct {
+	Command        string `json:"command"`
+	Text           string `json:"text"`
+	UserID         uint   `json:"user_id"`
+	UserName       string `json:"user_name"`
+	ConversationID uint   `json:"conversation_id"`
+}
+
+// commandResponse is the JSON an external endpoint may answer with. The reply
+// is ephemeral unless response_type is in_channel.
+type commandResponse struct {
+	Text         string                    `json:"text"`
+	ResponseType string                    `json:"response_type"`
+	Attachments  []model.MessageAttachment `json:"attachments"`
+}
+
+// commandCall is one run of a command
+type commandCall struct {
+	name         string
+	args         string
+	user         *model.User
+	conversation *model.Conversation
+}
+
+type builtinCommand struct {
+	description string
+	usage       string
+	run         func(call *commandCall) (*CommandResult, error)
+}
+
+// CommandService runs the slash commands users type in the message box. Built-in
+// commands are handled here; admins register others that are forwarded to an
+// external endpoint with X-Command-Signature set to "sha256=" and the hex
+// HMAC-SHA256 of the X-Command-Timestamp header, a dot and the body.
+type CommandService struct {
+	repo          *repository.SlashCommandRepository
+	conversations *ConversationService
+	userRepo      *repository.UserRepository
+	hub           *realtime.Hub
+	client        *http.Client
+	builtins      map[string]builtinCommand
+}
+
+func NewCommandService(repo *repository.SlashCommandRepository, conversations *ConversationService, userRepo *repository.UserRepository, client *http.Client, hub *realtime.Hub) *CommandService {
+	s := &CommandService{
+		repo:          repo,
+		conversations: conversations,
+		userRepo:      userRepo,
+		hub:           hub,
+		client:        client,
+	}
+	s.builtins = map[string]builtinCommand{
+		"help":   {description: "List the commands you can use", run: s.help},
+		"mute":   {description: "Mute notifications for this conversation", usage: "[duration, e.g. 30m, 8h or 2d]", run: s.mute},
+		"unmute": {description: "Turn notifications for this conversation back on", run: s.unmute},
+		"invite": {description: "Add people to this group", usage: "email [email...]", run: s.invite},
+		"topic":  {description: "Show or set the topic of this group", usage: "[topic, or - to clear it]", run: s.topic},
+		"shrug":  {description: "Append " + shrug + " to your message", usage: "[message]", run: s.shrug},
+		"giphy":  {description: "Post a link to GIFs matching the search", usage: "search terms", run: s.giphy},
+	}
+	return s
+}
+
+// ParseCommand splits a message such as "/topic Release day" into the command
+// name and its arguments. It reports false for messages that are not commands,
+// including those escaped with a second slash.
+func ParseCommand(content string) (string, string, bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") {
+		return "", "", false
+	}
+	word, args := content[1:], ""
+	if i := strings.IndexFunc(word, unicode.IsSpace); i >= 0 {
+		word, args = word[:i], word[i:]
+	}
+	name := strings.ToLower(word)
+	if !commandNamePattern.MatchString(name) {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// Unescape turns "//topic" into "/topic", so a message can start with a command
+// without running it. Other messages, such as "//comment" or a URL, are returned
+// unchanged.
+func (s *CommandService) Unescape(content string) (string, error) {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "//") {
+		return content, nil
+	}
+	name, _, isCommand := ParseCommand(trimmed[1:])
+	if !isCommand {
+		return content, nil
+	}
+	known, err := s.isKnown(name)
+	if err != nil || !known {
+		return content, err
+	}
+	return trimmed[1:], nil
+}
+
+// isKnown reports whether name is a built-in or enabled external command
+func (s *CommandService) isKnown(name string) (bool, error) {
+	if _, ok := s.builtins[name]; ok {
+		return true, nil
+	}
+	command, err := s.repo.FindByName(name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return command.Enabled, nil
+}
+
+// Execute runs the command in the conversation on behalf of a member who may send
+// messages there
+func (s *CommandService) Execute(userId uint, conversationId int64, name, args string) (*CommandResult, error) {
+	conversation, err := s.conversations.authorizeSend(userId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	call := &commandCall{name: name, args: args, user: user, conversation: conversation}
+
+	if builtin, ok := s.builtins[name]; ok {
+		return builtin.run(call)
+	}
+	command, err := s.repo.FindByName(name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownCommand
+		}
+		return nil, err
+	}
+	if !command.Enabled {
+		return nil, ErrUnknownCommand
+	}
+	return s.runExternal(command, call)
+}
+
+// Available lists the built-in commands and the enabled external ones
+func (s *CommandService) Available() ([]CommandInfo, error) {
+	commands, err := s.repo.FindEnabled()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]CommandInfo, 0, len(s.builtins)+len(commands))
+	for name, builtin := range s.builtins {
+		infos = append(infos, CommandInfo{Name: name, Description: builtin.description, UsageHint: builtin.usage, Builtin: true})
+	}
+	for _, command := range commands {
+		infos = append(infos, CommandInfo{Name: command.Name, Description: command.Description, UsageHint: command.UsageHint})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// ListCommands returns every external command, disabled ones included. Admins only.
+func (s *CommandService) ListCommands(actorId uint) ([]*model.SlashCommand, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAll()
+}
+
+// CreateCommand registers an external command and returns it with the secret
+// its endpoint verifies requests with. Admins only.
+func (s *CommandService) CreateCommand(actorId uint, input SlashCommandInput) (*model.SlashCommand, string, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, "", err
+	}
+	command := &model.SlashCommand{CreatedByID: actorId}
+	if err := s.applyInput(command, input); err != nil {
+		return nil, "", err
+	}
+	secret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	command.Secret = secret
+	if err := s.repo.Create(command); err != nil {
+		return nil, "", err
+	}
+	return command, secret, nil
+}
+
+// UpdateCommand changes an external command. Admins only.
+func (s *CommandService) UpdateCommand(actorId uint, commandId int64, input SlashCommandInput) (*model.SlashCommand, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	command, err := s.findCommand(commandId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(command, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(command); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// RotateSecret replaces the command's signing secret and returns the new one. Admins only.
+func (s *CommandService) RotateSecret(actorId uint, commandId int64) (string, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return "", err
+	}
+	command, err := s.findCommand(commandId)
+	if err != nil {
+		return "", err
+	}
+	secret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	command.Secret = secret
+	if err := s.repo.Save(command); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DeleteCommand removes an external command. Admins only.
+func (s *CommandService) DeleteCommand(actorId uint, commandId int64) error {
+	if err := s.requireAdmin(actorId); err != nil {
+		return err
+	}
+	if _, err := s.findCommand(commandId); err != nil {
+		return err
+	}
+	return s.repo.Delete(commandId)
+}
+
+func (s *CommandService) help(call *commandCall) (*CommandResult, error) {
+	infos, err := s.Available()
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(infos)+1)
+	lines = append(lines, "Available commands:")
+	for _, info := range infos {
+		line := "/" + info.Name
+		if info.UsageHint != "" {
+			line += " " + info.UsageHint
+		}
+		if info.Description != "" {
+			line += " - " + info.Description
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "Start a message with // to send it as text.")
+	return s.reply(call, strings.Join(lines, "\n"), nil), nil
+}
+
+func (s *CommandService) mute(call *commandCall) (*CommandResult, error) {
+	var until *time.Time
+	if call.args != "" {
+		duration, ok := parseMuteDuration(call.args)
+		if !ok {
+			return s.usage(call), nil
+		}
+		end := time.Now().Add(duration)
+		until = &end
+	}
+	if _, err := s.conversations.Mute(call.user.ID, int64(call.conversation.ID), until); err != nil {
+		return nil, err
+	}
+	if until == nil {
+		return s.reply(call, "Notifications for this conversation are muted until you unmute it.", nil), nil
+	}
+	return s.reply(call, fmt.Sprintf("Notifications for this conversation are muted for %s.", call.args), nil), nil
+}
+
+func (s *CommandService) unmute(call *commandCall) (*CommandResult, error) {
+	if _, err := s.conversations.Unmute(call.user.ID, int64(call.conversation.ID)); err != nil {
+		return nil, err
+	}
+	return s.reply(call, "Notifications for this conversation are back on.", nil), nil
+}
+
+func (s *CommandService) invite(call *commandCall) (*CommandResult, error) {
+	emails := strings.Fields(call.args)
+	if len(emails) == 0 {
+		return s.usage(call), nil
+	}
+	ids := make([]uint, 0, len(emails))
+	for _, email := range emails {
+		user, err := s.userRepo.FindByEmail(strings.TrimPrefix(email, "@"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return s.reply(call, fmt.Sprintf("No one has the email %s.", email), nil), nil
+			}
+			return nil, err
+		}
+		ids = append(ids, user.ID)
+	}
+	added, err := s.conversations.AddMembers(call.user.ID, int64(call.conversation.ID), ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) == 1 {
+		return s.reply(call, "Added 1 member.", nil), nil
+	}
+	return s.reply(call, fmt.Sprintf("Added %d members.", len(added)), nil), nil
+}
+
+func (s *CommandService) topic(call *commandCall) (*CommandResult, error) {
+	if !call.conversation.IsGroup() {
+		return nil, ErrNotGroup
+	}
+	if call.args == "" {
+		if call.conversation.Topic == "" {
+			return s.reply(call, "This group has no topic.", nil), nil
+		}
+		return s.reply(call, "The topic is: "+call.conversation.Topic, nil), nil
+	}
+	topic := call.args
+	if topic == "-" {
+		topic = ""
+	}
+	if _, err := s.conversations.SetTopic(call.user.ID, int64(call.conversation.ID), topic); err != nil {
+		return nil, err
+	}
+	return &CommandResult{Command: call.name}, nil
+}
+
+func (s *CommandService) shrug(call *commandCall) (*CommandResult, error) {
+	message, err := s.conversations.SendMessage(call.user.ID, int64(call.conversation.ID), strings.TrimSpace(call.args+" "+shrug))
+	if err != nil {
+		return nil, err
+	}
+	return &CommandResult{Command: call.name, Message: message}, nil
+}
+
+// giphy stands in for a GIF picker: it posts a link to the search results
+// instead of embedding a GIF, so it needs no API key
+func (s *CommandService) giphy(call *commandCall) (*CommandResult, error) {
+	terms := strings.Fields(call.args)
+	if len(terms) == 0 {
+		return s.usage(call), nil
+	}
+	link := giphySearchURL + url.PathEscape(strings.ToLower(strings.Join(terms, "-")))
+	message, err := s.conversations.SendMessage(call.user.ID, int64(call.conversation.ID), call.args+"\n"+link)
+	if err != nil {
+		return nil, err
+	}
+	return &CommandResult{Command: call.name, Message: message}, nil
+}
+
+// runExternal forwards the call to the command's endpoint and delivers its reply
+func (s *CommandService) runExternal(command *model.SlashCommand, call *commandCall) (*CommandResult, error) {
+	body, err := json.Marshal(CommandRequest{
+		Command:        "/" + command.Name,
+		Text:           call.args,
+		UserID:         call.user.ID,
+		UserName:       call.user.Name,
+		ConversationID: call.conversation.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, command.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, ErrCommandFailed
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go_starter-commands/1.0")
+	req.Header.Set("X-Command-Timestamp", timestamp)
+	req.Header.Set("X-Command-Signature", "sha256="+signWebhook(command.Secret, timestamp, string(body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, ErrCommandFailed
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, ErrCommandFailed
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, commandResponseLimit))
+	if err != nil {
+		return nil, ErrCommandFailed
+	}
+	var answer commandResponse
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &answer); err != nil {
+			return nil, ErrCommandFailed
+		}
+	}
+
+	text := strings.TrimSpace(answer.Text)
+	attachments, err := normalizeAttachments(answer.Attachments)
+	if err != nil || len(text) > maxWebhookTextLength || len(attachments) > maxWebhookAttachments {
+		return nil, ErrCommandFailed
+	}
+	if text == "" && len(attachments) == 0 {
+		return &CommandResult{Command: call.name}, nil
+	}
+	if answer.ResponseType != model.CommandReplyInChannel {
+		return s.reply(call, text, attachments), nil
+	}
+
+	conversationId := int64(call.conversation.ID)
+	if _, err := s.conversations.authorizeSend(call.user.ID, conversationId); err != nil {
+		return nil, err
+	}
+	message := &model.Message{
+		ConversationID: call.conversation.ID,
+		SenderID:       call.user.ID,
+		Type:           model.MessageTypeCommand,
+		Content:        text,
+		SenderName:     "/" + command.Name,
+		Attachments:    attachments,
+	}
+	if err := s.conversations.postIntegrationMessage(message); err != nil {
+		return nil, err
+	}
+	return &CommandResult{Command: call.name, Message: message}, nil
+}
+
+// reply sends an ephemeral message to the caller's sessions and returns it as the result
+func (s *CommandService) reply(call *commandCall, text string, attachments []model.MessageAttachment) *CommandResult {
+	ephemeral := &EphemeralMessage{
+		ConversationID: call.conversation.ID,
+		SenderName:     "/" + call.name,
+		Content:        text,
+		Attachments:    attachments,
+		CreatedAt:      time.Now(),
+	}
+	s.hub.PublishToUsers([]uint{call.user.ID}, realtime.Event{Type: realtime.EventMessageEphemeral, Data: ephemeral})
+	return &CommandResult{Command: call.name, Ephemeral: ephemeral}
+}
+
+// usage replies with how to call a built-in command
+func (s *CommandService) usage(call *commandCall) *CommandResult {
+	return s.reply(call, fmt.Sprintf("Usage: /%s %s", call.name, s.builtins[call.name].usage), nil)
+}
+
+// applyInput validates the input and copies it to the command
+func (s *CommandService) applyInput(command *model.SlashCommand, input SlashCommandInput) error {
+	name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input.Name), "/"))
+	if !commandNamePattern.MatchString(name) {
+		return ErrInvalidCommandName
+	}
+	if _, builtin := s.builtins[name]; builtin {
+		return ErrCommandNameTaken
+	}
+	existing, err := s.repo.FindByName(name)
+	if err == nil && existing.ID != command.ID {
+		return ErrCommandNameTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	rawURL := strings.TrimSpace(input.URL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(rawURL) > maxCommandURLLength {
+		return ErrInvalidCommandURL
+	}
+	description := strings.TrimSpace(input.Description)
+	if len(description) > maxCommandDescription {
+		description = description[:maxCommandDescription]
+	}
+	usageHint := strings.TrimSpace(input.UsageHint)
+	if len(usageHint) > maxCommandUsageHint {
+		usageHint = usageHint[:maxCommandUsageHint]
+	}
+
+	command.Name = name
+	command.Description = description
+	command.UsageHint = usageHint
+	command.URL = rawURL
+	command.Enabled = input.Enabled
+	return nil
+}
+
+func (s *CommandService) findCommand(commandId int64) (*model.SlashCommand, error) {
+	command, err := s.repo.FindById(commandId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSlashCommandNotFound
+		}
+		return nil, err
+	}
+	return command, nil
+}
+
+func (s *CommandService) requireAdmin(userId uint) error {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// parseMuteDuration reads durations such as 30m, 8h or 2d
+func parseMuteDuration(text string) (time.Duration, bool) {
+	if days, found := strings.CutSuffix(text, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil || duration <= 0 {
+		return 0, false
+	}
+	return duration, true
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrContactRequestNotFound = errors.New("contact request not found")
+	ErrContactRequestExists   = errors.New("a contact request between you is already pending")
+	ErrContactRequestClosed   = errors.New("contact request is no longer pending")
+	ErrAlreadyContact         = errors.New("user is already in your contacts")
+	ErrNotContact             = errors.New("user is not in your contacts")
+)
+
+// UserSummary is the public view of a user shared with other users
+type UserSummary struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// ContactView is a contact with their presence as seen by the owner of the list
+type ContactView struct {
+	User     UserSummary `json:"user"`
+	Presence string      `json:"presence"`
+	Since    time.Time   `json:"since"`
+}
+
+func newUserSummary(user *model.User) UserSummary {
+	return UserSummary{ID: user.ID, Name: user.Name, Email: user.Email}
+}
+
+type ContactService struct {
+	repo         *repository.ContactRepository
+	userRepo     *repository.UserRepository
+	blockRepo    *repository.BlockRepository
+	presence     *PresenceService
+	emailService *EmailService
+	hub          *realtime.Hub
+}
+
+func NewContactService(repo *repository.ContactRepository, userRepo *repository.UserRepository, blockRepo *repository.BlockRepository, presence *PresenceService, hub *realtime.Hub) *ContactService {
+	return &ContactService{
+		repo:         repo,
+		userRepo:     userRepo,
+		blockRepo:    blockRepo,
+		presence:     presence,
+		emailService: NewEmailService(),
+		hub:          hub,
+	}
+}
+
+// SendRequest asks another user to become a contact. If they already sent the
+// caller a pending request, that request is accepted instead.
+func (s *ContactService) SendRequest(userId, targetId uint) (*model.ContactRequest, error) {
+	if userId == targetId {
+		return nil, ErrCannotTargetSelf
+	}
+	requester, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.findUser(targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.blockRepo.IsBlockedEither(userId, targetId)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrBlocked
+	}
+	isContact, err := s.repo.IsContact(userId, targetId)
+	if err != nil {
+		return nil, err
+	}
+	if isContact {
+		return nil, ErrAlreadyContact
+	}
+
+	if pending, err := s.repo.FindPendingBetween(userId, targetId); err == nil {
+		if pending.AddresseeID == userId {
+			return s.AcceptRequest(userId, int64(pending.ID))
+		}
+		return nil, ErrContactRequestExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	request := &model.ContactRequest{
+		RequesterID: userId,
+		AddresseeID: targetId,
+		Status:      model.ContactRequestPending,
+	}
+	if err := s.repo.CreateRequest(request); err != nil {
+		return nil, err
+	}
+
+	s.hub.PublishToUsers([]uint{targetId}, realtime.Event{
+		Type: realtime.EventContactRequestReceived,
+		Data: map[string]interface{}{"request": request, "from": newUserSummary(requester)},
+	})
+
+	// Send notification email (async to not block the response)
+	go func() {
+		if err := s.emailService.SendContactRequestEmail(target.Email, target.Name, requester.Name); err != nil {
+			println("Failed to send contact request email: ", err.Error())
+		}
+	}()
+
+	return request, nil
+}
+
+// AcceptRequest accepts a pending request addressed to the caller
+func (s *ContactService) AcceptRequest(userId uint, requestId int64) (*model.ContactRequest, error) {
+	request, err := s.findRequest(requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request.AddresseeID != userId {
+		return nil, ErrContactRequestNotFound
+	}
+
+	accepted, err := s.repo.AcceptRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrContactRequestClosed
+	}
+	request.Status = model.ContactRequestAccepted
+
+	accepter, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	requester, err := s.findUser(request.RequesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.hub.PublishToUsers([]uint{request.RequesterID}, realtime.Event{
+		Type: realtime.EventContactRequestAccepted,
+		Data: map[string]interface{}{"request": request, "by": newUserSummary(accepter)},
+	})
+
+	// Send notification email (async to not block the response)
+	go func() {
+		if err := s.emailService.SendContactAcceptedEmail(requester.Email, requester.Name, accepter.Name); err != nil {
+			println("Failed to send contact accepted email: ", err.Error())
+		}
+	}()
+
+	return request, nil
+}
+
+// DeclineRequest declines a pending request addressed to the caller
+func (s *ContactService) DeclineRequest(userId uint, requestId int64) error {
+	request, err := s.findRequest(requestId)
+	if err != nil {
+		return err
+	}
+	if request.AddresseeID != userId {
+		return ErrContactRequestNotFound
+	}
+	if err := s.closeRequest(requestId, model.ContactRequestDeclined); err != nil {
+		return err
+	}
+	request.Status = model.ContactRequestDeclined
+
+	s.hub.PublishToUsers([]uint{request.RequesterID}, realtime.Event{Type: realtime.EventContactRequestDeclined, Data: request})
+	return nil
+}
+
+// CancelRequest withdraws a pending request the caller sent
+func (s *ContactService) CancelRequest(userId uint, requestId int64) error {
+	request, err := s.findRequest(requestId)
+	if err != nil {
+		return err
+	}
+	if request.RequesterID != userId {
+		return ErrContactRequestNotFound
+	}
+	if err := s.closeRequest(requestId, model.ContactRequestCancelled); err != nil {
+		return err
+	}
+	request.Status = model.ContactRequestCancelled
+
+	s.hub.PublishToUsers([]uint{request.AddresseeID}, realtime.Event{Type: realtime.EventContactRequestCancelled, Data: request})
+	return nil
+}
+
+func (s *ContactService) ListIncoming(userId uint) ([]*model.ContactRequest, error) {
+	return s.repo.FindIncoming(userId)
+}
+
+func (s *ContactService) ListOutgoing(userId uint) ([]*model.ContactRequest, error) {
+	return s.repo.FindOutgoing(userId)
+}
+
+// ListContacts returns the caller's contacts together with their presence
+func (s *ContactService) ListContacts(userId uint) ([]*ContactView, error) {
+	contacts, err := s.repo.FindContacts(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(contacts))
+	for _, contact := range contacts {
+		ids = append(ids, contact.ContactID)
+	}
+	users, err := s.userRepo.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	usersById := make(map[uint]*model.User, len(users))
+	for _, user := range users {
+		usersById[user.ID] = user
+	}
+	statuses, err := s.presence.Presence(userId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*ContactView, 0, len(contacts))
+	for _, contact := range contacts {
+		user, ok := usersById[contact.ContactID]
+		if !ok {
+			continue
+		}
+		views = append(views, &ContactView{
+			User:     newUserSummary(user),
+			Presence: statuses[contact.ContactID],
+			Since:    contact.CreatedAt,
+		})
+	}
+	return views, nil
+}
+
+// MutualContacts returns the contacts the caller shares with another user
+func (s *ContactService) MutualContacts(userId, otherUserId uint) ([]UserSummary, error) {
+	if _, err := s.findUser(otherUserId); err != nil {
+		return nil, err
+	}
+	ids, err := s.repo.MutualIds(userId, otherUserId)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]UserSummary, 0, len(users))
+	for _, user := range users {
+		summaries = append(summaries, newUserSummary(user))
+	}
+	return summaries, nil
+}
+
+func (s *ContactService) RemoveContact(userId, contactId uint) error {
+	removed, err := s.repo.DeleteContact(userId, contactId)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotContact
+	}
+	return nil
+}
+
+func (s *ContactService) closeRequest(requestId int64, status string) error {
+	updated, err := s.repo.UpdateRequestStatus(requestId, status)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrContactRequestClosed
+	}
+	return nil
+}
+
+func (s *ContactService) findRequest(requestId int64) (*model.ContactRequest, error) {
+	request, err := s.repo.FindRequest(requestId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactRequestNotFound
+		}
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *ContactService) findUser(userId uint) (*model.User, error) {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
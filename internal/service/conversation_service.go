@@ -0,0 +1,1043 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrNotMember            = errors.New("you are not a member of this conversation")
+	ErrForbidden            = errors.New("you do not have permission to perform this action")
+	ErrNotGroup             = errors.New("this action is only available in group conversations")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrAlreadyMember        = errors.New("user is already a member of this conversation")
+	ErrTargetNotMember      = errors.New("user is not a member of this conversation")
+	ErrInvalidRole          = errors.New("role must be admin or member")
+	ErrOwnerMustTransfer    = errors.New("owner must transfer ownership before leaving")
+	ErrCannotTargetSelf     = errors.New("this action cannot be applied to yourself")
+	ErrEmptyMessage         = errors.New("message content is required")
+	ErrBlocked              = errors.New("you cannot interact with this user")
+	ErrDMNotAllowed         = errors.New("this user only accepts direct messages from contacts")
+	ErrInvalidMuteUntil     = errors.New("mute end time must be in the future")
+	ErrMessageNotFound      = errors.New("message not found")
+	ErrConversationClosed   = errors.New("this conversation has been closed")
+	ErrInternalMessage      = errors.New("internal notes cannot be used here")
+	ErrInvalidTopic         = errors.New("topic must be at most 255 characters")
+	ErrInvalidMessageTTL    = errors.New("disappearing message timer must be 0 or between 1 minute and 90 days")
+)
+
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 100
+	maxTopicLength         = 255
+	minMessageTTL          = time.Minute
+	maxMessageTTL          = 90 * 24 * time.Hour
+)
+
+type ConversationService struct {
+	repo        *repository.ConversationRepository
+	msgRepo     *repository.MessageRepository
+	userRepo    *repository.UserRepository
+	blockRepo   *repository.BlockRepository
+	contactRepo *repository.ContactRepository
+	webhooks    *WebhookService
+	hub         *realtime.Hub
+}
+
+func NewConversationService(repo *repository.ConversationRepository, msgRepo *repository.MessageRepository, userRepo *repository.UserRepository, blockRepo *repository.BlockRepository, contactRepo *repository.ContactRepository, webhooks *WebhookService, hub *realtime.Hub) *ConversationService {
+	return &ConversationService{
+		repo:        repo,
+		msgRepo:     msgRepo,
+		userRepo:    userRepo,
+		blockRepo:   blockRepo,
+		contactRepo: contactRepo,
+		webhooks:    webhooks,
+		hub:         hub,
+	}
+}
+
+// CreateDirect returns the existing direct conversation between the two users or creates one
+func (s *ConversationService) CreateDirect(userId, otherUserId uint) (*model.Conversation, error) {
+	if userId == otherUserId {
+		return nil, ErrCannotTargetSelf
+	}
+	other, err := s.findUser(otherUserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureNotBlocked(userId, otherUserId); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindDirect(userId, otherUserId)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Users who only accept DMs from contacts cannot be reached by strangers
+	if other.DMPrivacy == model.DMPrivacyContacts {
+		isContact, err := s.contactRepo.IsContact(otherUserId, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !isContact {
+			return nil, ErrDMNotAllowed
+		}
+	}
+
+	now := time.Now()
+	conversation := &model.Conversation{Type: model.ConversationTypeDirect}
+	members := []*model.ConversationMember{
+		{UserID: userId, Role: model.MemberRoleMember, JoinedAt: now},
+		{UserID: otherUserId, Role: model.MemberRoleMember, JoinedAt: now},
+	}
+	if err := s.repo.Create(conversation, members); err != nil {
+		return nil, err
+	}
+
+	s.hub.PublishToUsers([]uint{userId, otherUserId}, realtime.Event{Type: realtime.EventConversationCreated, Data: conversation})
+	s.webhooks.Publish(model.WebhookConversationCreated, conversation)
+	return conversation, nil
+}
+
+// CreateGroup creates a group owned by ownerId with the given initial members
+func (s *ConversationService) CreateGroup(ownerId uint, name, avatarURL string, memberIds []uint) (*model.Conversation, error) {
+	owner, err := s.findUser(ownerId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	members := []*model.ConversationMember{{UserID: ownerId, Role: model.MemberRoleOwner, JoinedAt: now}}
+	seen := map[uint]bool{ownerId: true}
+	for _, id := range memberIds {
+		if seen[id] {
+			continue
+		}
+		if _, err := s.findUser(id); err != nil {
+			return nil, err
+		}
+		if err := s.ensureNotBlocked(ownerId, id); err != nil {
+			return nil, err
+		}
+		seen[id] = true
+		members = append(members, &model.ConversationMember{UserID: id, Role: model.MemberRoleMember, JoinedAt: now})
+	}
+
+	conversation := &model.Conversation{
+		Type:      model.ConversationTypeGroup,
+		Name:      name,
+		AvatarURL: avatarURL,
+		OwnerID:   ownerId,
+	}
+	if err := s.repo.Create(conversation, members); err != nil {
+		return nil, err
+	}
+
+	userIds := make([]uint, 0, len(members))
+	for _, m := range members {
+		userIds = append(userIds, m.UserID)
+	}
+	s.hub.PublishToUsers(userIds, realtime.Event{Type: realtime.EventConversationCreated, Data: conversation})
+	s.webhooks.Publish(model.WebhookConversationCreated, conversation)
+
+	if err := s.postSystemMessage(int64(conversation.ID), fmt.Sprintf("%s created the group \"%s\"", owner.Name, name)); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// ConversationListItem is a conversation together with the caller's own membership settings
+type ConversationListItem struct {
+	*model.Conversation
+	Membership *model.ConversationMember `json:"membership"`
+}
+
+// ListConversations returns the user's archived or unarchived conversations, pinned ones first
+func (s *ConversationService) ListConversations(userId uint, archived bool) ([]*ConversationListItem, error) {
+	conversations, err := s.repo.FindByUser(userId, archived)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.repo.FindMembershipsByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	byConversation := make(map[uint]*model.ConversationMember, len(memberships))
+	for _, m := range memberships {
+		byConversation[m.ConversationID] = m
+	}
+
+	items := make([]*ConversationListItem, 0, len(conversations))
+	for _, conversation := range conversations {
+		items = append(items, &ConversationListItem{
+			Conversation: conversation,
+			Membership:   byConversation[conversation.ID],
+		})
+	}
+	return items, nil
+}
+
+// Mute silences notifications for the caller until the given time, or forever when until is nil
+func (s *ConversationService) Mute(userId uint, conversationId int64, until *time.Time) (*model.ConversationMember, error) {
+	if until != nil && !until.After(time.Now()) {
+		return nil, ErrInvalidMuteUntil
+	}
+	return s.updateSettings(userId, conversationId, map[string]interface{}{"muted": true, "muted_until": until})
+}
+
+func (s *ConversationService) Unmute(userId uint, conversationId int64) (*model.ConversationMember, error) {
+	return s.updateSettings(userId, conversationId, map[string]interface{}{"muted": false, "muted_until": nil})
+}
+
+// Pin keeps the conversation at the top of the caller's list
+func (s *ConversationService) Pin(userId uint, conversationId int64) (*model.ConversationMember, error) {
+	return s.updateSettings(userId, conversationId, map[string]interface{}{"pinned_at": time.Now()})
+}
+
+func (s *ConversationService) Unpin(userId uint, conversationId int64) (*model.ConversationMember, error) {
+	return s.updateSettings(userId, conversationId, map[string]interface{}{"pinned_at": nil})
+}
+
+// Archive hides the conversation from the caller's main list until new activity
+// arrives, or for good while the conversation is muted
+func (s *ConversationService) Archive(userId uint, conversationId int64) (*model.ConversationMember, error) {
+	return s.updateSettings(userId, conversationId, map[string]interface{}{"archived_at": time.Now()})
+}
+
+func (s *ConversationService) Unarchive(userId uint, conversationId int64) (*model.ConversationMember, error) {
+	return s.updateSettings(userId, conversationId, map[string]interface{}{"archived_at": nil})
+}
+
+// GetConversation returns the conversation and its members if the user belongs to it
+func (s *ConversationService) GetConversation(userId uint, conversationId int64) (*model.Conversation, []*model.ConversationMember, error) {
+	conversation, _, err := s.authorize(userId, conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	members, err := s.repo.FindMembers(conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conversation, members, nil
+}
+
+func (s *ConversationService) ListMembers(userId uint, conversationId int64) ([]*model.ConversationMember, error) {
+	if _, _, err := s.authorize(userId, conversationId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindMembers(conversationId)
+}
+
+// SendMessage stores a message from a member and pushes it to everyone in the conversation
+func (s *ConversationService) SendMessage(userId uint, conversationId int64, content string) (*model.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	conversation, err := s.authorizeSend(userId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &model.Message{
+		ConversationID: uint(conversationId),
+		SenderID:       userId,
+		Type:           model.MessageTypeText,
+		Content:        content,
+	}
+	if err := s.msgRepo.Create(message); err != nil {
+		return nil, err
+	}
+	if err := s.recordActivity(conversationId); err != nil {
+		return nil, err
+	}
+
+	event := realtime.Event{Type: realtime.EventMessageCreated, Data: message}
+	s.broadcastFrom(conversationId, userId, event)
+	s.relayToVisitor(conversation, event)
+	s.notify(conversationId, message)
+	s.webhooks.Publish(model.WebhookMessageCreated, message)
+	return message, nil
+}
+
+// ListMessages returns a page of history, newest first, older than beforeId when set
+func (s *ConversationService) ListMessages(userId uint, conversationId int64, beforeId int64, limit int) ([]*model.Message, error) {
+	conversation, _, err := s.authorize(userId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	includeInternal, err := s.canSeeInternal(userId, conversation)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	if limit > maxMessagePageSize {
+		limit = maxMessagePageSize
+	}
+
+	// Messages from users the caller has blocked are hidden from their history
+	blockedIds, err := s.blockRepo.BlockedIds(userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.msgRepo.FindByConversation(conversationId, beforeId, limit, blockedIds, includeInternal)
+}
+
+// Typing tells other members the user is typing. Users with a block between them
+// and the typist are not told.
+func (s *ConversationService) Typing(userId uint, conversationId int64) error {
+	conversation, member, err := s.authorize(userId, conversationId)
+	if err != nil {
+		return err
+	}
+
+	related, err := s.blockRepo.RelatedIds(userId)
+	if err != nil {
+		return err
+	}
+	ids, err := s.repo.MemberIds(conversationId)
+	if err != nil {
+		return err
+	}
+	event := realtime.Event{
+		Type: realtime.EventTyping,
+		Data: map[string]interface{}{"conversation_id": conversationId, "user_id": userId},
+	}
+	s.hub.PublishToUsers(excludeIds(ids, append(related, userId)), event)
+	if member.Role != model.MemberRoleObserver {
+		s.relayToVisitor(conversation, event)
+	}
+	return nil
+}
+
+// AddMembers adds users to a group. Only owners and admins may add members.
+func (s *ConversationService) AddMembers(actorId uint, conversationId int64, userIds []uint) ([]*model.ConversationMember, error) {
+	_, actor, err := s.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManageMembers() {
+		return nil, ErrForbidden
+	}
+
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var added []*model.ConversationMember
+	var names []string
+	seen := map[uint]bool{}
+	for _, id := range userIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := s.repo.FindMember(conversationId, id); err == nil {
+			continue
+		}
+		user, err := s.findUser(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.ensureNotBlocked(actorId, id); err != nil {
+			return nil, err
+		}
+		added = append(added, &model.ConversationMember{
+			ConversationID: uint(conversationId),
+			UserID:         id,
+			Role:           model.MemberRoleMember,
+			JoinedAt:       now,
+		})
+		names = append(names, user.Name)
+	}
+	if len(added) == 0 {
+		return nil, ErrAlreadyMember
+	}
+
+	if err := s.repo.AddMembers(added); err != nil {
+		return nil, err
+	}
+
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventMemberAdded, Data: added})
+	if err := s.postSystemMessage(conversationId, fmt.Sprintf("%s added %s", actorUser.Name, strings.Join(names, ", "))); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveMember removes another member from a group. Admins may only remove plain
+// members; the owner may remove anyone.
+func (s *ConversationService) RemoveMember(actorId uint, conversationId int64, targetId uint) error {
+	if actorId == targetId {
+		return ErrCannotTargetSelf
+	}
+	_, actor, err := s.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	target, err := s.findMember(conversationId, targetId)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManageMembers() || target.IsOwner() {
+		return ErrForbidden
+	}
+	if target.Role == model.MemberRoleAdmin && !actor.IsOwner() {
+		return ErrForbidden
+	}
+
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return err
+	}
+	targetUser, err := s.findUser(targetId)
+	if err != nil {
+		return err
+	}
+
+	// Notify before removal so the removed user receives the event too
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventMemberRemoved, Data: target})
+	if err := s.repo.RemoveMember(conversationId, targetId); err != nil {
+		return err
+	}
+	return s.postSystemMessage(conversationId, fmt.Sprintf("%s removed %s", actorUser.Name, targetUser.Name))
+}
+
+// ChangeRole promotes a member to admin or demotes an admin to member. Owner only.
+func (s *ConversationService) ChangeRole(actorId uint, conversationId int64, targetId uint, role string) (*model.ConversationMember, error) {
+	if role != model.MemberRoleAdmin && role != model.MemberRoleMember {
+		return nil, ErrInvalidRole
+	}
+	if actorId == targetId {
+		return nil, ErrCannotTargetSelf
+	}
+	_, actor, err := s.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsOwner() {
+		return nil, ErrForbidden
+	}
+	target, err := s.findMember(conversationId, targetId)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role == role {
+		return target, nil
+	}
+
+	if err := s.repo.UpdateMemberRole(conversationId, targetId, role); err != nil {
+		return nil, err
+	}
+	target.Role = role
+
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+	targetUser, err := s.findUser(targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventMemberRoleChanged, Data: target})
+	text := fmt.Sprintf("%s made %s an admin", actorUser.Name, targetUser.Name)
+	if role == model.MemberRoleMember {
+		text = fmt.Sprintf("%s removed %s as admin", actorUser.Name, targetUser.Name)
+	}
+	if err := s.postSystemMessage(conversationId, text); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// Leave removes the caller from a group. The owner must hand over ownership first
+// unless they are the last member.
+func (s *ConversationService) Leave(userId uint, conversationId int64) error {
+	_, member, err := s.authorizeGroup(userId, conversationId)
+	if err != nil {
+		return err
+	}
+	if member.IsOwner() {
+		ids, err := s.repo.MemberIds(conversationId)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 1 {
+			return ErrOwnerMustTransfer
+		}
+	}
+
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveMember(conversationId, userId); err != nil {
+		return err
+	}
+	s.hub.PublishToUsers([]uint{userId}, realtime.Event{Type: realtime.EventMemberLeft, Data: member})
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventMemberLeft, Data: member})
+	return s.postSystemMessage(conversationId, fmt.Sprintf("%s left the group", user.Name))
+}
+
+// TransferOwnership hands the group over to another member; the previous owner becomes an admin
+func (s *ConversationService) TransferOwnership(actorId uint, conversationId int64, targetId uint) error {
+	if actorId == targetId {
+		return ErrCannotTargetSelf
+	}
+	_, actor, err := s.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	if !actor.IsOwner() {
+		return ErrForbidden
+	}
+	if _, err := s.findMember(conversationId, targetId); err != nil {
+		return err
+	}
+
+	if err := s.repo.TransferOwnership(conversationId, actorId, targetId); err != nil {
+		return err
+	}
+
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return err
+	}
+	targetUser, err := s.findUser(targetId)
+	if err != nil {
+		return err
+	}
+
+	s.broadcast(conversationId, realtime.Event{
+		Type: realtime.EventOwnerTransferred,
+		Data: map[string]interface{}{"conversation_id": conversationId, "from_user_id": actorId, "to_user_id": targetId},
+	})
+	return s.postSystemMessage(conversationId, fmt.Sprintf("%s transferred ownership to %s", actorUser.Name, targetUser.Name))
+}
+
+// UpdateGroup renames the group and/or changes its avatar. Nil fields are left unchanged.
+func (s *ConversationService) UpdateGroup(actorId uint, conversationId int64, name, avatarURL *string) (*model.Conversation, error) {
+	conversation, actor, err := s.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManageMembers() {
+		return nil, ErrForbidden
+	}
+
+	fields := map[string]interface{}{}
+	if name != nil && *name != conversation.Name {
+		fields["name"] = *name
+	}
+	if avatarURL != nil && *avatarURL != conversation.AvatarURL {
+		fields["avatar_url"] = *avatarURL
+	}
+	if len(fields) == 0 {
+		return conversation, nil
+	}
+
+	if err := s.repo.Update(conversationId, fields); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.FindById(conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventConversationUpdated, Data: updated})
+	if _, ok := fields["name"]; ok {
+		if err := s.postSystemMessage(conversationId, fmt.Sprintf("%s renamed the group to \"%s\"", actorUser.Name, updated.Name)); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := fields["avatar_url"]; ok {
+		if err := s.postSystemMessage(conversationId, fmt.Sprintf("%s changed the group avatar", actorUser.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// SetTopic sets the group's topic, or clears it when topic is empty. Any member may change it.
+func (s *ConversationService) SetTopic(actorId uint, conversationId int64, topic string) (*model.Conversation, error) {
+	topic = strings.TrimSpace(topic)
+	if len(topic) > maxTopicLength {
+		return nil, ErrInvalidTopic
+	}
+	conversation, _, err := s.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if topic == conversation.Topic {
+		return conversation, nil
+	}
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(conversationId, map[string]interface{}{"topic": topic}); err != nil {
+		return nil, err
+	}
+	conversation.Topic = topic
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventConversationUpdated, Data: conversation})
+	text := fmt.Sprintf("%s cleared the topic", actorUser.Name)
+	if topic != "" {
+		text = fmt.Sprintf("%s set the topic to \"%s\"", actorUser.Name, topic)
+	}
+	if err := s.postSystemMessage(conversationId, text); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// SetMessageTTL turns disappearing messages on for messages sent from now on, or
+// off when ttl is 0. Any member of a direct conversation may change it; in groups
+// only owners and admins may.
+func (s *ConversationService) SetMessageTTL(actorId uint, conversationId int64, ttl time.Duration) (*model.Conversation, error) {
+	if ttl != 0 && (ttl < minMessageTTL || ttl > maxMessageTTL) {
+		return nil, ErrInvalidMessageTTL
+	}
+	conversation, member, err := s.authorize(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.IsSupport() || (conversation.IsGroup() && !member.CanManageMembers()) {
+		return nil, ErrForbidden
+	}
+	seconds := int(ttl / time.Second)
+	if seconds == conversation.MessageTTL {
+		return conversation, nil
+	}
+	actorUser, err := s.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(conversationId, map[string]interface{}{"message_ttl": seconds}); err != nil {
+		return nil, err
+	}
+	conversation.MessageTTL = seconds
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventConversationUpdated, Data: conversation})
+	text := fmt.Sprintf("%s turned off disappearing messages", actorUser.Name)
+	if seconds > 0 {
+		text = fmt.Sprintf("%s set new messages to disappear after %s", actorUser.Name, describeTTL(ttl))
+	}
+	if err := s.postSystemMessage(conversationId, text); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// join adds a user to a group outside the admin-driven AddMembers flow, such as
+// through an invite, and records text as the system message
+func (s *ConversationService) join(conversationId int64, userId uint, text string) (*model.ConversationMember, error) {
+	if _, err := s.repo.FindMember(conversationId, userId); err == nil {
+		return nil, ErrAlreadyMember
+	}
+
+	member := &model.ConversationMember{
+		ConversationID: uint(conversationId),
+		UserID:         userId,
+		Role:           model.MemberRoleMember,
+		JoinedAt:       time.Now(),
+	}
+	if err := s.repo.AddMembers([]*model.ConversationMember{member}); err != nil {
+		return nil, err
+	}
+	if err := s.announceJoin(member, text); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// announceJoin tells the members about a member who was just stored and posts text
+// as a system message
+func (s *ConversationService) announceJoin(member *model.ConversationMember, text string) error {
+	conversationId := int64(member.ConversationID)
+	s.broadcast(conversationId, realtime.Event{Type: realtime.EventMemberAdded, Data: []*model.ConversationMember{member}})
+	return s.postSystemMessage(conversationId, text)
+}
+
+// authorize loads the conversation and the caller's membership
+func (s *ConversationService) authorize(userId uint, conversationId int64) (*model.Conversation, *model.ConversationMember, error) {
+	conversation, err := s.repo.FindById(conversationId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrConversationNotFound
+		}
+		return nil, nil, err
+	}
+	member, err := s.repo.FindMember(conversationId, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotMember
+		}
+		return nil, nil, err
+	}
+	return conversation, member, nil
+}
+
+// authorizeSend is authorize for posting: the conversation must be open, the
+// caller must not be an observer and, in direct conversations, not blocked
+func (s *ConversationService) authorizeSend(userId uint, conversationId int64) (*model.Conversation, error) {
+	conversation, member, err := s.authorize(userId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.IsClosed() {
+		return nil, ErrConversationClosed
+	}
+	// Observers follow a support chat silently and can only whisper
+	if member.Role == model.MemberRoleObserver {
+		return nil, ErrForbidden
+	}
+	if conversation.Type == model.ConversationTypeDirect {
+		if err := s.ensureDirectNotBlocked(userId, conversationId); err != nil {
+			return nil, err
+		}
+	}
+	return conversation, nil
+}
+
+// authorizeGroup is authorize restricted to group conversations
+func (s *ConversationService) authorizeGroup(userId uint, conversationId int64) (*model.Conversation, *model.ConversationMember, error) {
+	conversation, member, err := s.authorize(userId, conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !conversation.IsGroup() {
+		return nil, nil, ErrNotGroup
+	}
+	return conversation, member, nil
+}
+
+// findMessage loads a message and checks it belongs to the conversation
+func (s *ConversationService) findMessage(conversationId int64, messageId int64) (*model.Message, error) {
+	message, err := s.msgRepo.FindById(messageId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	if conversationId != 0 && message.ConversationID != uint(conversationId) {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+func (s *ConversationService) findMember(conversationId int64, userId uint) (*model.ConversationMember, error) {
+	member, err := s.repo.FindMember(conversationId, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTargetNotMember
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *ConversationService) findUser(userId uint) (*model.User, error) {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// postSystemMessage records a membership or settings change in the conversation history
+func (s *ConversationService) postSystemMessage(conversationId int64, content string) error {
+	_, err := s.postAutomatedMessage(conversationId, model.MessageTypeSystem, content)
+	return err
+}
+
+// postAutomatedMessage records a message no user sent, such as a system event or
+// a bot reply, and pushes it to the members and the visitor
+func (s *ConversationService) postAutomatedMessage(conversationId int64, messageType, content string) (*model.Message, error) {
+	message := &model.Message{
+		ConversationID: uint(conversationId),
+		Type:           messageType,
+		Content:        content,
+	}
+	if err := s.msgRepo.Create(message); err != nil {
+		return nil, err
+	}
+	if err := s.recordActivity(conversationId); err != nil {
+		return nil, err
+	}
+	event := realtime.Event{Type: realtime.EventMessageCreated, Data: message}
+	s.broadcast(conversationId, event)
+	if conversation, err := s.repo.FindById(conversationId); err == nil {
+		s.relayToVisitor(conversation, event)
+	}
+	s.webhooks.Publish(model.WebhookMessageCreated, message)
+	return message, nil
+}
+
+// postIntegrationMessage records a message an external system posted, such as an
+// incoming webhook post, and alerts the members like any other new message
+func (s *ConversationService) postIntegrationMessage(message *model.Message) error {
+	if err := s.msgRepo.Create(message); err != nil {
+		return err
+	}
+	return s.announce(message)
+}
+
+// announce tells the members about a message that was just stored and alerts
+// them. Members who blocked the sender are left out.
+func (s *ConversationService) announce(message *model.Message) error {
+	conversationId := int64(message.ConversationID)
+	if err := s.recordActivity(conversationId); err != nil {
+		return err
+	}
+	event := realtime.Event{Type: realtime.EventMessageCreated, Data: message}
+	if message.SenderID != 0 {
+		s.broadcastFrom(conversationId, message.SenderID, event)
+	} else {
+		s.broadcast(conversationId, event)
+	}
+	s.notify(conversationId, message)
+	s.webhooks.Publish(model.WebhookMessageCreated, message)
+	return nil
+}
+
+// postInternalMessage records a message only agents can see, such as a whisper or
+// a collaboration event, and pushes it to the staff members of the conversation
+func (s *ConversationService) postInternalMessage(conversationId int64, senderId uint, messageType, content string) (*model.Message, error) {
+	message := &model.Message{
+		ConversationID: uint(conversationId),
+		SenderID:       senderId,
+		Type:           messageType,
+		Content:        content,
+		Internal:       true,
+	}
+	if err := s.msgRepo.Create(message); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Touch(conversationId, time.Now()); err != nil {
+		return nil, err
+	}
+	s.broadcastToStaff(conversationId, realtime.Event{Type: realtime.EventMessageCreated, Data: message})
+	return message, nil
+}
+
+// canSeeInternal reports whether the user may read agent-only messages of the
+// conversation. Only support chats have them, and only staff see them there.
+func (s *ConversationService) canSeeInternal(userId uint, conversation *model.Conversation) (bool, error) {
+	if !conversation.IsSupport() {
+		return true, nil
+	}
+	user, err := s.findUser(userId)
+	if err != nil {
+		return false, err
+	}
+	return user.IsStaff(), nil
+}
+
+// broadcastToStaff publishes the event to the members of the conversation who are agents or admins
+func (s *ConversationService) broadcastToStaff(conversationId int64, event realtime.Event) {
+	ids, err := s.repo.MemberIds(conversationId)
+	if err != nil {
+		return
+	}
+	users, err := s.userRepo.FindByIds(ids)
+	if err != nil {
+		return
+	}
+	var staff []uint
+	for _, user := range users {
+		if user.IsStaff() {
+			staff = append(staff, user.ID)
+		}
+	}
+	s.hub.PublishToUsers(staff, event)
+}
+
+// notifyManagers publishes the event to the owner and admins of the conversation
+func (s *ConversationService) notifyManagers(conversationId int64, event realtime.Event) {
+	members, err := s.repo.FindMembers(conversationId)
+	if err != nil {
+		return
+	}
+	var ids []uint
+	for _, m := range members {
+		if m.CanManageMembers() {
+			ids = append(ids, m.UserID)
+		}
+	}
+	s.hub.PublishToUsers(ids, event)
+}
+
+// updateSettings applies per-member settings for the caller and syncs them to the caller's other sessions
+func (s *ConversationService) updateSettings(userId uint, conversationId int64, fields map[string]interface{}) (*model.ConversationMember, error) {
+	if _, _, err := s.authorize(userId, conversationId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateMemberSettings(conversationId, userId, fields); err != nil {
+		return nil, err
+	}
+	member, err := s.repo.FindMember(conversationId, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.PublishToUsers([]uint{userId}, realtime.Event{Type: realtime.EventMembershipUpdated, Data: member})
+	return member, nil
+}
+
+// recordActivity moves the conversation to the top of member lists and brings it
+// back out of the archive for members who have not muted it
+func (s *ConversationService) recordActivity(conversationId int64) error {
+	now := time.Now()
+	if err := s.repo.Touch(conversationId, now); err != nil {
+		return err
+	}
+	return s.repo.UnarchiveForActivity(conversationId, now)
+}
+
+// notify sends a notification for a new message to every member who should be
+// alerted: not the sender, not members who muted the conversation and not
+// members who blocked the sender
+func (s *ConversationService) notify(conversationId int64, message *model.Message) {
+	members, err := s.repo.FindMembers(conversationId)
+	if err != nil {
+		return
+	}
+	blockers, err := s.blockRepo.BlockerIds(message.SenderID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	var recipients []uint
+	for _, m := range members {
+		if m.UserID == message.SenderID || m.IsMuted(now) {
+			continue
+		}
+		recipients = append(recipients, m.UserID)
+	}
+	s.hub.PublishToUsers(excludeIds(recipients, blockers), realtime.Event{Type: realtime.EventNotification, Data: message})
+}
+
+// ensureNotBlocked fails with ErrBlocked when either user has blocked the other
+func (s *ConversationService) ensureNotBlocked(userId, otherUserId uint) error {
+	blocked, err := s.blockRepo.IsBlockedEither(userId, otherUserId)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// ensureDirectNotBlocked applies ensureNotBlocked to the other participant of a direct conversation
+func (s *ConversationService) ensureDirectNotBlocked(userId uint, conversationId int64) error {
+	ids, err := s.repo.MemberIds(conversationId)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id != userId {
+			return s.ensureNotBlocked(userId, id)
+		}
+	}
+	return nil
+}
+
+// broadcastFrom publishes an event caused by senderId to every member except those who blocked the sender
+func (s *ConversationService) broadcastFrom(conversationId int64, senderId uint, event realtime.Event) {
+	ids, err := s.repo.MemberIds(conversationId)
+	if err != nil {
+		return
+	}
+	blockers, err := s.blockRepo.BlockerIds(senderId)
+	if err != nil {
+		return
+	}
+	s.hub.PublishToUsers(excludeIds(ids, blockers), event)
+}
+
+// broadcast publishes the event to every current member of the conversation
+func (s *ConversationService) broadcast(conversationId int64, event realtime.Event) {
+	ids, err := s.repo.MemberIds(conversationId)
+	if err != nil {
+		return
+	}
+	s.hub.PublishToUsers(ids, event)
+}
+
+// relayToVisitor forwards a conversation event to the website visitor of a support conversation
+func (s *ConversationService) relayToVisitor(conversation *model.Conversation, event realtime.Event) {
+	if conversation.VisitorID == nil {
+		return
+	}
+	s.hub.Publish(realtime.VisitorTopic(*conversation.VisitorID), event)
+}
+
+// describeTTL spells out a disappearing message timer, such as "7 days" or "90 minutes"
+func describeTTL(ttl time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+	for _, unit := range units {
+		if ttl%unit.size == 0 {
+			count := int(ttl / unit.size)
+			if count == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", count, unit.name)
+		}
+	}
+	return ttl.String()
+}
+
+// excludeIds returns ids without any of the excluded values
+func excludeIds(ids []uint, excluded []uint) []uint {
+	if len(excluded) == 0 {
+		return ids
+	}
+	skip := make(map[uint]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !skip[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	_ "time/tzdata" // business hours must not depend on the host's zoneinfo files
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDepartmentNotFound     = errors.New("department not found")
+	ErrDepartmentExists       = errors.New("a department with this name already exists")
+	ErrInvalidFallback        = errors.New("fallback must be another existing department that does not fall back to this one")
+	ErrInvalidOverflow        = errors.New("overflow time must not be negative and requires a fallback department")
+	ErrAlreadyDepartmentAgent = errors.New("agent already belongs to this department")
+	ErrNotDepartmentAgent     = errors.New("agent does not belong to this department")
+	ErrDepartmentRuleNotFound = errors.New("department rule not found")
+	ErrEmptyDepartmentRule    = errors.New("a rule needs a page URL or a language condition")
+	ErrInvalidTimezone        = errors.New("unknown timezone")
+	ErrInvalidBusinessHours   = errors.New("business hours need a weekday from 0 to 6 and HH:MM times with opening before closing")
+	ErrInvalidHolidayDate     = errors.New("holiday date must be formatted as YYYY-MM-DD")
+	ErrHolidayExists          = errors.New("this date is already a holiday for the department")
+	ErrHolidayNotFound        = errors.New("holiday not found")
+)
+
+// BusinessHoursInput is one weekly opening window, times as "HH:MM" in the department's timezone
+type BusinessHoursInput struct {
+	Weekday  int
+	OpensAt  string
+	ClosesAt string
+}
+
+// Availability tells the widget whether support is open and, if not, when it opens again
+type Availability struct {
+	Online       bool       `json:"online"`
+	DepartmentID *uint      `json:"department_id,omitempty"`
+	NextOpenAt   *time.Time `json:"next_open_at,omitempty"`
+}
+
+// DepartmentInput holds the admin-editable settings of a department
+type DepartmentInput struct {
+	Name                 string
+	Description          string
+	FallbackDepartmentID *uint
+	OverflowAfterSeconds int
+	Email                string
+}
+
+// DepartmentView is a department together with the agents who work in it
+type DepartmentView struct {
+	*model.Department
+	AgentIDs []uint `json:"agent_ids"`
+}
+
+// DepartmentSummary is the public view of a department offered to visitors
+type DepartmentSummary struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Online      bool   `json:"online"`
+}
+
+type DepartmentService struct {
+	repo     *repository.DepartmentRepository
+	userRepo *repository.UserRepository
+	routing  *RoutingService
+}
+
+func NewDepartmentService(repo *repository.DepartmentRepository, userRepo *repository.UserRepository, routing *RoutingService) *DepartmentService {
+	return &DepartmentService{
+		repo:     repo,
+		userRepo: userRepo,
+		routing:  routing,
+	}
+}
+
+func (s *DepartmentService) List() ([]*model.Department, error) {
+	return s.repo.FindAll()
+}
+
+// ListPublic returns the departments a visitor can pick from in the widget
+func (s *DepartmentService) ListPublic() ([]DepartmentSummary, error) {
+	departments, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	summaries := make([]DepartmentSummary, 0, len(departments))
+	for _, department := range departments {
+		summaries = append(summaries, DepartmentSummary{
+			ID:          department.ID,
+			Name:        department.Name,
+			Description: department.Description,
+			Online:      department.IsOpen(now),
+		})
+	}
+	return summaries, nil
+}
+
+func (s *DepartmentService) Get(departmentId int64) (*DepartmentView, error) {
+	department, err := s.findDepartment(departmentId)
+	if err != nil {
+		return nil, err
+	}
+	agentIds, err := s.repo.AgentIds(departmentId)
+	if err != nil {
+		return nil, err
+	}
+	return &DepartmentView{Department: department, AgentIDs: agentIds}, nil
+}
+
+func (s *DepartmentService) Create(adminId uint, input DepartmentInput) (*model.Department, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.ensureNameFree(0, input.Name); err != nil {
+		return nil, err
+	}
+	if err := s.validateOverflow(0, input); err != nil {
+		return nil, err
+	}
+
+	department := &model.Department{
+		Name:                 input.Name,
+		Description:          input.Description,
+		FallbackDepartmentID: input.FallbackDepartmentID,
+		OverflowAfterSeconds: input.OverflowAfterSeconds,
+		Email:                strings.TrimSpace(input.Email),
+		Timezone:             "UTC",
+	}
+	if err := s.repo.Create(department); err != nil {
+		return nil, err
+	}
+	return department, nil
+}
+
+// Update replaces the department's settings
+func (s *DepartmentService) Update(adminId uint, departmentId int64, input DepartmentInput) (*model.Department, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findDepartment(departmentId); err != nil {
+		return nil, err
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := s.ensureNameFree(departmentId, input.Name); err != nil {
+		return nil, err
+	}
+	if err := s.validateOverflow(departmentId, input); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.Update(departmentId, map[string]interface{}{
+		"name":                   input.Name,
+		"description":            input.Description,
+		"fallback_department_id": input.FallbackDepartmentID,
+		"overflow_after_seconds": input.OverflowAfterSeconds,
+		"email":                  strings.TrimSpace(input.Email),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.findDepartment(departmentId)
+}
+
+// Delete removes the department; its waiting chats go back to the general queue
+func (s *DepartmentService) Delete(adminId uint, departmentId int64) error {
+	if err := s.requireAdmin(adminId); err != nil {
+		return err
+	}
+	if _, err := s.findDepartment(departmentId); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(departmentId, time.Now()); err != nil {
+		return err
+	}
+	return s.routing.RouteQueue(context.Background())
+}
+
+// AddAgent lets an agent answer chats for the department
+func (s *DepartmentService) AddAgent(adminId uint, departmentId int64, userId uint) (*model.DepartmentAgent, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findDepartment(departmentId); err != nil {
+		return nil, err
+	}
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAgent() {
+		return nil, ErrNotAgent
+	}
+	exists, err := s.repo.IsAgent(departmentId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAlreadyDepartmentAgent
+	}
+
+	agent := &model.DepartmentAgent{DepartmentID: uint(departmentId), UserID: userId}
+	if err := s.repo.AddAgent(agent); err != nil {
+		return nil, err
+	}
+	if err := s.routing.RouteQueue(context.Background()); err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+func (s *DepartmentService) RemoveAgent(adminId uint, departmentId int64, userId uint) error {
+	if err := s.requireAdmin(adminId); err != nil {
+		return err
+	}
+	removed, err := s.repo.RemoveAgent(departmentId, userId)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotDepartmentAgent
+	}
+	return nil
+}
+
+// AddRule adds an automatic routing rule on the visitor's page URL and/or language
+func (s *DepartmentService) AddRule(adminId uint, departmentId int64, pageURLContains, language string, priority int) (*model.DepartmentRule, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findDepartment(departmentId); err != nil {
+		return nil, err
+	}
+	pageURLContains = strings.TrimSpace(pageURLContains)
+	language = strings.TrimSpace(language)
+	if pageURLContains == "" && language == "" {
+		return nil, ErrEmptyDepartmentRule
+	}
+
+	rule := &model.DepartmentRule{
+		DepartmentID:    uint(departmentId),
+		PageURLContains: pageURLContains,
+		Language:        language,
+		Priority:        priority,
+	}
+	if err := s.repo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *DepartmentService) DeleteRule(adminId uint, departmentId, ruleId int64) error {
+	if err := s.requireAdmin(adminId); err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteRule(departmentId, ruleId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDepartmentRuleNotFound
+	}
+	return nil
+}
+
+// SetHours sets the department's timezone and replaces its weekly business hours.
+// An empty list keeps the department open around the clock.
+func (s *DepartmentService) SetHours(adminId uint, departmentId int64, timezone string, hours []BusinessHoursInput) (*model.Department, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findDepartment(departmentId); err != nil {
+		return nil, err
+	}
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, ErrInvalidTimezone
+	}
+
+	windows := make([]*model.BusinessHours, 0, len(hours))
+	for _, input := range hours {
+		if input.Weekday < 0 || input.Weekday > 6 || !validClock(input.OpensAt) || !validClock(input.ClosesAt) || input.OpensAt >= input.ClosesAt {
+			return nil, ErrInvalidBusinessHours
+		}
+		windows = append(windows, &model.BusinessHours{
+			DepartmentID: uint(departmentId),
+			Weekday:      input.Weekday,
+			OpensAt:      input.OpensAt,
+			ClosesAt:     input.ClosesAt,
+		})
+	}
+	if err := s.repo.ReplaceHours(departmentId, timezone, windows); err != nil {
+		return nil, err
+	}
+	return s.findDepartment(departmentId)
+}
+
+// AddHoliday closes the department for a whole day in its timezone
+func (s *DepartmentService) AddHoliday(adminId uint, departmentId int64, date, name string) (*model.Holiday, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findDepartment(departmentId); err != nil {
+		return nil, err
+	}
+	date = strings.TrimSpace(date)
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, ErrInvalidHolidayDate
+	}
+	exists, err := s.repo.HasHoliday(departmentId, date)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrHolidayExists
+	}
+
+	holiday := &model.Holiday{DepartmentID: uint(departmentId), Date: date, Name: strings.TrimSpace(name)}
+	if err := s.repo.CreateHoliday(holiday); err != nil {
+		return nil, err
+	}
+	return holiday, nil
+}
+
+func (s *DepartmentService) DeleteHoliday(adminId uint, departmentId, holidayId int64) error {
+	if err := s.requireAdmin(adminId); err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteHoliday(departmentId, holidayId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrHolidayNotFound
+	}
+	return nil
+}
+
+// Availability reports whether chats are taken right now. For a department this
+// follows its business hours; the general queue is open while any department is.
+func (s *DepartmentService) Availability(departmentId *uint, now time.Time) (*Availability, error) {
+	if departmentId != nil {
+		department, err := s.findDepartment(int64(*departmentId))
+		if err != nil {
+			return nil, err
+		}
+		return &Availability{
+			Online:       department.IsOpen(now),
+			DepartmentID: departmentId,
+			NextOpenAt:   department.NextOpening(now),
+		}, nil
+	}
+
+	departments, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(departments) == 0 {
+		return &Availability{Online: true}, nil
+	}
+	availability := &Availability{}
+	for _, department := range departments {
+		if department.IsOpen(now) {
+			return &Availability{Online: true}, nil
+		}
+		next := department.NextOpening(now)
+		if next != nil && (availability.NextOpenAt == nil || next.Before(*availability.NextOpenAt)) {
+			availability.NextOpenAt = next
+		}
+	}
+	return availability, nil
+}
+
+// Resolve picks the department for a new chat: the one the visitor selected, or
+// else the first rule matching their page URL and language. nil means the
+// general queue.
+func (s *DepartmentService) Resolve(departmentId *uint, pageURL, language string) (*uint, error) {
+	if departmentId != nil {
+		if _, err := s.findDepartment(int64(*departmentId)); err != nil {
+			return nil, err
+		}
+		return departmentId, nil
+	}
+
+	rules, err := s.repo.FindRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.Matches(pageURL, language) {
+			return &rule.DepartmentID, nil
+		}
+	}
+	return nil, nil
+}
+
+// validateOverflow checks the fallback exists and following fallbacks from it
+// never leads back to the department, so chats cannot bounce between departments
+func (s *DepartmentService) validateOverflow(departmentId int64, input DepartmentInput) error {
+	if input.OverflowAfterSeconds < 0 || (input.OverflowAfterSeconds > 0 && input.FallbackDepartmentID == nil) {
+		return ErrInvalidOverflow
+	}
+
+	seen := map[uint]bool{}
+	next := input.FallbackDepartmentID
+	for next != nil && !seen[*next] {
+		if int64(*next) == departmentId {
+			return ErrInvalidFallback
+		}
+		seen[*next] = true
+		department, err := s.repo.FindById(int64(*next))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidFallback
+			}
+			return err
+		}
+		next = department.FallbackDepartmentID
+	}
+	return nil
+}
+
+// validClock checks an "HH:MM" time of day; "24:00" is allowed as a closing time
+func validClock(clock string) bool {
+	if clock == "24:00" {
+		return true
+	}
+	parsed, err := time.Parse("15:04", clock)
+	return err == nil && parsed.Format("15:04") == clock
+}
+
+func (s *DepartmentService) ensureNameFree(departmentId int64, name string) error {
+	existing, err := s.repo.FindByName(name)
+	if err == nil && int64(existing.ID) != departmentId {
+		return ErrDepartmentExists
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *DepartmentService) requireAdmin(userId uint) error {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *DepartmentService) findDepartment(departmentId int64) (*model.Department, error) {
+	department, err := s.repo.FindById(departmentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+	return department, nil
+}
+
+func (s *DepartmentService) findUser(userId uint) (*model.User, error) {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -70,6 +70,105 @@ Livechat team`, userName)
 	return s.sendEmail(toEmail, subtle, body)
 }
 
+// SendConversationInviteEmail invites someone without an account to join a group
+func (s *EmailService) SendConversationInviteEmail(toEmail, inviterName, conversationName, inviteURL string) error {
+	subject := fmt.Sprintf("%s invited you to %s on LiveChat", inviterName, conversationName)
+	body := fmt.Sprintf(`Hello,
+
+%s has invited you to join the group "%s" on LiveChat.
+
+Create your account with this email address and you will be added automatically:
+%s
+
+Best regards,
+Livechat team`, inviterName, conversationName, inviteURL)
+
+	return s.sendEmail(toEmail, subject, body)
+}
+
+// SendContactRequestEmail tells a user someone wants to add them as a contact
+func (s *EmailService) SendContactRequestEmail(toEmail, userName, requesterName string) error {
+	subject := fmt.Sprintf("%s wants to connect on LiveChat", requesterName)
+	body := fmt.Sprintf(`Hello %s,
+
+%s sent you a contact request on LiveChat. Sign in to accept or decline it.
+
+Best regards,
+Livechat team`, userName, requesterName)
+
+	return s.sendEmail(toEmail, subject, body)
+}
+
+// SendContactAcceptedEmail tells a user their contact request was accepted
+func (s *EmailService) SendContactAcceptedEmail(toEmail, userName, accepterName string) error {
+	subject := fmt.Sprintf("%s accepted your contact request", accepterName)
+	body := fmt.Sprintf(`Hello %s,
+
+%s accepted your contact request. You can now chat with each other on LiveChat.
+
+Best regards,
+Livechat team`, userName, accepterName)
+
+	return s.sendEmail(toEmail, subject, body)
+}
+
+// OfflineMessageEmail is what a department is told about a message left while it was closed
+type OfflineMessageEmail struct {
+	ID             uint
+	DepartmentName string
+	VisitorName    string
+	VisitorEmail   string
+	PageURL        string
+	Message        string
+}
+
+// SendOfflineMessageEmail forwards a visitor's offline message to a department
+func (s *EmailService) SendOfflineMessageEmail(toEmail string, data OfflineMessageEmail) error {
+	subject := fmt.Sprintf("New offline message from %s", data.VisitorName)
+	body, err := renderEmailTemplate(offlineMessageEmailTemplate, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendHTMLEmail(toEmail, subject, body)
+}
+
+// RatingRequestEmail asks a visitor who left without rating their chat to rate it
+type RatingRequestEmail struct {
+	VisitorName string
+	AgentName   string
+	RatingURL   string
+}
+
+// SendRatingRequestEmail sends a visitor the link to rate a closed chat
+func (s *EmailService) SendRatingRequestEmail(toEmail string, data RatingRequestEmail) error {
+	subject := "How was your chat with us?"
+	body, err := renderEmailTemplate(ratingRequestEmailTemplate, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendHTMLEmail(toEmail, subject, body)
+}
+
+// TranscriptLinkEmail points a visitor to a transcript too long to send in the email itself
+type TranscriptLinkEmail struct {
+	Title       string
+	DownloadURL string
+	ExpiresAt   string
+}
+
+// SendTranscriptLinkEmail sends a visitor the download link of their chat transcript
+func (s *EmailService) SendTranscriptLinkEmail(toEmail string, data TranscriptLinkEmail) error {
+	subject := fmt.Sprintf("Transcript of your chat: %s", data.Title)
+	body, err := renderEmailTemplate(transcriptLinkEmailTemplate, data)
+	if err != nil {
+		return err
+	}
+
+	return s.SendHTMLEmail(toEmail, subject, body)
+}
+
 // sendEmail is the internal method that handles the actual sending
 func (s *EmailService) sendEmail(to, subject, body string) error {
 	m := mail.NewMessage()
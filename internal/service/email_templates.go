@@ -0,0 +1,43 @@
+package service
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// Email templates for messages that include text written by visitors or users.
+// html/template escapes that text, so it cannot inject markup into the email.
+
+var offlineMessageEmailTemplate = template.Must(template.New("offline_message").Parse(`<p>Hello,</p>
+<p>{{.VisitorName}} left a message for {{.DepartmentName}} while support was offline.</p>
+<table>
+  <tr><td><strong>From</strong></td><td>{{.VisitorName}} &lt;{{.VisitorEmail}}&gt;</td></tr>
+  {{if .PageURL}}<tr><td><strong>Page</strong></td><td>{{.PageURL}}</td></tr>{{end}}
+  <tr><td><strong>Reference</strong></td><td>#{{.ID}}</td></tr>
+</table>
+<blockquote style="white-space: pre-wrap">{{.Message}}</blockquote>
+<p>Reply to the visitor by email and mark the message as resolved once you have followed up.</p>
+<p>Best regards,<br>Livechat team</p>
+`))
+
+var ratingRequestEmailTemplate = template.Must(template.New("rating_request").Parse(`<p>Hello {{.VisitorName}},</p>
+<p>Thank you for chatting with us{{if .AgentName}} and with {{.AgentName}}{{end}}. How did we do?</p>
+<p><a href="{{.RatingURL}}">Rate your conversation</a></p>
+<p>It only takes a moment and helps us improve.</p>
+<p>Best regards,<br>Livechat team</p>
+`))
+
+var transcriptLinkEmailTemplate = template.Must(template.New("transcript_link").Parse(`<p>Hello,</p>
+<p>The transcript of your chat "{{.Title}}" is ready.</p>
+<p><a href="{{.DownloadURL}}">Download the transcript</a></p>
+<p>The link is valid until {{.ExpiresAt}}.</p>
+<p>Best regards,<br>Livechat team</p>
+`))
+
+func renderEmailTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
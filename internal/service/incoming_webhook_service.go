@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIncomingWebhookNotFound    = errors.New("incoming webhook not found")
+	ErrIncomingWebhookRevoked     = errors.New("incoming webhook is already revoked")
+	ErrInvalidIncomingWebhookName = errors.New("webhook name must be 1 to 100 characters")
+	ErrInvalidIncomingRateLimit   = errors.New("rate limit must be between 1 and 600 messages per minute")
+	ErrInvalidWebhookPayload      = errors.New("payload needs text of at most 4000 characters or up to 10 attachments")
+	ErrInvalidAttachment          = errors.New("attachments need an absolute http or https URL of at most 500 characters, a title of at most 200 and a text of at most 1000")
+	ErrWebhookRateLimited         = errors.New("rate limit exceeded")
+	ErrIncomingWebhooksSupport    = errors.New("incoming webhooks are not available in support chats")
+)
+
+const (
+	incomingWebhookTokenBytes     = 32
+	defaultIncomingWebhookLimit   = 30
+	maxIncomingWebhookLimit       = 600
+	incomingWebhookWindow         = time.Minute
+	incomingWebhookUsageRetention = time.Hour
+	incomingWebhookTouchInterval  = time.Minute
+	maxIncomingWebhookName        = 100
+	maxWebhookTextLength          = 4000
+	maxWebhookAttachments         = 10
+	maxAttachmentURLLength        = 500
+	maxAttachmentTitleLength      = 200
+	maxAttachmentTextLength       = 1000
+)
+
+// IncomingWebhookPayload is a message an external system posts through a webhook URL
+type IncomingWebhookPayload struct {
+	Text        string
+	Username    string // overrides the webhook's name as the sender for this message
+	Attachments []model.MessageAttachment
+}
+
+// IncomingWebhookService lets conversation managers hand out secret URLs that
+// external systems post messages to without signing in. The URL's token is only shown
+// when the webhook is created or its token regenerated.
+type IncomingWebhookService struct {
+	repo          *repository.IncomingWebhookRepository
+	conversations *ConversationService
+	appURL        string
+}
+
+func NewIncomingWebhookService(repo *repository.IncomingWebhookRepository, conversations *ConversationService) *IncomingWebhookService {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+
+	return &IncomingWebhookService{
+		repo:          repo,
+		conversations: conversations,
+		appURL:        strings.TrimRight(appURL, "/"),
+	}
+}
+
+// List returns the conversation's webhooks, revoked ones included. Managers only.
+func (s *IncomingWebhookService) List(actorId uint, conversationId int64) ([]*model.IncomingWebhook, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByConversation(conversationId)
+}
+
+// Create adds a webhook to the conversation and returns it with its URL. A zero
+// rate limit uses the default. Managers only.
+func (s *IncomingWebhookService) Create(actorId uint, conversationId int64, name string, rateLimit int) (*model.IncomingWebhook, string, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, "", err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxIncomingWebhookName {
+		return nil, "", ErrInvalidIncomingWebhookName
+	}
+	if rateLimit == 0 {
+		rateLimit = defaultIncomingWebhookLimit
+	}
+	if rateLimit < 1 || rateLimit > maxIncomingWebhookLimit {
+		return nil, "", ErrInvalidIncomingRateLimit
+	}
+
+	token, err := util.GenerateRandomToken(incomingWebhookTokenBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	hook := &model.IncomingWebhook{
+		ConversationID: uint(conversationId),
+		Name:           name,
+		TokenHash:      hashAPIKeySecret(token),
+		RateLimit:      rateLimit,
+		CreatedByID:    actorId,
+	}
+	if err := s.repo.Create(hook); err != nil {
+		return nil, "", err
+	}
+	return hook, s.hookURL(token), nil
+}
+
+// RegenerateToken gives the webhook a new URL; the old one stops working at once.
+// Managers only.
+func (s *IncomingWebhookService) RegenerateToken(actorId uint, conversationId, hookId int64) (string, error) {
+	if _, err := s.findHook(actorId, conversationId, hookId); err != nil {
+		return "", err
+	}
+	token, err := util.GenerateRandomToken(incomingWebhookTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	replaced, err := s.repo.ReplaceToken(hookId, hashAPIKeySecret(token))
+	if err != nil {
+		return "", err
+	}
+	if !replaced {
+		return "", ErrIncomingWebhookRevoked
+	}
+	return s.hookURL(token), nil
+}
+
+// Revoke disables the webhook's URL for good. Managers only.
+func (s *IncomingWebhookService) Revoke(actorId uint, conversationId, hookId int64) (*model.IncomingWebhook, error) {
+	hook, err := s.findHook(actorId, conversationId, hookId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	revoked, err := s.repo.Revoke(hookId, now)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrIncomingWebhookRevoked
+	}
+	hook.RevokedAt = &now
+	return hook, nil
+}
+
+// Post publishes the payload in the conversation of the webhook the token belongs to.
+// Unknown and revoked tokens both fail with ErrIncomingWebhookNotFound.
+func (s *IncomingWebhookService) Post(token string, payload IncomingWebhookPayload, now time.Time) (*model.Message, error) {
+	hook, err := s.repo.FindByTokenHash(hashAPIKeySecret(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, err
+	}
+	if hook.RevokedAt != nil {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	message, err := webhookMessage(hook, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fixed one-minute windows, counted in the database so every replica agrees
+	count, err := s.repo.Hit(hook.ID, now.Truncate(incomingWebhookWindow))
+	if err != nil {
+		return nil, err
+	}
+	if count > int64(hook.RateLimit) {
+		return nil, ErrWebhookRateLimited
+	}
+	if err := s.repo.TouchLastUsed(hook.ID, now, incomingWebhookTouchInterval); err != nil {
+		return nil, err
+	}
+
+	if err := s.conversations.postIntegrationMessage(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// RetryAfter returns how long a rate limited webhook has to wait before posting again
+func (s *IncomingWebhookService) RetryAfter(now time.Time) time.Duration {
+	return now.Truncate(incomingWebhookWindow).Add(incomingWebhookWindow).Sub(now)
+}
+
+// PurgeUsage deletes post counts of past rate limit windows. It runs
+// periodically as a background job.
+func (s *IncomingWebhookService) PurgeUsage(ctx context.Context) error {
+	return s.repo.PurgeUsage(time.Now().Add(-incomingWebhookUsageRetention))
+}
+
+func (s *IncomingWebhookService) findHook(actorId uint, conversationId, hookId int64) (*model.IncomingWebhook, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	hook, err := s.repo.FindById(hookId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIncomingWebhookNotFound
+		}
+		return nil, err
+	}
+	if hook.ConversationID != uint(conversationId) {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	return hook, nil
+}
+
+// authorizeManager checks that the actor may manage the conversation's webhooks:
+// an owner or admin of a group, or either participant of a direct conversation.
+// Support chats have none, since they are worked by whichever agent the chat is
+// routed to and posts from outside would bypass routing and SLA timers.
+func (s *IncomingWebhookService) authorizeManager(actorId uint, conversationId int64) error {
+	conversation, actor, err := s.conversations.authorize(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	if conversation.IsSupport() {
+		return ErrIncomingWebhooksSupport
+	}
+	if conversation.IsGroup() && !actor.CanManageMembers() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *IncomingWebhookService) hookURL(token string) string {
+	return s.appURL + "/api/hooks/" + token
+}
+
+// webhookMessage validates the payload and returns the message it describes
+func webhookMessage(hook *model.IncomingWebhook, payload IncomingWebhookPayload) (*model.Message, error) {
+	text := strings.TrimSpace(payload.Text)
+	if len(text) > maxWebhookTextLength || len(payload.Attachments) > maxWebhookAttachments {
+		return nil, ErrInvalidWebhookPayload
+	}
+	if text == "" && len(payload.Attachments) == 0 {
+		return nil, ErrInvalidWebhookPayload
+	}
+	attachments, err := normalizeAttachments(payload.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	senderName := strings.TrimSpace(payload.Username)
+	if senderName == "" || len(senderName) > maxIncomingWebhookName {
+		senderName = hook.Name
+	}
+	hookId := hook.ID
+	message := &model.Message{
+		ConversationID:    hook.ConversationID,
+		Type:              model.MessageTypeWebhook,
+		Content:           text,
+		IncomingWebhookID: &hookId,
+		SenderName:        senderName,
+	}
+	message.Attachments = attachments
+	return message, nil
+}
+
+// normalizeAttachments trims and validates linked attachments. It returns nil
+// for an empty list so messages without attachments store none.
+func normalizeAttachments(input []model.MessageAttachment) ([]model.MessageAttachment, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	attachments := make([]model.MessageAttachment, 0, len(input))
+	for _, attachment := range input {
+		attachment.URL = strings.TrimSpace(attachment.URL)
+		attachment.Title = strings.TrimSpace(attachment.Title)
+		attachment.Text = strings.TrimSpace(attachment.Text)
+		parsed, err := url.Parse(attachment.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" ||
+			len(attachment.URL) > maxAttachmentURLLength || len(attachment.Title) > maxAttachmentTitleLength || len(attachment.Text) > maxAttachmentTextLength {
+			return nil, ErrInvalidAttachment
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInviteNotFound        = errors.New("invite not found")
+	ErrInviteInvalid         = errors.New("invite has expired, been revoked or reached its usage limit")
+	ErrJoinRequestNotFound   = errors.New("join request not found")
+	ErrJoinRequestPending    = errors.New("a join request is already pending for this conversation")
+	ErrJoinRequestReviewed   = errors.New("join request has already been reviewed")
+	ErrInvalidInviteSettings = errors.New("expiry and max uses must not be negative")
+)
+
+const (
+	inviteCodeBytes       = 18
+	emailInviteTokenBytes = 32
+	emailInviteExpiry     = 7 * 24 * time.Hour
+)
+
+// InviteResult tells the caller whether joining through an invite added them
+// immediately or queued a join request for admin approval
+type InviteResult struct {
+	Member      *model.ConversationMember `json:"member,omitempty"`
+	JoinRequest *model.JoinRequest        `json:"join_request,omitempty"`
+}
+
+type InviteService struct {
+	repo          *repository.InviteRepository
+	conversations *ConversationService
+	userRepo      *repository.UserRepository
+	emailService  *EmailService
+	hub           *realtime.Hub
+	appURL        string
+}
+
+func NewInviteService(repo *repository.InviteRepository, conversations *ConversationService, userRepo *repository.UserRepository, hub *realtime.Hub) *InviteService {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+
+	return &InviteService{
+		repo:          repo,
+		conversations: conversations,
+		userRepo:      userRepo,
+		emailService:  NewEmailService(),
+		hub:           hub,
+		appURL:        strings.TrimRight(appURL, "/"),
+	}
+}
+
+// CreateInvite generates a new invite link for a group. A zero expiresIn never
+// expires and zero maxUses allows unlimited joins.
+func (s *InviteService) CreateInvite(actorId uint, conversationId int64, expiresIn time.Duration, maxUses int, requiresApproval bool) (*model.ConversationInvite, error) {
+	if expiresIn < 0 || maxUses < 0 {
+		return nil, ErrInvalidInviteSettings
+	}
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, err
+	}
+
+	code, err := util.GenerateRandomToken(inviteCodeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &model.ConversationInvite{
+		ConversationID:   uint(conversationId),
+		Code:             code,
+		CreatedBy:        actorId,
+		MaxUses:          maxUses,
+		RequiresApproval: requiresApproval,
+	}
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		invite.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.Create(invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (s *InviteService) ListInvites(actorId uint, conversationId int64) ([]*model.ConversationInvite, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByConversation(conversationId)
+}
+
+func (s *InviteService) RevokeInvite(actorId uint, conversationId int64, inviteId int64) error {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return err
+	}
+	invite, err := s.repo.FindById(inviteId)
+	if err != nil || invite.ConversationID != uint(conversationId) {
+		return ErrInviteNotFound
+	}
+	return s.repo.Revoke(inviteId)
+}
+
+// PreviewInvite returns the group behind a usable invite code without joining it
+func (s *InviteService) PreviewInvite(code string) (*model.ConversationInvite, *model.Conversation, int, error) {
+	invite, err := s.findUsableInvite(code)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	conversation, err := s.conversations.repo.FindById(int64(invite.ConversationID))
+	if err != nil {
+		return nil, nil, 0, ErrConversationNotFound
+	}
+	memberIds, err := s.conversations.repo.MemberIds(int64(invite.ConversationID))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return invite, conversation, len(memberIds), nil
+}
+
+// JoinByCode redeems an invite for the user. When the invite requires approval a
+// pending join request is queued for the group's admins instead; either way the
+// attempt counts towards the invite's max uses once it is stored.
+func (s *InviteService) JoinByCode(userId uint, code string) (*InviteResult, error) {
+	invite, err := s.findUsableInvite(code)
+	if err != nil {
+		return nil, err
+	}
+	conversationId := int64(invite.ConversationID)
+
+	if _, err := s.conversations.repo.FindMember(conversationId, userId); err == nil {
+		return nil, ErrAlreadyMember
+	}
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if invite.RequiresApproval {
+		if _, err := s.repo.FindPendingJoinRequest(conversationId, userId); err == nil {
+			return nil, ErrJoinRequestPending
+		}
+		request := &model.JoinRequest{
+			ConversationID: invite.ConversationID,
+			UserID:         userId,
+			InviteID:       invite.ID,
+			Status:         model.JoinRequestPending,
+		}
+		if err := s.redeem(invite, request); err != nil {
+			return nil, err
+		}
+		s.conversations.notifyManagers(conversationId, realtime.Event{Type: realtime.EventJoinRequested, Data: request})
+		return &InviteResult{JoinRequest: request}, nil
+	}
+
+	member := &model.ConversationMember{
+		ConversationID: invite.ConversationID,
+		UserID:         userId,
+		Role:           model.MemberRoleMember,
+		JoinedAt:       time.Now(),
+	}
+	if err := s.redeem(invite, member); err != nil {
+		return nil, err
+	}
+	if err := s.conversations.announceJoin(member, fmt.Sprintf("%s joined via invite link", user.Name)); err != nil {
+		return nil, err
+	}
+	return &InviteResult{Member: member}, nil
+}
+
+// redeem uses up one use of the invite together with storing the member or join
+// request, so a failed insert does not cost the invite a use
+func (s *InviteService) redeem(invite *model.ConversationInvite, record interface{}) error {
+	redeemed, err := s.repo.Redeem(invite.ID, record)
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return ErrInviteInvalid
+	}
+	return nil
+}
+
+func (s *InviteService) ListJoinRequests(actorId uint, conversationId int64) ([]*model.JoinRequest, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindJoinRequestsByStatus(conversationId, model.JoinRequestPending)
+}
+
+// ApproveJoinRequest admits the requester. The invite's use was already consumed
+// when the request was queued, so approval works even after the link expires.
+func (s *InviteService) ApproveJoinRequest(actorId uint, conversationId int64, requestId int64) (*model.ConversationMember, error) {
+	request, err := s.reviewableRequest(actorId, conversationId, requestId)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewed, err := s.repo.ReviewJoinRequest(requestId, model.JoinRequestApproved, actorId)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrJoinRequestReviewed
+	}
+	request.Status = model.JoinRequestApproved
+	request.ReviewedBy = actorId
+
+	actorUser, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+	requester, err := s.conversations.findUser(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.conversations.join(conversationId, request.UserID, fmt.Sprintf("%s approved %s's request to join", actorUser.Name, requester.Name))
+	if err != nil {
+		return nil, err
+	}
+	s.hub.PublishToUsers([]uint{request.UserID}, realtime.Event{Type: realtime.EventJoinRequestApproved, Data: request})
+	return member, nil
+}
+
+func (s *InviteService) DeclineJoinRequest(actorId uint, conversationId int64, requestId int64) error {
+	request, err := s.reviewableRequest(actorId, conversationId, requestId)
+	if err != nil {
+		return err
+	}
+
+	reviewed, err := s.repo.ReviewJoinRequest(requestId, model.JoinRequestDeclined, actorId)
+	if err != nil {
+		return err
+	}
+	if !reviewed {
+		return ErrJoinRequestReviewed
+	}
+	request.Status = model.JoinRequestDeclined
+	request.ReviewedBy = actorId
+
+	s.hub.PublishToUsers([]uint{request.UserID}, realtime.Event{Type: realtime.EventJoinRequestDeclined, Data: request})
+	return nil
+}
+
+// InviteByEmail adds an existing user straight away, or emails an invitation link
+// to an address without an account. The link's token is only sent to that address
+// and is redeemed with AcceptEmailInvite once the invitee has an account.
+func (s *InviteService) InviteByEmail(actorId uint, conversationId int64, email string) (*model.EmailInvite, []*model.ConversationMember, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, nil, err
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if user, err := s.userRepo.FindByEmail(email); err == nil {
+		members, err := s.conversations.AddMembers(actorId, conversationId, []uint{user.ID})
+		return nil, members, err
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	conversation, err := s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	inviter, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := util.GenerateRandomToken(emailInviteTokenBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	invite := &model.EmailInvite{
+		ConversationID: uint(conversationId),
+		Email:          email,
+		TokenHash:      hashAPIKeySecret(token),
+		InvitedBy:      actorId,
+		ExpiresAt:      time.Now().Add(emailInviteExpiry),
+	}
+	if err := s.repo.CreateEmailInvite(invite); err != nil {
+		return nil, nil, err
+	}
+
+	// Send invitation email (async to not block the response)
+	go func() {
+		registerURL := fmt.Sprintf("%s/register?email=%s&invite=%s", s.appURL, url.QueryEscape(email), url.QueryEscape(token))
+		if err := s.emailService.SendConversationInviteEmail(email, inviter.Name, conversation.Name, registerURL); err != nil {
+			println("Failed to send invite email: ", err.Error())
+		}
+	}()
+
+	return invite, nil, nil
+}
+
+// AcceptEmailInvite joins the user to the group an emailed invitation is for. The
+// token proves the user received the email; the user's account must also be
+// registered under the invited address.
+func (s *InviteService) AcceptEmailInvite(userId uint, token string) (*model.ConversationMember, error) {
+	invite, err := s.repo.FindEmailInviteByTokenHash(hashAPIKeySecret(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(strings.TrimSpace(user.Email), invite.Email) {
+		return nil, ErrInviteNotFound
+	}
+	now := time.Now()
+	if invite.AcceptedAt != nil || !invite.ExpiresAt.After(now) {
+		return nil, ErrInviteInvalid
+	}
+
+	// Join first so a failed join leaves the link usable
+	member, err := s.conversations.join(int64(invite.ConversationID), user.ID, fmt.Sprintf("%s joined from an email invitation", user.Name))
+	if err != nil && !errors.Is(err, ErrAlreadyMember) {
+		return nil, err
+	}
+	if _, markErr := s.repo.MarkEmailInviteAccepted(invite.ID, now); markErr != nil {
+		return nil, markErr
+	}
+	return member, err
+}
+
+func (s *InviteService) findUsableInvite(code string) (*model.ConversationInvite, error) {
+	invite, err := s.repo.FindByCode(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+	if !invite.IsUsable(time.Now()) {
+		return nil, ErrInviteInvalid
+	}
+	return invite, nil
+}
+
+// authorizeManager checks that the actor is an owner or admin of the group
+func (s *InviteService) authorizeManager(actorId uint, conversationId int64) error {
+	_, actor, err := s.conversations.authorizeGroup(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManageMembers() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *InviteService) reviewableRequest(actorId uint, conversationId int64, requestId int64) (*model.JoinRequest, error) {
+	if err := s.authorizeManager(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	request, err := s.repo.FindJoinRequest(requestId)
+	if err != nil || request.ConversationID != uint(conversationId) {
+		return nil, ErrJoinRequestNotFound
+	}
+	if request.Status != model.JoinRequestPending {
+		return nil, ErrJoinRequestReviewed
+	}
+	return request, nil
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOfflineMessageNotFound = errors.New("offline message not found")
+	ErrInvalidOfflineStatus   = errors.New("status must be open or resolved")
+)
+
+const (
+	defaultOfflineMessagePageSize = 50
+	maxOfflineMessagePageSize     = 100
+)
+
+// OfflineMessageInput is the form a visitor fills in while support is closed
+type OfflineMessageInput struct {
+	DepartmentID *uint
+	Name         string
+	Email        string
+	Message      string
+}
+
+// OfflineMessageService collects messages visitors leave outside business hours
+// and forwards them to the department by email
+type OfflineMessageService struct {
+	repo         *repository.OfflineMessageRepository
+	deptRepo     *repository.DepartmentRepository
+	visitorRepo  *repository.VisitorRepository
+	userRepo     *repository.UserRepository
+	departments  *DepartmentService
+	hub          *realtime.Hub
+	emailService *EmailService
+}
+
+func NewOfflineMessageService(repo *repository.OfflineMessageRepository, deptRepo *repository.DepartmentRepository, visitorRepo *repository.VisitorRepository, userRepo *repository.UserRepository, departments *DepartmentService, hub *realtime.Hub) *OfflineMessageService {
+	return &OfflineMessageService{
+		repo:         repo,
+		deptRepo:     deptRepo,
+		visitorRepo:  visitorRepo,
+		userRepo:     userRepo,
+		departments:  departments,
+		hub:          hub,
+		emailService: NewEmailService(),
+	}
+}
+
+// Submit records a visitor's offline message for the department they picked, or
+// the one matched by the department rules, and emails it to that department
+func (s *OfflineMessageService) Submit(visitorId uint, input OfflineMessageInput) (*model.OfflineMessage, error) {
+	input.Message = strings.TrimSpace(input.Message)
+	if input.Message == "" {
+		return nil, ErrEmptyMessage
+	}
+	visitor, err := s.visitorRepo.FindById(visitorId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVisitorNotFound
+		}
+		return nil, err
+	}
+	departmentId, err := s.departments.Resolve(input.DepartmentID, visitor.PageURL, visitor.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = visitor.Name
+	}
+	message := &model.OfflineMessage{
+		DepartmentID: departmentId,
+		VisitorID:    visitor.ID,
+		Name:         name,
+		Email:        strings.TrimSpace(input.Email),
+		Message:      input.Message,
+		PageURL:      visitor.PageURL,
+		Status:       model.OfflineMessageOpen,
+	}
+	if err := s.repo.Create(message); err != nil {
+		return nil, err
+	}
+
+	// Remember the contact details the visitor gave for later chats
+	fields := map[string]interface{}{"last_seen_at": time.Now()}
+	if visitor.Name == "" && name != "" {
+		fields["name"] = name
+	}
+	if visitor.Email == "" {
+		fields["email"] = message.Email
+	}
+	if err := s.visitorRepo.Update(visitor.ID, fields); err != nil {
+		return nil, err
+	}
+
+	if err := s.deliver(message, visitor); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// List returns a page of offline messages, newest first. Agents and admins only.
+func (s *OfflineMessageService) List(actorId uint, status string, departmentId *uint, beforeId int64, limit int) ([]*model.OfflineMessage, error) {
+	if status != "" && status != model.OfflineMessageOpen && status != model.OfflineMessageResolved {
+		return nil, ErrInvalidOfflineStatus
+	}
+	if err := s.requireStaff(actorId); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultOfflineMessagePageSize
+	}
+	if limit > maxOfflineMessagePageSize {
+		limit = maxOfflineMessagePageSize
+	}
+	return s.repo.Find(status, departmentId, beforeId, limit)
+}
+
+func (s *OfflineMessageService) Get(actorId uint, id int64) (*model.OfflineMessage, error) {
+	if err := s.requireStaff(actorId); err != nil {
+		return nil, err
+	}
+	return s.findMessage(id)
+}
+
+// SetStatus resolves an offline message once an agent has followed up, or reopens it
+func (s *OfflineMessageService) SetStatus(actorId uint, id int64, status string) (*model.OfflineMessage, error) {
+	if status != model.OfflineMessageOpen && status != model.OfflineMessageResolved {
+		return nil, ErrInvalidOfflineStatus
+	}
+	if err := s.requireStaff(actorId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findMessage(id); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{"resolved_by_id": nil, "resolved_at": nil}
+	if status == model.OfflineMessageResolved {
+		fields["resolved_by_id"] = actorId
+		fields["resolved_at"] = time.Now()
+	}
+	if _, err := s.repo.UpdateStatus(id, status, fields); err != nil {
+		return nil, err
+	}
+	return s.findMessage(id)
+}
+
+// deliver emails the message to the department's address, or to its agents when it
+// has none. Messages for the general queue go to the admins.
+func (s *OfflineMessageService) deliver(message *model.OfflineMessage, visitor *model.Visitor) error {
+	departmentName := "Support"
+	var recipients []string
+	var userIds []uint
+	var err error
+	if message.DepartmentID != nil {
+		department, err := s.deptRepo.FindById(int64(*message.DepartmentID))
+		if err != nil {
+			return err
+		}
+		departmentName = department.Name
+		if department.Email != "" {
+			recipients = append(recipients, department.Email)
+		}
+		if userIds, err = s.deptRepo.AgentIds(int64(department.ID)); err != nil {
+			return err
+		}
+	} else if userIds, err = s.userRepo.IdsByRole(model.UserRoleAdmin); err != nil {
+		return err
+	}
+
+	if len(recipients) == 0 {
+		users, err := s.userRepo.FindByIds(userIds)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			recipients = append(recipients, user.Email)
+		}
+	}
+	s.hub.PublishToUsers(userIds, realtime.Event{Type: realtime.EventOfflineMessageReceived, Data: message})
+
+	data := OfflineMessageEmail{
+		ID:             message.ID,
+		DepartmentName: departmentName,
+		VisitorName:    message.Name,
+		VisitorEmail:   message.Email,
+		PageURL:        message.PageURL,
+		Message:        message.Message,
+	}
+	if data.VisitorName == "" {
+		data.VisitorName = visitor.DisplayName()
+	}
+
+	// Send notification emails (async to not block the response)
+	go func() {
+		for _, recipient := range recipients {
+			if err := s.emailService.SendOfflineMessageEmail(recipient, data); err != nil {
+				println("Failed to send offline message email: ", err.Error())
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *OfflineMessageService) findMessage(id int64) (*model.OfflineMessage, error) {
+	message, err := s.repo.FindById(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOfflineMessageNotFound
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *OfflineMessageService) requireStaff(userId uint) error {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsStaff() {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAlreadyPinned  = errors.New("message is already pinned")
+	ErrNotPinned      = errors.New("message is not pinned")
+	ErrPinLimit       = errors.New("this conversation has reached the maximum number of pinned messages")
+	ErrCannotPinEvent = errors.New("system messages cannot be pinned")
+)
+
+const (
+	maxPinnedMessages = 25
+	pinPreviewLength  = 50
+)
+
+type PinService struct {
+	repo          *repository.PinRepository
+	conversations *ConversationService
+	hub           *realtime.Hub
+}
+
+func NewPinService(repo *repository.PinRepository, conversations *ConversationService, hub *realtime.Hub) *PinService {
+	return &PinService{
+		repo:          repo,
+		conversations: conversations,
+		hub:           hub,
+	}
+}
+
+// Pin pins a message for everyone in the conversation. In groups only owners and
+// admins may pin; in direct conversations either participant may.
+func (s *PinService) Pin(actorId uint, conversationId int64, messageId int64) (*model.PinnedMessage, error) {
+	if err := s.authorizePinning(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	message, err := s.conversations.findMessage(conversationId, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if message.Type == model.MessageTypeSystem {
+		return nil, ErrCannotPinEvent
+	}
+	if message.Internal {
+		return nil, ErrInternalMessage
+	}
+
+	if _, err := s.repo.FindByMessage(messageId); err == nil {
+		return nil, ErrAlreadyPinned
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	count, err := s.repo.CountByConversation(conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxPinnedMessages {
+		return nil, ErrPinLimit
+	}
+
+	pin := &model.PinnedMessage{
+		ConversationID: uint(conversationId),
+		MessageID:      message.ID,
+		PinnedBy:       actorId,
+	}
+	if err := s.repo.Create(pin); err != nil {
+		return nil, err
+	}
+	pin.Message = message
+
+	actor, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.conversations.broadcast(conversationId, realtime.Event{Type: realtime.EventMessagePinned, Data: pin})
+	if err := s.conversations.postSystemMessage(conversationId, fmt.Sprintf("%s pinned a message: \"%s\"", actor.Name, preview(message.Content))); err != nil {
+		return nil, err
+	}
+	return pin, nil
+}
+
+func (s *PinService) Unpin(actorId uint, conversationId int64, messageId int64) error {
+	if err := s.authorizePinning(actorId, conversationId); err != nil {
+		return err
+	}
+	message, err := s.conversations.findMessage(conversationId, messageId)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.repo.DeleteByMessage(messageId)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotPinned
+	}
+
+	actor, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return err
+	}
+
+	s.conversations.broadcast(conversationId, realtime.Event{
+		Type: realtime.EventMessageUnpinned,
+		Data: map[string]interface{}{"conversation_id": conversationId, "message_id": messageId, "unpinned_by": actorId},
+	})
+	return s.conversations.postSystemMessage(conversationId, fmt.Sprintf("%s unpinned a message: \"%s\"", actor.Name, preview(message.Content)))
+}
+
+// ListPinned returns the conversation's pinned messages, newest pin first
+func (s *PinService) ListPinned(userId uint, conversationId int64) ([]*model.PinnedMessage, error) {
+	if _, _, err := s.conversations.authorize(userId, conversationId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByConversation(conversationId)
+}
+
+func (s *PinService) authorizePinning(actorId uint, conversationId int64) error {
+	conversation, member, err := s.conversations.authorize(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	if conversation.IsGroup() && !member.CanManageMembers() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// preview shortens message content for use inside system messages
+func preview(content string) string {
+	if utf8.RuneCountInString(content) <= pinPreviewLength {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[:pinPreviewLength]) + "…"
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPollNotFound     = errors.New("poll not found")
+	ErrPollClosed       = errors.New("this poll is closed")
+	ErrInvalidPoll      = errors.New("a poll needs a question of at most 300 characters and 2 to 10 distinct options of at most 100 characters")
+	ErrInvalidPollClose = errors.New("close time must be in the future")
+	ErrInvalidVote      = errors.New("votes must be options of this poll, and exactly one in a single choice poll")
+	ErrPollsUnsupported = errors.New("polls are not available in support chats")
+)
+
+const (
+	maxPollQuestionLength = 300
+	minPollOptions        = 2
+	maxPollOptions        = 10
+	maxPollOptionLength   = 100
+	pollCloseBatchSize    = 50
+)
+
+// PollInput holds the settings of a new poll
+type PollInput struct {
+	Question       string
+	Options        []string
+	MultipleChoice bool
+	Anonymous      bool
+	ClosesAt       *time.Time // nil for a poll that stays open until closed by hand
+}
+
+// PollOptionResult is an option with its tally
+type PollOptionResult struct {
+	*model.PollOption
+	Votes  int64  `json:"votes"`
+	Voters []uint `json:"voters,omitempty"` // named polls only
+}
+
+// PollResults is a poll with its current tallies
+type PollResults struct {
+	Poll        *model.Poll         `json:"poll"`
+	Options     []*PollOptionResult `json:"options"`
+	TotalVoters int64               `json:"total_voters"`
+	MyVotes     []uint              `json:"my_votes,omitempty"` // the caller's options; not sent in live updates
+}
+
+// PollService runs polls posted in conversations. Tallies are counted from the
+// stored votes on every read, so concurrent votes can never lose an update, and
+// members get the new tallies pushed after each vote.
+type PollService struct {
+	repo          *repository.PollRepository
+	conversations *ConversationService
+}
+
+func NewPollService(repo *repository.PollRepository, conversations *ConversationService) *PollService {
+	return &PollService{
+		repo:          repo,
+		conversations: conversations,
+	}
+}
+
+// Create posts a poll message in the conversation
+func (s *PollService) Create(userId uint, conversationId int64, input PollInput) (*PollResults, error) {
+	conversation, err := s.conversations.authorizeSend(userId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.IsSupport() {
+		return nil, ErrPollsUnsupported
+	}
+	question := strings.TrimSpace(input.Question)
+	if question == "" || len(question) > maxPollQuestionLength {
+		return nil, ErrInvalidPoll
+	}
+	if len(input.Options) < minPollOptions || len(input.Options) > maxPollOptions {
+		return nil, ErrInvalidPoll
+	}
+	if input.ClosesAt != nil && !input.ClosesAt.After(time.Now()) {
+		return nil, ErrInvalidPollClose
+	}
+
+	seen := map[string]bool{}
+	options := make([]*model.PollOption, 0, len(input.Options))
+	for i, text := range input.Options {
+		text = strings.TrimSpace(text)
+		key := strings.ToLower(text)
+		if text == "" || len(text) > maxPollOptionLength || seen[key] {
+			return nil, ErrInvalidPoll
+		}
+		seen[key] = true
+		options = append(options, &model.PollOption{Position: i, Text: text})
+	}
+
+	poll := &model.Poll{
+		ConversationID: conversation.ID,
+		CreatorID:      userId,
+		Question:       question,
+		MultipleChoice: input.MultipleChoice,
+		Anonymous:      input.Anonymous,
+		ClosesAt:       input.ClosesAt,
+	}
+	message := &model.Message{
+		ConversationID: conversation.ID,
+		SenderID:       userId,
+		Type:           model.MessageTypePoll,
+		Content:        question,
+	}
+	if err := s.repo.Create(poll, options, message); err != nil {
+		return nil, err
+	}
+	if err := s.conversations.announce(message); err != nil {
+		return nil, err
+	}
+	return s.results(poll, 0)
+}
+
+// Get returns the poll's tallies and the caller's votes
+func (s *PollService) Get(userId uint, conversationId, pollId int64) (*PollResults, error) {
+	poll, _, err := s.findPoll(userId, conversationId, pollId)
+	if err != nil {
+		return nil, err
+	}
+	return s.results(poll, userId)
+}
+
+// Vote replaces the caller's votes with optionIds and pushes the new tallies to
+// the members
+func (s *PollService) Vote(userId uint, conversationId, pollId int64, optionIds []uint) (*PollResults, error) {
+	poll, _, err := s.findPoll(userId, conversationId, pollId)
+	if err != nil {
+		return nil, err
+	}
+	options, err := s.repo.FindOptions(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	valid := make(map[uint]bool, len(options))
+	for _, option := range options {
+		valid[option.ID] = true
+	}
+	seen := map[uint]bool{}
+	votes := make([]uint, 0, len(optionIds))
+	for _, id := range optionIds {
+		if !valid[id] {
+			return nil, ErrInvalidVote
+		}
+		if !seen[id] {
+			seen[id] = true
+			votes = append(votes, id)
+		}
+	}
+	if len(votes) == 0 || (!poll.MultipleChoice && len(votes) > 1) {
+		return nil, ErrInvalidVote
+	}
+	return s.cast(poll, userId, votes)
+}
+
+// Retract withdraws the caller's votes while the poll is open
+func (s *PollService) Retract(userId uint, conversationId, pollId int64) (*PollResults, error) {
+	poll, _, err := s.findPoll(userId, conversationId, pollId)
+	if err != nil {
+		return nil, err
+	}
+	return s.cast(poll, userId, nil)
+}
+
+// Close ends the poll early. Only its creator and group owners and admins may close it.
+func (s *PollService) Close(userId uint, conversationId, pollId int64) (*PollResults, error) {
+	poll, member, err := s.findPoll(userId, conversationId, pollId)
+	if err != nil {
+		return nil, err
+	}
+	if poll.CreatorID != userId && !member.CanManageMembers() {
+		return nil, ErrForbidden
+	}
+	results, err := s.close(poll, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		return nil, ErrPollClosed
+	}
+	return results, nil
+}
+
+// CloseDue closes polls whose close time has passed and pushes their final
+// tallies. It runs periodically as a background job; the conditional close lets
+// replicas run it side by side.
+func (s *PollService) CloseDue(ctx context.Context) error {
+	now := time.Now()
+	polls, err := s.repo.FindDueToClose(now, pollCloseBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, poll := range polls {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.close(poll, *poll.ClosesAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cast stores the user's votes and pushes the new tallies
+func (s *PollService) cast(poll *model.Poll, userId uint, optionIds []uint) (*PollResults, error) {
+	open, err := s.repo.Vote(poll.ID, userId, optionIds, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !open {
+		return nil, ErrPollClosed
+	}
+	results, err := s.results(poll, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.conversations.broadcast(int64(poll.ConversationID), realtime.Event{Type: realtime.EventPollUpdated, Data: results})
+
+	mine := *results
+	mine.MyVotes = optionIds
+	return &mine, nil
+}
+
+// close marks the poll closed and pushes its final tallies. It returns nil
+// results when the poll was already closed.
+func (s *PollService) close(poll *model.Poll, closedAt time.Time) (*PollResults, error) {
+	closed, err := s.repo.Close(poll.ID, closedAt)
+	if err != nil || !closed {
+		return nil, err
+	}
+	poll.ClosedAt = &closedAt
+	results, err := s.results(poll, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.conversations.broadcast(int64(poll.ConversationID), realtime.Event{Type: realtime.EventPollClosed, Data: results})
+	return results, nil
+}
+
+// results tallies the poll. Voters are listed for named polls only; the votes of
+// userId are included when it is set.
+func (s *PollService) results(poll *model.Poll, userId uint) (*PollResults, error) {
+	options, err := s.repo.FindOptions(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	results := &PollResults{Poll: poll, Options: make([]*PollOptionResult, 0, len(options))}
+	byOption := make(map[uint]*PollOptionResult, len(options))
+	for _, option := range options {
+		result := &PollOptionResult{PollOption: option}
+		results.Options = append(results.Options, result)
+		byOption[option.ID] = result
+	}
+
+	if poll.Anonymous {
+		counts, err := s.repo.CountVotes(poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		for optionId, count := range counts {
+			if result, ok := byOption[optionId]; ok {
+				result.Votes = count
+			}
+		}
+		if results.TotalVoters, err = s.repo.CountVoters(poll.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		votes, err := s.repo.FindVotes(poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		voters := map[uint]bool{}
+		for _, vote := range votes {
+			if result, ok := byOption[vote.OptionID]; ok {
+				result.Votes++
+				result.Voters = append(result.Voters, vote.UserID)
+			}
+			voters[vote.UserID] = true
+		}
+		results.TotalVoters = int64(len(voters))
+	}
+
+	if userId != 0 {
+		if results.MyVotes, err = s.repo.FindUserVotes(poll.ID, userId); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// findPoll checks the caller is a member and loads a poll of the conversation
+func (s *PollService) findPoll(userId uint, conversationId, pollId int64) (*model.Poll, *model.ConversationMember, error) {
+	_, member, err := s.conversations.authorize(userId, conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	poll, err := s.repo.FindById(pollId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPollNotFound
+		}
+		return nil, nil, err
+	}
+	if poll.ConversationID != uint(conversationId) {
+		return nil, nil, ErrPollNotFound
+	}
+	return poll, member, nil
+}
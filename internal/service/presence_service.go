@@ -0,0 +1,48 @@
+package service
+
+import (
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+)
+
+// Presence statuses
+const (
+	PresenceOnline  = "online"
+	PresenceOffline = "offline"
+)
+
+type PresenceService struct {
+	hub       *realtime.Hub
+	blockRepo *repository.BlockRepository
+}
+
+func NewPresenceService(hub *realtime.Hub, blockRepo *repository.BlockRepository) *PresenceService {
+	return &PresenceService{
+		hub:       hub,
+		blockRepo: blockRepo,
+	}
+}
+
+// Presence returns the status of each requested user as seen by the viewer. A user
+// is online while they hold an open event stream; users with a block between them
+// and the viewer always appear offline.
+func (s *PresenceService) Presence(viewerId uint, userIds []uint) (map[uint]string, error) {
+	related, err := s.blockRepo.RelatedIds(viewerId)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[uint]bool, len(related))
+	for _, id := range related {
+		hidden[id] = true
+	}
+
+	statuses := make(map[uint]string, len(userIds))
+	for _, id := range userIds {
+		if !hidden[id] && s.hub.IsOnline(realtime.UserTopic(id)) {
+			statuses[id] = PresenceOnline
+		} else {
+			statuses[id] = PresenceOffline
+		}
+	}
+	return statuses, nil
+}
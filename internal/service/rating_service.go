@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidScore      = errors.New("score must be between 1 and 5")
+	ErrChatNotClosed     = errors.New("a chat can only be rated once it is closed")
+	ErrAlreadyRated      = errors.New("this chat has already been rated")
+	ErrInvalidRatingLink = errors.New("rating link is invalid or expired")
+	ErrInvalidDateRange  = errors.New("from must be before to and the range at most one year")
+)
+
+const (
+	ratingLinkTTL        = 7 * 24 * time.Hour
+	ratingRequestBatch   = 50
+	maxReportRange       = 366 * 24 * time.Hour
+	defaultRatingPage    = 50
+	maxRatingPage        = 100
+	ratingRequestMaxWait = 3 * 24 * time.Hour // older chats are never asked for a rating
+)
+
+// RatingFilter selects the ratings of a period, optionally for one agent or department
+type RatingFilter struct {
+	From         time.Time
+	To           time.Time
+	AgentID      *uint
+	DepartmentID *uint
+}
+
+func (f RatingFilter) query() repository.RatingFilter {
+	return repository.RatingFilter{From: f.From, To: f.To, AgentID: f.AgentID, DepartmentID: f.DepartmentID}
+}
+
+// RatingStats summarizes the ratings of one agent or department
+type RatingStats struct {
+	Count            int64   `json:"count"`
+	Average          float64 `json:"average"`
+	SatisfactionRate float64 `json:"satisfaction_rate"` // share of ratings of 4 or 5
+}
+
+// AgentRatingStats are the ratings of chats an agent handled. Agent is nil for
+// chats that were closed before reaching an agent.
+type AgentRatingStats struct {
+	Agent *UserSummary `json:"agent"`
+	RatingStats
+}
+
+// DepartmentRatingStats are the ratings of chats of a department. DepartmentID is
+// nil for the general queue.
+type DepartmentRatingStats struct {
+	DepartmentID   *uint  `json:"department_id"`
+	DepartmentName string `json:"department_name"`
+	RatingStats
+}
+
+// RatingRequest is what the rating page shows about the chat a link is for
+type RatingRequest struct {
+	ConversationID uint       `json:"conversation_id"`
+	AgentName      string     `json:"agent_name,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	Rated          bool       `json:"rated"`
+}
+
+// RatingService collects post-chat satisfaction ratings and reports on them
+type RatingService struct {
+	repo          *repository.RatingRepository
+	conversations *ConversationService
+	visitorRepo   *repository.VisitorRepository
+	deptRepo      *repository.DepartmentRepository
+	emailService  *EmailService
+	appURL        string
+	requestDelay  time.Duration // 0 disables rating request emails
+}
+
+func NewRatingService(repo *repository.RatingRepository, conversations *ConversationService, visitorRepo *repository.VisitorRepository, deptRepo *repository.DepartmentRepository) *RatingService {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+	// RATING_REQUEST_DELAY is how long after a chat closes the visitor is emailed
+	// a rating link if they have not rated it yet, e.g. "30m"
+	requestDelay, err := time.ParseDuration(os.Getenv("RATING_REQUEST_DELAY"))
+	if err != nil || requestDelay < 0 {
+		requestDelay = 0
+	}
+	return &RatingService{
+		repo:          repo,
+		conversations: conversations,
+		visitorRepo:   visitorRepo,
+		deptRepo:      deptRepo,
+		emailService:  NewEmailService(),
+		appURL:        strings.TrimRight(appURL, "/"),
+		requestDelay:  requestDelay,
+	}
+}
+
+// Rate stores the visitor's rating of one of their closed chats
+func (s *RatingService) Rate(visitorId uint, conversationId int64, score int, comment string) (*model.ChatRating, error) {
+	conversation, err := s.findConversation(conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.VisitorID == nil || *conversation.VisitorID != visitorId {
+		return nil, ErrConversationNotFound
+	}
+	return s.rate(conversation, score, comment)
+}
+
+// RatingRequest describes the chat an emailed rating link is for
+func (s *RatingService) RatingRequest(token string) (*RatingRequest, error) {
+	conversation, err := s.conversationForLink(token)
+	if err != nil {
+		return nil, err
+	}
+	request := &RatingRequest{ConversationID: conversation.ID, ClosedAt: conversation.ClosedAt}
+	if conversation.AssignedAgentID != nil {
+		if agent, err := s.conversations.findUser(*conversation.AssignedAgentID); err == nil {
+			request.AgentName = agent.Name
+		}
+	}
+	if _, err := s.repo.FindByConversation(int64(conversation.ID)); err == nil {
+		request.Rated = true
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return request, nil
+}
+
+// RateWithLink stores a rating given through an emailed rating link
+func (s *RatingService) RateWithLink(token string, score int, comment string) (*model.ChatRating, error) {
+	conversation, err := s.conversationForLink(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.rate(conversation, score, comment)
+}
+
+// List returns a page of ratings with their comments, newest first. Admin only.
+func (s *RatingService) List(actorId uint, filter RatingFilter, beforeId int64, limit int) ([]*model.ChatRating, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if err := validateDateRange(filter.From, filter.To); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultRatingPage
+	}
+	if limit > maxRatingPage {
+		limit = maxRatingPage
+	}
+	return s.repo.Find(filter.query(), beforeId, limit)
+}
+
+// AgentStats returns the rating summary of every agent over the period. Admin only.
+func (s *RatingService) AgentStats(actorId uint, filter RatingFilter) ([]*AgentRatingStats, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if err := validateDateRange(filter.From, filter.To); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.AggregateByAgent(filter.query())
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, row := range rows {
+		if row.GroupID != nil {
+			ids = append(ids, *row.GroupID)
+		}
+	}
+	users, err := s.conversations.userRepo.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	usersById := make(map[uint]*model.User, len(users))
+	for _, user := range users {
+		usersById[user.ID] = user
+	}
+
+	stats := make([]*AgentRatingStats, 0, len(rows))
+	for _, row := range rows {
+		entry := &AgentRatingStats{RatingStats: newRatingStats(row)}
+		if row.GroupID != nil {
+			if user, ok := usersById[*row.GroupID]; ok {
+				summary := newUserSummary(user)
+				entry.Agent = &summary
+			} else {
+				entry.Agent = &UserSummary{ID: *row.GroupID}
+			}
+		}
+		stats = append(stats, entry)
+	}
+	return stats, nil
+}
+
+// DepartmentStats returns the rating summary of every department over the period. Admin only.
+func (s *RatingService) DepartmentStats(actorId uint, filter RatingFilter) ([]*DepartmentRatingStats, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if err := validateDateRange(filter.From, filter.To); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.AggregateByDepartment(filter.query())
+	if err != nil {
+		return nil, err
+	}
+	departments, err := s.deptRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	departmentsById := make(map[uint]*model.Department, len(departments))
+	for _, department := range departments {
+		departmentsById[department.ID] = department
+	}
+
+	stats := make([]*DepartmentRatingStats, 0, len(rows))
+	for _, row := range rows {
+		entry := &DepartmentRatingStats{DepartmentID: row.GroupID, RatingStats: newRatingStats(row)}
+		if row.GroupID != nil {
+			if department, ok := departmentsById[*row.GroupID]; ok {
+				entry.DepartmentName = department.Name
+			}
+		}
+		stats = append(stats, entry)
+	}
+	return stats, nil
+}
+
+// SendRatingRequests emails a rating link to visitors who left a closed chat
+// without rating it. Each chat is claimed with a conditional update first, so
+// concurrent server replicas never email the same visitor twice. It runs as a
+// background job and does nothing unless RATING_REQUEST_DELAY is set.
+func (s *RatingService) SendRatingRequests(ctx context.Context) error {
+	if s.requestDelay == 0 {
+		return nil
+	}
+	now := time.Now()
+	due, err := s.conversations.repo.FindRatingRequestsDue(now.Add(-ratingRequestMaxWait), now.Add(-s.requestDelay), ratingRequestBatch)
+	if err != nil {
+		return err
+	}
+	for _, conversation := range due {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		claimed, err := s.conversations.repo.MarkRatingRequested(int64(conversation.ID), now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.sendRatingRequest(conversation); err != nil {
+			// The chat stays marked; a visitor is asked at most once
+			println("Failed to send rating request email: ", err.Error())
+		}
+	}
+	return nil
+}
+
+func (s *RatingService) sendRatingRequest(conversation *model.Conversation) error {
+	visitor, err := s.visitorRepo.FindById(*conversation.VisitorID)
+	if err != nil {
+		return err
+	}
+	token, err := util.GenerateRatingToken(conversation.ID, ratingLinkTTL)
+	if err != nil {
+		return err
+	}
+	data := RatingRequestEmail{
+		VisitorName: visitor.DisplayName(),
+		RatingURL:   fmt.Sprintf("%s/rate?token=%s", s.appURL, url.QueryEscape(token)),
+	}
+	if conversation.AssignedAgentID != nil {
+		if agent, err := s.conversations.findUser(*conversation.AssignedAgentID); err == nil {
+			data.AgentName = agent.Name
+		}
+	}
+	return s.emailService.SendRatingRequestEmail(visitor.Email, data)
+}
+
+func (s *RatingService) rate(conversation *model.Conversation, score int, comment string) (*model.ChatRating, error) {
+	if score < 1 || score > 5 {
+		return nil, ErrInvalidScore
+	}
+	if !conversation.IsSupport() || conversation.VisitorID == nil {
+		return nil, ErrNotSupportChat
+	}
+	if !conversation.IsClosed() {
+		return nil, ErrChatNotClosed
+	}
+	if _, err := s.repo.FindByConversation(int64(conversation.ID)); err == nil {
+		return nil, ErrAlreadyRated
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	rating := &model.ChatRating{
+		ConversationID: conversation.ID,
+		VisitorID:      *conversation.VisitorID,
+		AgentID:        conversation.AssignedAgentID,
+		DepartmentID:   conversation.DepartmentID,
+		Score:          score,
+		Comment:        strings.TrimSpace(comment),
+	}
+	if err := s.repo.Create(rating); err != nil {
+		return nil, err
+	}
+	return rating, nil
+}
+
+func (s *RatingService) conversationForLink(token string) (*model.Conversation, error) {
+	claims, err := util.ValidateRatingToken(token)
+	if err != nil {
+		return nil, ErrInvalidRatingLink
+	}
+	conversation, err := s.findConversation(int64(claims.ConversationID))
+	if err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			return nil, ErrInvalidRatingLink
+		}
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (s *RatingService) findConversation(conversationId int64) (*model.Conversation, error) {
+	conversation, err := s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (s *RatingService) requireAdmin(userId uint) error {
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func validateDateRange(from, to time.Time) error {
+	if !from.Before(to) || to.Sub(from) > maxReportRange {
+		return ErrInvalidDateRange
+	}
+	return nil
+}
+
+func newRatingStats(row *repository.RatingAggregate) RatingStats {
+	stats := RatingStats{Count: row.Count, Average: row.Average}
+	if row.Count > 0 {
+		stats.SatisfactionRate = float64(row.Positive) / float64(row.Count)
+	}
+	return stats
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+	ErrInvalidRetentionPolicy  = errors.New("a retention policy needs a name of at most 100 characters, 1 to 3650 days and a conversation type of direct, group, support or none")
+)
+
+const (
+	purgeBatchSize         = 500
+	maxPurgeBatches        = 20 // per run, so a large backlog is worked off over several runs
+	maxRetentionDays       = 3650
+	maxRetentionPolicyName = 100
+	maxPurgeRunPageSize    = 100
+)
+
+// RetentionPolicyInput holds the editable fields of a retention policy
+type RetentionPolicyInput struct {
+	Name             string
+	ConversationType string
+	MaxAgeDays       int
+	KeepPinned       bool
+	Enabled          bool
+}
+
+// RetentionService lets admins set how long messages are kept and deletes
+// messages that disappeared or outlived a policy. Deletion runs in small batches,
+// each in its own short transaction, so the messages table is never locked for long.
+type RetentionService struct {
+	repo          *repository.RetentionRepository
+	conversations *ConversationService
+	userRepo      *repository.UserRepository
+}
+
+func NewRetentionService(repo *repository.RetentionRepository, conversations *ConversationService, userRepo *repository.UserRepository) *RetentionService {
+	return &RetentionService{
+		repo:          repo,
+		conversations: conversations,
+		userRepo:      userRepo,
+	}
+}
+
+// ListPolicies returns every retention policy. Admins only.
+func (s *RetentionService) ListPolicies(actorId uint) ([]*model.RetentionPolicy, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPolicies()
+}
+
+// CreatePolicy adds a retention policy. Admins only.
+func (s *RetentionService) CreatePolicy(actorId uint, input RetentionPolicyInput) (*model.RetentionPolicy, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	policy := &model.RetentionPolicy{CreatedByID: actorId}
+	if err := applyRetentionInput(policy, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdatePolicy changes a retention policy. Admins only.
+func (s *RetentionService) UpdatePolicy(actorId uint, policyId int64, input RetentionPolicyInput) (*model.RetentionPolicy, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	policy, err := s.findPolicy(policyId)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyRetentionInput(policy, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeletePolicy removes a retention policy. Admins only.
+func (s *RetentionService) DeletePolicy(actorId uint, policyId int64) error {
+	if err := s.requireAdmin(actorId); err != nil {
+		return err
+	}
+	if _, err := s.findPolicy(policyId); err != nil {
+		return err
+	}
+	return s.repo.DeletePolicy(policyId)
+}
+
+// Runs returns the purge job's recorded runs, newest first, older than beforeId
+// when set. Admins only.
+func (s *RetentionService) Runs(actorId uint, beforeId int64, limit int) ([]*model.PurgeRun, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxPurgeRunPageSize {
+		limit = maxPurgeRunPageSize
+	}
+	return s.repo.FindRuns(beforeId, limit)
+}
+
+// Purge deletes expired disappearing messages, then messages past each enabled
+// retention policy, and records what it deleted. It runs periodically as a
+// background job; replicas running it side by side only repeat deletes that
+// match nothing.
+func (s *RetentionService) Purge(ctx context.Context) error {
+	run := &model.PurgeRun{StartedAt: time.Now()}
+	err := s.purge(ctx, run)
+	if err != nil {
+		run.Error = err.Error()
+	} else if run.Batches == 0 {
+		return nil
+	}
+	run.FinishedAt = time.Now()
+	if recordErr := s.repo.CreateRun(run); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	return err
+}
+
+func (s *RetentionService) purge(ctx context.Context, run *model.PurgeRun) error {
+	now := run.StartedAt
+	expired, err := s.purgeBatches(ctx, run, func() ([]*model.Message, error) {
+		return s.repo.FindExpired(now, purgeBatchSize)
+	})
+	run.ExpiredMessages += expired
+	if err != nil {
+		return err
+	}
+
+	policies, err := s.repo.FindEnabledPolicies()
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		cutoff := now.AddDate(0, 0, -policy.MaxAgeDays)
+		deleted, err := s.purgeBatches(ctx, run, func() ([]*model.Message, error) {
+			return s.repo.FindPastRetention(policy, cutoff, purgeBatchSize)
+		})
+		run.RetentionMessages += deleted
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeBatches deletes the batches find returns until it runs dry or the run
+// reaches its batch limit, and returns how many messages it deleted
+func (s *RetentionService) purgeBatches(ctx context.Context, run *model.PurgeRun, find func() ([]*model.Message, error)) (int64, error) {
+	var deleted int64
+	for run.Batches < maxPurgeBatches {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		messages, err := find()
+		if err != nil || len(messages) == 0 {
+			return deleted, err
+		}
+		counts, err := s.repo.DeleteMessages(messages)
+		if err != nil {
+			return deleted, err
+		}
+		run.Batches++
+		run.Attachments += counts.Attachments
+		run.Pins += counts.Pins
+		run.Bookmarks += counts.Bookmarks
+		run.Polls += counts.Polls
+		deleted += counts.Messages
+		s.announceDeleted(messages)
+		if len(messages) < purgeBatchSize {
+			return deleted, nil
+		}
+	}
+	return deleted, nil
+}
+
+// announceDeleted tells the members of each conversation which of its messages are gone
+func (s *RetentionService) announceDeleted(messages []*model.Message) {
+	byConversation := map[uint][]uint{}
+	for _, message := range messages {
+		byConversation[message.ConversationID] = append(byConversation[message.ConversationID], message.ID)
+	}
+	for conversationId, ids := range byConversation {
+		s.conversations.broadcast(int64(conversationId), realtime.Event{
+			Type: realtime.EventMessagesDeleted,
+			Data: map[string]interface{}{"conversation_id": conversationId, "message_ids": ids},
+		})
+	}
+}
+
+func (s *RetentionService) findPolicy(policyId int64) (*model.RetentionPolicy, error) {
+	policy, err := s.repo.FindPolicyById(policyId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRetentionPolicyNotFound
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *RetentionService) requireAdmin(userId uint) error {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// applyRetentionInput validates the input and copies it to the policy
+func applyRetentionInput(policy *model.RetentionPolicy, input RetentionPolicyInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxRetentionPolicyName {
+		return ErrInvalidRetentionPolicy
+	}
+	if input.MaxAgeDays < 1 || input.MaxAgeDays > maxRetentionDays {
+		return ErrInvalidRetentionPolicy
+	}
+	switch input.ConversationType {
+	case "", model.ConversationTypeDirect, model.ConversationTypeGroup, model.ConversationTypeSupport:
+	default:
+		return ErrInvalidRetentionPolicy
+	}
+
+	policy.Name = name
+	policy.ConversationType = input.ConversationType
+	policy.MaxAgeDays = input.MaxAgeDays
+	policy.KeepPinned = input.KeepPinned
+	policy.Enabled = input.Enabled
+	return nil
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+)
+
+// Routing strategies, selected with the ROUTING_STRATEGY environment variable
+const (
+	RoutingRoundRobin = "round_robin"
+	RoutingLeastBusy  = "least_busy"
+)
+
+const (
+	routingBatchSize     = 50
+	routingCandidateSize = 10
+	maxQueueUpdates      = 200
+)
+
+// RoutingService assigns queued support conversations to available agents.
+// Every step is an atomic conditional update, so several server replicas can
+// route the same queue without assigning a chat twice or overloading an agent.
+type RoutingService struct {
+	agentRepo     *repository.AgentRepository
+	deptRepo      *repository.DepartmentRepository
+	conversations *ConversationService
+	hub           *realtime.Hub
+	strategy      string
+	mu            sync.Mutex // one routing pass at a time per replica
+}
+
+func NewRoutingService(agentRepo *repository.AgentRepository, deptRepo *repository.DepartmentRepository, conversations *ConversationService, hub *realtime.Hub) *RoutingService {
+	strategy := os.Getenv("ROUTING_STRATEGY")
+	if strategy != RoutingLeastBusy {
+		strategy = RoutingRoundRobin
+	}
+	return &RoutingService{
+		agentRepo:     agentRepo,
+		deptRepo:      deptRepo,
+		conversations: conversations,
+		hub:           hub,
+		strategy:      strategy,
+	}
+}
+
+// RouteQueue moves chats that waited too long in a department to its fallback,
+// offers queued chats, longest waiting first, to agents of their department with
+// free capacity, then tells the visitors still waiting their new position. It
+// also runs periodically as a background job.
+func (s *RoutingService) RouteQueue(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.overflow(time.Now()); err != nil {
+		return err
+	}
+
+	queued, err := s.conversations.repo.FindQueued(routingBatchSize)
+	if err != nil {
+		return err
+	}
+	// Chats without an available agent stay queued; later chats may belong to a
+	// department that still has capacity
+	for _, conversation := range queued {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.assign(conversation); err != nil {
+			return err
+		}
+	}
+	return s.publishQueuePositions()
+}
+
+// ListQueue returns the chats waiting for an agent. Only the support staff may see it.
+func (s *RoutingService) ListQueue(actorId uint) ([]*model.Conversation, error) {
+	actor, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsStaff() {
+		return nil, ErrForbidden
+	}
+	return s.conversations.repo.FindQueued(maxQueueUpdates)
+}
+
+// ChatClosed frees the slot of the agent who handled a closed chat and routes the queue
+func (s *RoutingService) ChatClosed(conversation *model.Conversation) error {
+	if conversation.AssignedAgentID != nil {
+		if err := s.agentRepo.Release(*conversation.AssignedAgentID); err != nil {
+			return err
+		}
+	}
+	return s.RouteQueue(context.Background())
+}
+
+// AgentOffline marks the agent offline and puts their open chats back in the queue
+// so other agents pick them up
+func (s *RoutingService) AgentOffline(agentId uint) error {
+	changed, err := s.agentRepo.MarkOffline(agentId)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	agent, err := s.conversations.findUser(agentId)
+	if err != nil {
+		return err
+	}
+	active, err := s.conversations.repo.FindActiveByAgent(agentId)
+	if err != nil {
+		return err
+	}
+	for _, conversation := range active {
+		conversationId := int64(conversation.ID)
+		requeued, err := s.conversations.repo.Requeue(conversationId, agentId)
+		if err != nil {
+			return err
+		}
+		if !requeued {
+			continue
+		}
+		if err := s.conversations.repo.RemoveMember(conversationId, agentId); err != nil {
+			return err
+		}
+		conversation.Status = model.SupportStatusQueued
+		conversation.AssignedAgentID = nil
+		conversation.AssignedAt = nil
+
+		s.hub.PublishToUsers([]uint{agentId}, realtime.Event{Type: realtime.EventChatRequeued, Data: conversation})
+		s.conversations.relayToVisitor(conversation, realtime.Event{Type: realtime.EventChatRequeued, Data: conversation})
+		if err := s.conversations.postSystemMessage(conversationId, fmt.Sprintf("%s is no longer available, connecting you to another agent", agent.Name)); err != nil {
+			return err
+		}
+	}
+	return s.RouteQueue(context.Background())
+}
+
+// assign tries to hand the conversation to the best available agent of its
+// department. It reports false when no such agent has capacity.
+func (s *RoutingService) assign(conversation *model.Conversation) (bool, error) {
+	candidates, err := s.agentRepo.Candidates(s.strategy == RoutingLeastBusy, conversation.DepartmentID, routingCandidateSize)
+	if err != nil {
+		return false, err
+	}
+
+	conversationId := int64(conversation.ID)
+	for _, candidate := range candidates {
+		now := time.Now()
+		claimed, err := s.agentRepo.Claim(candidate.UserID, now)
+		if err != nil {
+			return false, err
+		}
+		if !claimed {
+			// Another replica filled the agent's last slot first
+			continue
+		}
+
+		assigned, err := s.conversations.repo.Assign(conversationId, candidate.UserID, now)
+		if err != nil || !assigned {
+			// The chat was assigned or closed elsewhere; give the slot back
+			if releaseErr := s.agentRepo.Release(candidate.UserID); releaseErr != nil {
+				return false, releaseErr
+			}
+			return false, err
+		}
+		conversation.Status = model.SupportStatusActive
+		conversation.AssignedAgentID = &candidate.UserID
+		conversation.AssignedAt = &now
+
+		agent, err := s.conversations.findUser(candidate.UserID)
+		if err != nil {
+			return false, err
+		}
+		if _, err := s.conversations.join(conversationId, agent.ID, fmt.Sprintf("%s joined the chat", agent.Name)); err != nil && !errors.Is(err, ErrAlreadyMember) {
+			return false, err
+		}
+
+		s.hub.PublishToUsers([]uint{agent.ID}, realtime.Event{Type: realtime.EventChatAssigned, Data: conversation})
+		s.conversations.relayToVisitor(conversation, realtime.Event{
+			Type: realtime.EventChatAssigned,
+			Data: map[string]interface{}{"conversation_id": conversation.ID, "agent_id": agent.ID, "agent_name": agent.Name},
+		})
+		return true, nil
+	}
+	return false, nil
+}
+
+// overflow moves chats that waited longer than their department allows to its fallback department
+func (s *RoutingService) overflow(now time.Time) error {
+	due, err := s.conversations.repo.FindOverflowDue(now, routingBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, conversation := range due {
+		from, err := s.deptRepo.FindById(int64(*conversation.DepartmentID))
+		if err != nil {
+			return err
+		}
+		if from.FallbackDepartmentID == nil {
+			continue
+		}
+		to, err := s.deptRepo.FindById(int64(*from.FallbackDepartmentID))
+		if err != nil {
+			return err
+		}
+
+		moved, err := s.conversations.repo.MoveDepartment(int64(conversation.ID), from.ID, &to.ID, now)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			continue
+		}
+		conversation.DepartmentID = &to.ID
+		conversation.RoutedAt = &now
+
+		s.conversations.relayToVisitor(conversation, realtime.Event{Type: realtime.EventChatDepartmentChanged, Data: conversation})
+		text := fmt.Sprintf("No one from %s is available yet, moving you to %s", from.Name, to.Name)
+		if err := s.conversations.postSystemMessage(int64(conversation.ID), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishQueuePositions tells every waiting visitor their place in their department's queue
+func (s *RoutingService) publishQueuePositions() error {
+	queued, err := s.conversations.repo.FindQueued(maxQueueUpdates)
+	if err != nil {
+		return err
+	}
+	positions := map[uint]int{} // by department, 0 for the general queue
+	for _, conversation := range queued {
+		var departmentId uint
+		if conversation.DepartmentID != nil {
+			departmentId = *conversation.DepartmentID
+		}
+		positions[departmentId]++
+		s.conversations.relayToVisitor(conversation, realtime.Event{
+			Type: realtime.EventQueuePosition,
+			Data: map[string]interface{}{"conversation_id": conversation.ID, "position": positions[departmentId]},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrScheduledNotFound   = errors.New("scheduled message not found")
+	ErrScheduledNotPending = errors.New("scheduled message has already been sent or cancelled")
+	ErrSendAtInPast        = errors.New("send_at must be in the future")
+)
+
+const (
+	scheduledDispatchBatch = 50
+	scheduledClaimLease    = 5 * time.Minute // far longer than a send takes
+	scheduledStaleReason   = "delivery was interrupted; the message may not have been sent"
+)
+
+type ScheduledMessageService struct {
+	repo          repository.IScheduledMessageRepository
+	conversations *ConversationService
+	send          func(userId uint, conversationId int64, content string) (*model.Message, error)
+	logger        *zap.Logger
+}
+
+func NewScheduledMessageService(repo repository.IScheduledMessageRepository, conversations *ConversationService, logger *zap.Logger) *ScheduledMessageService {
+	return &ScheduledMessageService{
+		repo:          repo,
+		conversations: conversations,
+		send:          conversations.SendMessage,
+		logger:        logger,
+	}
+}
+
+// Schedule stores a message to be sent by the caller at sendAt
+func (s *ScheduledMessageService) Schedule(userId uint, conversationId int64, content string, sendAt time.Time) (*model.ScheduledMessage, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	if !sendAt.After(time.Now()) {
+		return nil, ErrSendAtInPast
+	}
+	if _, _, err := s.conversations.authorize(userId, conversationId); err != nil {
+		return nil, err
+	}
+
+	message := &model.ScheduledMessage{
+		ConversationID: uint(conversationId),
+		SenderID:       userId,
+		Content:        content,
+		SendAt:         sendAt,
+		Status:         model.ScheduledPending,
+	}
+	if err := s.repo.Create(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// ListPending returns the caller's pending messages, optionally for one conversation
+func (s *ScheduledMessageService) ListPending(userId uint, conversationId int64) ([]*model.ScheduledMessage, error) {
+	return s.repo.FindPendingBySender(userId, conversationId)
+}
+
+// Update edits the content and/or send time of a pending message. Nil fields are left unchanged.
+func (s *ScheduledMessageService) Update(userId uint, id int64, content *string, sendAt *time.Time) (*model.ScheduledMessage, error) {
+	if _, err := s.findOwned(userId, id); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if content != nil {
+		trimmed := strings.TrimSpace(*content)
+		if trimmed == "" {
+			return nil, ErrEmptyMessage
+		}
+		fields["content"] = trimmed
+	}
+	if sendAt != nil {
+		if !sendAt.After(time.Now()) {
+			return nil, ErrSendAtInPast
+		}
+		fields["send_at"] = *sendAt
+	}
+	if len(fields) > 0 {
+		updated, err := s.repo.UpdatePending(id, fields)
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			return nil, ErrScheduledNotPending
+		}
+	}
+	return s.repo.FindById(id)
+}
+
+func (s *ScheduledMessageService) Cancel(userId uint, id int64) error {
+	if _, err := s.findOwned(userId, id); err != nil {
+		return err
+	}
+	cancelled, err := s.repo.UpdatePending(id, map[string]interface{}{"status": model.ScheduledCancelled})
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrScheduledNotPending
+	}
+	return nil
+}
+
+// DispatchDue sends every message whose time has come through the normal send
+// path. It is safe to run on several replicas at once: each row is claimed by
+// exactly one replica before it is sent, so no message is delivered twice. Rows
+// are claimed one at a time, so a replica that stops mid-run leaves at most one
+// claimed row unfinished, which a later run marks failed once its lease is up.
+func (s *ScheduledMessageService) DispatchDue(ctx context.Context) error {
+	now := time.Now()
+	if _, err := s.repo.FailStale(now.Add(-scheduledClaimLease), scheduledStaleReason); err != nil {
+		return err
+	}
+	for i := 0; i < scheduledDispatchBatch && ctx.Err() == nil; i++ {
+		scheduled, err := s.repo.ClaimNext(time.Now())
+		if err != nil {
+			return err
+		}
+		if scheduled == nil {
+			return nil
+		}
+		if err := s.dispatch(scheduled); err != nil {
+			s.logger.Error("Failed to record scheduled message delivery",
+				zap.Uint("scheduled_message_id", scheduled.ID),
+				zap.String("error", err.Error()),
+			)
+		}
+	}
+	return ctx.Err()
+}
+
+// dispatch sends a claimed message and stores the outcome
+func (s *ScheduledMessageService) dispatch(scheduled *model.ScheduledMessage) error {
+	message, err := s.send(scheduled.SenderID, int64(scheduled.ConversationID), scheduled.Content)
+	if err != nil {
+		return s.repo.MarkFailed(scheduled.ID, err.Error())
+	}
+	return s.repo.MarkSent(scheduled.ID, message.ID)
+}
+
+func (s *ScheduledMessageService) findOwned(userId uint, id int64) (*model.ScheduledMessage, error) {
+	message, err := s.repo.FindById(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduledNotFound
+		}
+		return nil, err
+	}
+	if message.SenderID != userId {
+		return nil, ErrScheduledNotFound
+	}
+	if message.Status != model.ScheduledPending {
+		return nil, ErrScheduledNotPending
+	}
+	return message, nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go_starter/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// fakeScheduledRepo keeps scheduled messages in memory. Its claim is atomic like
+// the locking claim of the real repository.
+type fakeScheduledRepo struct {
+	mu       sync.Mutex
+	messages map[uint]*model.ScheduledMessage
+	failMark map[uint]bool // MarkSent fails for these IDs
+}
+
+func newFakeScheduledRepo(messages ...*model.ScheduledMessage) *fakeScheduledRepo {
+	repo := &fakeScheduledRepo{messages: map[uint]*model.ScheduledMessage{}, failMark: map[uint]bool{}}
+	for _, message := range messages {
+		repo.messages[message.ID] = message
+	}
+	return repo
+}
+
+func (r *fakeScheduledRepo) Create(message *model.ScheduledMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message.ID = uint(len(r.messages) + 1)
+	r.messages[message.ID] = message
+	return nil
+}
+
+func (r *fakeScheduledRepo) FindById(id int64) (*model.ScheduledMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *r.messages[uint(id)]
+	return &copied, nil
+}
+
+func (r *fakeScheduledRepo) FindPendingBySender(senderId uint, conversationId int64) ([]*model.ScheduledMessage, error) {
+	return nil, nil
+}
+
+func (r *fakeScheduledRepo) UpdatePending(id int64, fields map[string]interface{}) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeScheduledRepo) ClaimNext(now time.Time) (*model.ScheduledMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var next *model.ScheduledMessage
+	for _, message := range r.messages {
+		if message.Status == model.ScheduledPending && !message.SendAt.After(now) &&
+			(next == nil || message.SendAt.Before(next.SendAt)) {
+			next = message
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Status = model.ScheduledSending
+	next.ClaimedAt = &now
+	copied := *next
+	return &copied, nil
+}
+
+func (r *fakeScheduledRepo) FailStale(claimedBefore time.Time, reason string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var failed int64
+	for _, message := range r.messages {
+		if message.Status == model.ScheduledSending && message.ClaimedAt.Before(claimedBefore) {
+			message.Status = model.ScheduledFailed
+			message.Error = reason
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+func (r *fakeScheduledRepo) MarkSent(id uint, messageId uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failMark[id] {
+		return errors.New("connection lost")
+	}
+	if message := r.messages[id]; message.Status == model.ScheduledSending {
+		message.Status = model.ScheduledSent
+		message.MessageID = &messageId
+	}
+	return nil
+}
+
+func (r *fakeScheduledRepo) MarkFailed(id uint, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if message := r.messages[id]; message.Status == model.ScheduledSending {
+		message.Status = model.ScheduledFailed
+		message.Error = reason
+	}
+	return nil
+}
+
+func (r *fakeScheduledRepo) status(id uint) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.messages[id].Status
+}
+
+// countingSender records how often each scheduled content was sent
+type countingSender struct {
+	mu    sync.Mutex
+	sends map[string]int
+}
+
+func (c *countingSender) send(userId uint, conversationId int64, content string) (*model.Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sends[content]++
+	return &model.Message{ID: uint(len(c.sends)), Content: content}, nil
+}
+
+func dueMessages(n int, sendAt time.Time) []*model.ScheduledMessage {
+	messages := make([]*model.ScheduledMessage, 0, n)
+	for i := 1; i <= n; i++ {
+		messages = append(messages, &model.ScheduledMessage{
+			ID:             uint(i),
+			ConversationID: 1,
+			SenderID:       1,
+			Content:        fmt.Sprintf("message %d", i),
+			SendAt:         sendAt,
+			Status:         model.ScheduledPending,
+		})
+	}
+	return messages
+}
+
+func TestDispatchDueSendsEachMessageOnceAcrossReplicas(t *testing.T) {
+	repo := newFakeScheduledRepo(dueMessages(40, time.Now().Add(-time.Minute))...)
+	sender := &countingSender{sends: map[string]int{}}
+
+	// Four replicas share the repository and run the job at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		replica := &ScheduledMessageService{repo: repo, send: sender.send, logger: zap.NewNop()}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := replica.DispatchDue(context.Background()); err != nil {
+				t.Errorf("DispatchDue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(sender.sends) != 40 {
+		t.Errorf("sent %d distinct messages, want 40", len(sender.sends))
+	}
+	for content, count := range sender.sends {
+		if count != 1 {
+			t.Errorf("%q sent %d times, want once", content, count)
+		}
+	}
+	for id := uint(1); id <= 40; id++ {
+		if status := repo.status(id); status != model.ScheduledSent {
+			t.Errorf("message %d has status %q, want sent", id, status)
+		}
+	}
+}
+
+func TestDispatchDueKeepsGoingAfterAnError(t *testing.T) {
+	repo := newFakeScheduledRepo(dueMessages(3, time.Now().Add(-time.Minute))...)
+	repo.failMark[2] = true
+	sender := &countingSender{sends: map[string]int{}}
+	s := &ScheduledMessageService{repo: repo, send: sender.send, logger: zap.NewNop()}
+
+	if err := s.DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if len(sender.sends) != 3 {
+		t.Errorf("sent %d messages, want all 3", len(sender.sends))
+	}
+	if status := repo.status(1); status != model.ScheduledSent {
+		t.Errorf("message 1 has status %q, want sent", status)
+	}
+	if status := repo.status(3); status != model.ScheduledSent {
+		t.Errorf("message 3 has status %q, want sent", status)
+	}
+	// The send of message 2 could not be recorded, so it stays claimed
+	if status := repo.status(2); status != model.ScheduledSending {
+		t.Errorf("message 2 has status %q, want sending", status)
+	}
+}
+
+func TestDispatchDueFailsStaleClaims(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-scheduledClaimLease - time.Minute)
+	fresh := now.Add(-time.Second)
+	repo := newFakeScheduledRepo(
+		&model.ScheduledMessage{ID: 1, Content: "stale", SendAt: stale, Status: model.ScheduledSending, ClaimedAt: &stale},
+		&model.ScheduledMessage{ID: 2, Content: "fresh", SendAt: fresh, Status: model.ScheduledSending, ClaimedAt: &fresh},
+	)
+	sender := &countingSender{sends: map[string]int{}}
+	s := &ScheduledMessageService{repo: repo, send: sender.send, logger: zap.NewNop()}
+
+	if err := s.DispatchDue(context.Background()); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if status := repo.status(1); status != model.ScheduledFailed {
+		t.Errorf("stale claim has status %q, want failed", status)
+	}
+	if repo.messages[1].Error != scheduledStaleReason {
+		t.Errorf("stale claim has error %q, want %q", repo.messages[1].Error, scheduledStaleReason)
+	}
+	// A claim still within its lease belongs to a dispatcher that may be sending it
+	if status := repo.status(2); status != model.ScheduledSending {
+		t.Errorf("fresh claim has status %q, want sending", status)
+	}
+	if len(sender.sends) != 0 {
+		t.Errorf("claimed messages were sent again: %v", sender.sends)
+	}
+}
@@ -0,0 +1,658 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSLAPolicyNotFound = errors.New("SLA policy not found")
+	ErrSLAPolicyExists   = errors.New("an SLA policy for this department and priority already exists")
+	ErrInvalidPriority   = errors.New("priority must be low, normal, high or urgent")
+	ErrInvalidSLATargets = errors.New("targets must not be negative and at least one must be set")
+	ErrInvalidSLAWarning = errors.New("warning_percent must be between 1 and 99")
+	ErrInvalidSLAMetric  = errors.New("metric must be first_response or resolution")
+)
+
+const (
+	slaTimerBatch          = 200
+	defaultSLAWarningShare = 80
+	defaultSLABreachPage   = 50
+	maxSLABreachPage       = 100
+)
+
+// slaMetrics are the targets a policy can set, in the order their timers start
+var slaMetrics = []string{model.SLAFirstResponse, model.SLAResolution}
+
+// SLATargets are the response targets of a policy, in seconds. 0 disables a target.
+type SLATargets struct {
+	FirstResponseSeconds int
+	ResolutionSeconds    int
+	WarningPercent       int // defaults to 80
+}
+
+// SLABreachFilter selects the breaches of a period, optionally for one department, agent or metric
+type SLABreachFilter struct {
+	From         time.Time
+	To           time.Time
+	DepartmentID *uint
+	AgentID      *uint
+	Metric       string
+}
+
+func (f SLABreachFilter) query() repository.SLABreachFilter {
+	return repository.SLABreachFilter{From: f.From, To: f.To, DepartmentID: f.DepartmentID, AgentID: f.AgentID, Metric: f.Metric}
+}
+
+// SLABreachStats is the number of missed targets of one metric in a department.
+// DepartmentID is nil for the general queue.
+type SLABreachStats struct {
+	DepartmentID   *uint  `json:"department_id"`
+	DepartmentName string `json:"department_name"`
+	Metric         string `json:"metric"`
+	Count          int64  `json:"count"`
+}
+
+// SLAAlert is pushed to agents and admins when a chat nears or misses a target
+type SLAAlert struct {
+	Timer            *model.SLATimer `json:"timer"`
+	ConversationName string          `json:"conversation_name"`
+	Priority         string          `json:"priority"`
+	DepartmentID     *uint           `json:"department_id,omitempty"`
+	AssignedAgentID  *uint           `json:"assigned_agent_id,omitempty"`
+}
+
+// SLAService tracks first-response and resolution targets of support chats
+type SLAService struct {
+	repo          *repository.SLARepository
+	conversations *ConversationService
+	deptRepo      *repository.DepartmentRepository
+	hub           *realtime.Hub
+}
+
+func NewSLAService(repo *repository.SLARepository, conversations *ConversationService, deptRepo *repository.DepartmentRepository, hub *realtime.Hub) *SLAService {
+	return &SLAService{
+		repo:          repo,
+		conversations: conversations,
+		deptRepo:      deptRepo,
+		hub:           hub,
+	}
+}
+
+// ListPolicies returns every SLA policy. Admin only.
+func (s *SLAService) ListPolicies(actorId uint) ([]*model.SLAPolicy, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPolicies()
+}
+
+// CreatePolicy sets the targets of chats of a priority in a department, or the
+// default targets for that priority when departmentId is nil. Admin only.
+func (s *SLAService) CreatePolicy(actorId uint, departmentId *uint, priority string, targets SLATargets) (*model.SLAPolicy, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if !model.IsValidPriority(priority) {
+		return nil, ErrInvalidPriority
+	}
+	if err := validateSLATargets(&targets); err != nil {
+		return nil, err
+	}
+	if departmentId != nil {
+		if _, err := s.deptRepo.FindById(int64(*departmentId)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrDepartmentNotFound
+			}
+			return nil, err
+		}
+	}
+	// The unique index does not cover default policies, where department_id is NULL
+	if _, err := s.repo.FindPolicy(departmentId, priority); err == nil {
+		return nil, ErrSLAPolicyExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	policy := &model.SLAPolicy{
+		DepartmentID:         departmentId,
+		Priority:             priority,
+		FirstResponseSeconds: targets.FirstResponseSeconds,
+		ResolutionSeconds:    targets.ResolutionSeconds,
+		WarningPercent:       targets.WarningPercent,
+	}
+	if err := s.repo.CreatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdatePolicy changes the targets of a policy. Timers already running keep the
+// deadlines they started with. Admin only.
+func (s *SLAService) UpdatePolicy(actorId uint, policyId int64, targets SLATargets) (*model.SLAPolicy, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if err := validateSLATargets(&targets); err != nil {
+		return nil, err
+	}
+	if _, err := s.findPolicy(policyId); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{
+		"first_response_seconds": targets.FirstResponseSeconds,
+		"resolution_seconds":     targets.ResolutionSeconds,
+		"warning_percent":        targets.WarningPercent,
+	}
+	if err := s.repo.UpdatePolicy(policyId, fields); err != nil {
+		return nil, err
+	}
+	return s.findPolicy(policyId)
+}
+
+// DeletePolicy removes a policy. Timers already running keep their deadlines. Admin only.
+func (s *SLAService) DeletePolicy(actorId uint, policyId int64) error {
+	if err := s.requireAdmin(actorId); err != nil {
+		return err
+	}
+	if _, err := s.findPolicy(policyId); err != nil {
+		return err
+	}
+	return s.repo.DeletePolicy(policyId)
+}
+
+// Timers returns the SLA timers of a support chat. Agents and admins only.
+func (s *SLAService) Timers(actorId uint, conversationId int64) ([]*model.SLATimer, error) {
+	if _, err := s.authorizeStaff(actorId, conversationId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindTimers(conversationId)
+}
+
+// SetPriority changes the priority of a live support chat and moves its running
+// timers to the targets of the new priority, counted from when they started.
+// Agents and admins only.
+func (s *SLAService) SetPriority(actorId uint, conversationId int64, priority string) (*model.Conversation, error) {
+	if !model.IsValidPriority(priority) {
+		return nil, ErrInvalidPriority
+	}
+	conversation, err := s.authorizeStaff(actorId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.IsClosed() {
+		return nil, ErrConversationClosed
+	}
+	if conversation.Priority == priority {
+		return conversation, nil
+	}
+	if err := s.conversations.repo.Update(conversationId, map[string]interface{}{"priority": priority}); err != nil {
+		return nil, err
+	}
+	conversation.Priority = priority
+	if err := s.reschedule(conversation, time.Now()); err != nil {
+		return nil, err
+	}
+
+	s.conversations.broadcastToStaff(conversationId, realtime.Event{Type: realtime.EventConversationUpdated, Data: conversation})
+	return conversation, nil
+}
+
+// ChatOpened starts the timers of a support chat joining the queue from its
+// policy, if it has one. Time spent with the bot before that does not count.
+func (s *SLAService) ChatOpened(conversation *model.Conversation) error {
+	policy, err := s.policyFor(conversation)
+	if err != nil || policy == nil {
+		return err
+	}
+	department, err := s.schedule(conversation.DepartmentID)
+	if err != nil {
+		return err
+	}
+
+	var timers []*model.SLATimer
+	for _, metric := range slaMetrics {
+		target := policy.Target(metric)
+		if target == 0 {
+			continue
+		}
+		warnAt, dueAt := slaDeadlines(department, slaStart(conversation), target, policy.WarningPercent)
+		timers = append(timers, &model.SLATimer{
+			ConversationID: conversation.ID,
+			Metric:         metric,
+			PolicyID:       policy.ID,
+			TargetSeconds:  int(target / time.Second),
+			Status:         model.SLATimerRunning,
+			StartedAt:      slaStart(conversation),
+			WarnAt:         warnAt,
+			DueAt:          dueAt,
+		})
+	}
+	if len(timers) == 0 {
+		return nil
+	}
+	return s.repo.CreateTimers(timers)
+}
+
+// Breaches returns a page of missed targets, newest first. Supervisors and admins only.
+func (s *SLAService) Breaches(actorId uint, filter SLABreachFilter, beforeId int64, limit int) ([]*model.SLABreach, error) {
+	if err := s.requireSupervisor(actorId); err != nil {
+		return nil, err
+	}
+	if err := validateBreachFilter(filter); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultSLABreachPage
+	}
+	if limit > maxSLABreachPage {
+		limit = maxSLABreachPage
+	}
+	return s.repo.FindBreaches(filter.query(), beforeId, limit)
+}
+
+// BreachStats returns the number of missed targets per department and metric over
+// the period. Supervisors and admins only.
+func (s *SLAService) BreachStats(actorId uint, filter SLABreachFilter) ([]*SLABreachStats, error) {
+	if err := s.requireSupervisor(actorId); err != nil {
+		return nil, err
+	}
+	if err := validateBreachFilter(filter); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.CountBreaches(filter.query())
+	if err != nil {
+		return nil, err
+	}
+	departments, err := s.deptRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	departmentsById := make(map[uint]*model.Department, len(departments))
+	for _, department := range departments {
+		departmentsById[department.ID] = department
+	}
+
+	stats := make([]*SLABreachStats, 0, len(rows))
+	for _, row := range rows {
+		entry := &SLABreachStats{DepartmentID: row.DepartmentID, Metric: row.Metric, Count: row.Count}
+		if row.DepartmentID != nil {
+			if department, ok := departmentsById[*row.DepartmentID]; ok {
+				entry.DepartmentName = department.Name
+			}
+		}
+		stats = append(stats, entry)
+	}
+	return stats, nil
+}
+
+// EvaluateTimers stops timers whose chat got its reply or was closed, warns agents
+// about chats nearing a target and records the targets missed. Every transition is
+// a conditional update, so concurrent server replicas never warn or record twice.
+// It runs as a background job.
+func (s *SLAService) EvaluateTimers(ctx context.Context) error {
+	now := time.Now()
+	var afterId int64
+	for {
+		timers, err := s.repo.FindOpenTimers(afterId, slaTimerBatch)
+		if err != nil {
+			return err
+		}
+		conversations := map[uint]*model.Conversation{}
+		for _, timer := range timers {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			conversation, ok := conversations[timer.ConversationID]
+			if !ok {
+				conversation, err = s.conversations.repo.FindById(int64(timer.ConversationID))
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				conversations[timer.ConversationID] = conversation
+			}
+			if err := s.evaluate(timer, conversation, now); err != nil {
+				return err
+			}
+		}
+		if len(timers) < slaTimerBatch {
+			return nil
+		}
+		afterId = int64(timers[len(timers)-1].ID)
+	}
+}
+
+func (s *SLAService) evaluate(timer *model.SLATimer, conversation *model.Conversation, now time.Time) error {
+	timerId := int64(timer.ID)
+	if conversation == nil {
+		_, err := s.repo.Complete(timerId, model.SLATimerCancelled, now)
+		return err
+	}
+
+	completedAt, status, err := s.completion(timer, conversation)
+	if err != nil {
+		return err
+	}
+	if completedAt != nil {
+		// A reply or close the job had not seen before the deadline still misses it
+		if timer.Status == model.SLATimerRunning && completedAt.After(timer.DueAt) {
+			if _, err := s.breach(timer, conversation); err != nil {
+				return err
+			}
+		}
+		_, err := s.repo.Complete(timerId, status, *completedAt)
+		return err
+	}
+	if timer.Status != model.SLATimerRunning {
+		return nil
+	}
+
+	if !now.Before(timer.DueAt) {
+		breached, err := s.breach(timer, conversation)
+		if err != nil || !breached {
+			return err
+		}
+		return s.alert(realtime.EventSLABreached, timer, conversation)
+	}
+	if timer.WarnedAt == nil && !now.Before(timer.WarnAt) {
+		warned, err := s.repo.MarkWarned(timerId, now)
+		if err != nil || !warned {
+			return err
+		}
+		timer.WarnedAt = &now
+		return s.alert(realtime.EventSLAWarning, timer, conversation)
+	}
+	return nil
+}
+
+// completion returns when the timer's target was reached and the status it ends
+// with, or nil while the chat still waits
+func (s *SLAService) completion(timer *model.SLATimer, conversation *model.Conversation) (*time.Time, string, error) {
+	if timer.Metric == model.SLAFirstResponse {
+		reply, err := s.conversations.msgRepo.FindFirstReply(int64(conversation.ID))
+		if err == nil {
+			return &reply.CreatedAt, model.SLATimerMet, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", err
+		}
+		// The visitor left before anyone answered
+		if conversation.IsClosed() {
+			return conversation.ClosedAt, model.SLATimerCancelled, nil
+		}
+		return nil, "", nil
+	}
+	if conversation.IsClosed() {
+		return conversation.ClosedAt, model.SLATimerMet, nil
+	}
+	return nil, "", nil
+}
+
+func (s *SLAService) breach(timer *model.SLATimer, conversation *model.Conversation) (bool, error) {
+	breach := &model.SLABreach{
+		TimerID:        timer.ID,
+		ConversationID: timer.ConversationID,
+		Metric:         timer.Metric,
+		Priority:       conversation.Priority,
+		DepartmentID:   conversation.DepartmentID,
+		AgentID:        conversation.AssignedAgentID,
+		TargetSeconds:  timer.TargetSeconds,
+		DueAt:          timer.DueAt,
+		BreachedAt:     timer.DueAt,
+	}
+	breached, err := s.repo.Breach(timer, breach)
+	if err != nil || !breached {
+		return breached, err
+	}
+	timer.Status = model.SLATimerBreached
+	timer.BreachedAt = &breach.BreachedAt
+	return true, nil
+}
+
+// alert pushes an SLA event to the assigned agent, or to the department's agents
+// while the chat is queued, and to the supervisors and admins
+func (s *SLAService) alert(eventType string, timer *model.SLATimer, conversation *model.Conversation) error {
+	var agentIds []uint
+	var err error
+	switch {
+	case conversation.AssignedAgentID != nil:
+		agentIds = []uint{*conversation.AssignedAgentID}
+	case conversation.DepartmentID != nil:
+		agentIds, err = s.deptRepo.AgentIds(int64(*conversation.DepartmentID))
+	default:
+		agentIds, err = s.conversations.userRepo.IdsByRole(model.UserRoleAgent)
+	}
+	if err != nil {
+		return err
+	}
+	supervisorIds, err := s.conversations.userRepo.IdsByRole(model.UserRoleSupervisor)
+	if err != nil {
+		return err
+	}
+	adminIds, err := s.conversations.userRepo.IdsByRole(model.UserRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	seen := map[uint]bool{}
+	var userIds []uint
+	for _, id := range append(append(agentIds, supervisorIds...), adminIds...) {
+		if !seen[id] {
+			seen[id] = true
+			userIds = append(userIds, id)
+		}
+	}
+	s.hub.PublishToUsers(userIds, realtime.Event{Type: eventType, Data: SLAAlert{
+		Timer:            timer,
+		ConversationName: conversation.Name,
+		Priority:         conversation.Priority,
+		DepartmentID:     conversation.DepartmentID,
+		AssignedAgentID:  conversation.AssignedAgentID,
+	}})
+	return nil
+}
+
+// reschedule moves the chat's running timers to the targets of its current
+// priority, starts the ones the new policy adds and cancels the ones it drops.
+// Chats still with the bot have no timers yet; they start at the handoff.
+func (s *SLAService) reschedule(conversation *model.Conversation, now time.Time) error {
+	if conversation.Status == model.SupportStatusBot {
+		return nil
+	}
+	policy, err := s.policyFor(conversation)
+	if err != nil {
+		return err
+	}
+	department, err := s.schedule(conversation.DepartmentID)
+	if err != nil {
+		return err
+	}
+	timers, err := s.repo.FindTimers(int64(conversation.ID))
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, timer := range timers {
+		existing[timer.Metric] = true
+		if timer.Status != model.SLATimerRunning {
+			continue
+		}
+		var target time.Duration
+		if policy != nil {
+			target = policy.Target(timer.Metric)
+		}
+		if target == 0 {
+			if _, err := s.repo.Complete(int64(timer.ID), model.SLATimerCancelled, now); err != nil {
+				return err
+			}
+			continue
+		}
+		warnAt, dueAt := slaDeadlines(department, timer.StartedAt, target, policy.WarningPercent)
+		if _, err := s.repo.Reschedule(int64(timer.ID), policy.ID, int(target/time.Second), warnAt, dueAt); err != nil {
+			return err
+		}
+	}
+	if policy == nil {
+		return nil
+	}
+
+	var added []*model.SLATimer
+	for _, metric := range slaMetrics {
+		target := policy.Target(metric)
+		if existing[metric] || target == 0 {
+			continue
+		}
+		warnAt, dueAt := slaDeadlines(department, slaStart(conversation), target, policy.WarningPercent)
+		added = append(added, &model.SLATimer{
+			ConversationID: conversation.ID,
+			Metric:         metric,
+			PolicyID:       policy.ID,
+			TargetSeconds:  int(target / time.Second),
+			Status:         model.SLATimerRunning,
+			StartedAt:      slaStart(conversation),
+			WarnAt:         warnAt,
+			DueAt:          dueAt,
+		})
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return s.repo.CreateTimers(added)
+}
+
+// policyFor returns the policy for the chat's priority in its department, falling
+// back to the default policy for that priority. It returns nil when neither exists.
+func (s *SLAService) policyFor(conversation *model.Conversation) (*model.SLAPolicy, error) {
+	priority := conversation.Priority
+	if priority == "" {
+		priority = model.PriorityNormal
+	}
+	if conversation.DepartmentID != nil {
+		policy, err := s.repo.FindPolicy(conversation.DepartmentID, priority)
+		if err == nil {
+			return policy, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	policy, err := s.repo.FindPolicy(nil, priority)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return policy, err
+}
+
+// schedule returns the department whose business hours the timers follow. The
+// general queue, and departments deleted since, count every hour.
+func (s *SLAService) schedule(departmentId *uint) (*model.Department, error) {
+	if departmentId == nil {
+		return &model.Department{}, nil
+	}
+	department, err := s.deptRepo.FindById(int64(*departmentId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.Department{}, nil
+	}
+	return department, err
+}
+
+func (s *SLAService) authorizeStaff(actorId uint, conversationId int64) (*model.Conversation, error) {
+	actor, err := s.conversations.findUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsStaff() {
+		return nil, ErrForbidden
+	}
+	conversation, err := s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	if !conversation.IsSupport() {
+		return nil, ErrNotSupportChat
+	}
+	return conversation, nil
+}
+
+func (s *SLAService) findPolicy(policyId int64) (*model.SLAPolicy, error) {
+	policy, err := s.repo.FindPolicyById(policyId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSLAPolicyNotFound
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *SLAService) requireSupervisor(userId uint) error {
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.CanSupervise() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *SLAService) requireAdmin(userId uint) error {
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// slaStart returns when the chat's timers start: when it joined the queue, or
+// when it was created for chats queued before that was recorded
+func slaStart(conversation *model.Conversation) time.Time {
+	if conversation.QueuedAt != nil {
+		return *conversation.QueuedAt
+	}
+	return conversation.CreatedAt
+}
+
+// slaDeadlines returns when agents are warned and when the target is missed, in
+// the department's business time from start
+func slaDeadlines(department *model.Department, start time.Time, target time.Duration, warningPercent int) (time.Time, time.Time) {
+	warnAfter := target * time.Duration(warningPercent) / 100
+	return department.AddBusinessTime(start, warnAfter), department.AddBusinessTime(start, target)
+}
+
+func validateSLATargets(targets *SLATargets) error {
+	if targets.FirstResponseSeconds < 0 || targets.ResolutionSeconds < 0 {
+		return ErrInvalidSLATargets
+	}
+	if targets.FirstResponseSeconds == 0 && targets.ResolutionSeconds == 0 {
+		return ErrInvalidSLATargets
+	}
+	if targets.WarningPercent == 0 {
+		targets.WarningPercent = defaultSLAWarningShare
+	}
+	if targets.WarningPercent < 1 || targets.WarningPercent > 99 {
+		return ErrInvalidSLAWarning
+	}
+	return nil
+}
+
+func validateBreachFilter(filter SLABreachFilter) error {
+	if filter.Metric != "" && filter.Metric != model.SLAFirstResponse && filter.Metric != model.SLAResolution {
+		return ErrInvalidSLAMetric
+	}
+	return validateDateRange(filter.From, filter.To)
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+)
+
+// QueueStats describes the chats waiting in one department's queue. DepartmentID
+// is nil for the general queue.
+type QueueStats struct {
+	DepartmentID       *uint  `json:"department_id"`
+	DepartmentName     string `json:"department_name"`
+	Queued             int    `json:"queued"`
+	LongestWaitSeconds int64  `json:"longest_wait_seconds"`
+	AverageWaitSeconds int64  `json:"average_wait_seconds"`
+}
+
+// AgentStatusCounts is how many agents are in each availability status
+type AgentStatusCounts struct {
+	Online  int `json:"online"`
+	Away    int `json:"away"`
+	Offline int `json:"offline"`
+}
+
+// DashboardOverview sums up the support team's current load
+type DashboardOverview struct {
+	GeneratedAt        time.Time         `json:"generated_at"`
+	QueuedChats        int               `json:"queued_chats"`
+	ActiveChats        int               `json:"active_chats"`
+	LongestWaitSeconds int64             `json:"longest_wait_seconds"`
+	AverageWaitSeconds int64             `json:"average_wait_seconds"`
+	FreeSlots          int               `json:"free_slots"` // chats online agents can still take
+	Agents             AgentStatusCounts `json:"agents"`
+	Queues             []*QueueStats     `json:"queues"`
+}
+
+// LiveChat is a queued or ongoing support chat as supervisors see it. WaitSeconds
+// is how long the visitor has waited for an agent, or waited before getting one.
+type LiveChat struct {
+	ConversationID  uint       `json:"conversation_id"`
+	Name            string     `json:"name"`
+	Status          string     `json:"status"`
+	Priority        string     `json:"priority,omitempty"`
+	DepartmentID    *uint      `json:"department_id,omitempty"`
+	AssignedAgentID *uint      `json:"assigned_agent_id,omitempty"`
+	QueuedAt        *time.Time `json:"queued_at,omitempty"`
+	AssignedAt      *time.Time `json:"assigned_at,omitempty"`
+	WaitSeconds     int64      `json:"wait_seconds"`
+}
+
+// AgentLoad is an agent's availability together with the chats they handle
+type AgentLoad struct {
+	User        UserSummary `json:"user"`
+	Status      string      `json:"status"`
+	MaxChats    int         `json:"max_chats"`
+	ActiveChats int         `json:"active_chats"`
+	LastSeenAt  time.Time   `json:"last_seen_at"`
+	Chats       []*LiveChat `json:"chats"`
+}
+
+// DashboardSnapshot is everything the live dashboard shows at one moment
+type DashboardSnapshot struct {
+	Overview *DashboardOverview `json:"overview"`
+	Agents   []*AgentLoad       `json:"agents"`
+	Queue    []*LiveChat        `json:"queue"`
+}
+
+// SupervisorService computes the live monitoring dashboard of the support team.
+// Access is checked by middleware.SupervisorMiddleware on its routes.
+type SupervisorService struct {
+	conversations *ConversationService
+	agentRepo     *repository.AgentRepository
+	deptRepo      *repository.DepartmentRepository
+}
+
+func NewSupervisorService(conversations *ConversationService, agentRepo *repository.AgentRepository, deptRepo *repository.DepartmentRepository) *SupervisorService {
+	return &SupervisorService{
+		conversations: conversations,
+		agentRepo:     agentRepo,
+		deptRepo:      deptRepo,
+	}
+}
+
+// Overview returns queue lengths, waiting times and agent statuses
+func (s *SupervisorService) Overview(now time.Time) (*DashboardOverview, error) {
+	snapshot, err := s.Snapshot(now)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Overview, nil
+}
+
+// Agents returns every agent with their status and the chats they handle
+func (s *SupervisorService) Agents(now time.Time) ([]*AgentLoad, error) {
+	snapshot, err := s.Snapshot(now)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Agents, nil
+}
+
+// Queue returns the chats waiting for an agent, longest waiting first
+func (s *SupervisorService) Queue(now time.Time) ([]*LiveChat, error) {
+	snapshot, err := s.Snapshot(now)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Queue, nil
+}
+
+// Snapshot computes the whole dashboard from the database, so every server
+// replica reports the same figures
+func (s *SupervisorService) Snapshot(now time.Time) (*DashboardSnapshot, error) {
+	chats, err := s.conversations.repo.FindLiveSupport()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := s.agentRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	departments, err := s.deptRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &DashboardOverview{GeneratedAt: now, Queues: []*QueueStats{}}
+	queues := map[uint]*QueueStats{}
+	general := &QueueStats{DepartmentName: "General"}
+	for _, department := range departments {
+		stats := &QueueStats{DepartmentID: &department.ID, DepartmentName: department.Name}
+		queues[department.ID] = stats
+		overview.Queues = append(overview.Queues, stats)
+	}
+	overview.Queues = append(overview.Queues, general)
+
+	queue := []*LiveChat{}
+	chatsByAgent := map[uint][]*LiveChat{}
+	totalWaits := map[*QueueStats]int64{}
+	var totalWait int64
+	for _, conversation := range chats {
+		chat := newLiveChat(conversation, now)
+		if conversation.Status == model.SupportStatusActive && conversation.AssignedAgentID != nil {
+			overview.ActiveChats++
+			chatsByAgent[*conversation.AssignedAgentID] = append(chatsByAgent[*conversation.AssignedAgentID], chat)
+			continue
+		}
+
+		queue = append(queue, chat)
+		overview.QueuedChats++
+		totalWait += chat.WaitSeconds
+		overview.LongestWaitSeconds = max(overview.LongestWaitSeconds, chat.WaitSeconds)
+
+		stats := general
+		if conversation.DepartmentID != nil {
+			if departmentStats, ok := queues[*conversation.DepartmentID]; ok {
+				stats = departmentStats
+			}
+		}
+		stats.Queued++
+		totalWaits[stats] += chat.WaitSeconds
+		stats.LongestWaitSeconds = max(stats.LongestWaitSeconds, chat.WaitSeconds)
+	}
+	for stats, wait := range totalWaits {
+		stats.AverageWaitSeconds = wait / int64(stats.Queued)
+	}
+	if overview.QueuedChats > 0 {
+		overview.AverageWaitSeconds = totalWait / int64(overview.QueuedChats)
+	}
+
+	agents, err := s.agentLoads(profiles, chatsByAgent, overview)
+	if err != nil {
+		return nil, err
+	}
+	return &DashboardSnapshot{Overview: overview, Agents: agents, Queue: queue}, nil
+}
+
+// agentLoads pairs agent profiles with their users and chats and counts their statuses
+func (s *SupervisorService) agentLoads(profiles []*model.AgentProfile, chatsByAgent map[uint][]*LiveChat, overview *DashboardOverview) ([]*AgentLoad, error) {
+	ids := make([]uint, 0, len(profiles))
+	for _, profile := range profiles {
+		ids = append(ids, profile.UserID)
+	}
+	users, err := s.conversations.userRepo.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	usersById := make(map[uint]*model.User, len(users))
+	for _, user := range users {
+		usersById[user.ID] = user
+	}
+
+	agents := make([]*AgentLoad, 0, len(profiles))
+	for _, profile := range profiles {
+		user, ok := usersById[profile.UserID]
+		if !ok || !user.IsAgent() {
+			continue
+		}
+		chats := chatsByAgent[profile.UserID]
+		if chats == nil {
+			chats = []*LiveChat{}
+		}
+		agents = append(agents, &AgentLoad{
+			User:        newUserSummary(user),
+			Status:      profile.Status,
+			MaxChats:    profile.MaxChats,
+			ActiveChats: len(chats),
+			LastSeenAt:  profile.LastSeenAt,
+			Chats:       chats,
+		})
+
+		switch profile.Status {
+		case model.AgentStatusOnline:
+			overview.Agents.Online++
+			overview.FreeSlots += max(profile.MaxChats-len(chats), 0)
+		case model.AgentStatusAway:
+			overview.Agents.Away++
+		default:
+			overview.Agents.Offline++
+		}
+	}
+	return agents, nil
+}
+
+func newLiveChat(conversation *model.Conversation, now time.Time) *LiveChat {
+	chat := &LiveChat{
+		ConversationID:  conversation.ID,
+		Name:            conversation.Name,
+		Status:          conversation.Status,
+		Priority:        conversation.Priority,
+		DepartmentID:    conversation.DepartmentID,
+		AssignedAgentID: conversation.AssignedAgentID,
+		QueuedAt:        conversation.QueuedAt,
+		AssignedAt:      conversation.AssignedAt,
+	}
+	if conversation.QueuedAt != nil {
+		waitedUntil := now
+		if conversation.Status == model.SupportStatusActive && conversation.AssignedAt != nil {
+			waitedUntil = *conversation.AssignedAt
+		}
+		chat.WaitSeconds = int64(waitedUntil.Sub(*conversation.QueuedAt) / time.Second)
+	}
+	return chat
+}
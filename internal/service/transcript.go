@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+)
+
+const transcriptTimeLayout = "2006-01-02 15:04:05 UTC"
+
+// transcriptLine is one message of a transcript with its sender resolved to a name
+type transcriptLine struct {
+	ID        uint      `json:"id"`
+	Sender    string    `json:"sender"`
+	SenderID  uint      `json:"sender_id,omitempty"`
+	VisitorID *uint     `json:"visitor_id,omitempty"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content"`
+	Internal  bool      `json:"internal,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Time formats the line's timestamp for the text and HTML transcripts
+func (l transcriptLine) Time() string {
+	return l.CreatedAt.UTC().Format(transcriptTimeLayout)
+}
+
+// transcript is a conversation ready to be rendered in any transcript format
+type transcript struct {
+	ConversationID uint             `json:"conversation_id"`
+	Title          string           `json:"title"`
+	Type           string           `json:"type"`
+	ClosedAt       *time.Time       `json:"closed_at,omitempty"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Messages       []transcriptLine `json:"messages"`
+}
+
+var transcriptHTMLTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: sans-serif; color: #222; }
+  .message { margin: 0 0 12px; }
+  .meta { color: #777; font-size: 12px; }
+  .system { color: #777; font-style: italic; }
+  .internal { background: #fff6d5; }
+  .content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+<p class="meta">Transcript generated {{.GeneratedAt.UTC.Format "2006-01-02 15:04 UTC"}}</p>
+{{range .Messages}}<div class="message{{if eq .Type "system"}} system{{end}}{{if .Internal}} internal{{end}}">
+  <div class="meta">{{.Time}} &middot; {{.Sender}}{{if .Internal}} (internal){{end}}</div>
+  <div class="content">{{.Content}}</div>
+</div>
+{{end}}</body>
+</html>
+`))
+
+// render writes the transcript in the given format
+func (t *transcript) render(format string) ([]byte, error) {
+	switch format {
+	case model.TranscriptText:
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s\nTranscript generated %s\n\n", t.Title, t.GeneratedAt.UTC().Format(transcriptTimeLayout))
+		for _, line := range t.Messages {
+			sender := line.Sender
+			if line.Internal {
+				sender += " (internal)"
+			}
+			fmt.Fprintf(&b, "[%s] %s: %s\n", line.Time(), sender, line.Content)
+		}
+		return []byte(b.String()), nil
+	case model.TranscriptHTML:
+		var b bytes.Buffer
+		if err := transcriptHTMLTemplate.Execute(&b, t); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case model.TranscriptJSON:
+		return json.MarshalIndent(t, "", "  ")
+	}
+	return nil, ErrInvalidTranscriptFormat
+}
+
+// transcriptContentType returns the MIME type of a transcript format
+func transcriptContentType(format string) string {
+	switch format {
+	case model.TranscriptHTML:
+		return "text/html; charset=utf-8"
+	case model.TranscriptJSON:
+		return "application/json"
+	}
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidTranscriptFormat = errors.New("format must be txt, html or json")
+	ErrExportNotFound          = errors.New("transcript export not found")
+	ErrExportNotReady          = errors.New("transcript is still being generated")
+	ErrInvalidDownloadLink     = errors.New("download link is invalid or expired")
+	ErrNoTranscriptEmail       = errors.New("the visitor has not shared an email address")
+)
+
+const (
+	// Transcripts with more messages are generated in the background
+	inlineTranscriptLimit = 500
+	transcriptPageSize    = 500
+	transcriptLinkTTL     = 7 * 24 * time.Hour
+	transcriptBatchSize   = 10
+	// Exports still processing after this long were abandoned by their replica
+	transcriptStaleAfter = 10 * time.Minute
+)
+
+// TranscriptFile is a rendered transcript ready to be downloaded
+type TranscriptFile struct {
+	Filename    string
+	ContentType string
+	Body        []byte
+}
+
+// TranscriptService exports conversation transcripts and emails them to visitors
+type TranscriptService struct {
+	repo          *repository.TranscriptRepository
+	conversations *ConversationService
+	visitorRepo   *repository.VisitorRepository
+	emailService  *EmailService
+	appURL        string
+}
+
+func NewTranscriptService(repo *repository.TranscriptRepository, conversations *ConversationService, visitorRepo *repository.VisitorRepository) *TranscriptService {
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:8080"
+	}
+	return &TranscriptService{
+		repo:          repo,
+		conversations: conversations,
+		visitorRepo:   visitorRepo,
+		emailService:  NewEmailService(),
+		appURL:        strings.TrimRight(appURL, "/"),
+	}
+}
+
+// Export renders the transcript of a conversation the user takes part in, or of
+// any support chat for agents and admins. Agent-only messages are included for
+// staff. Large transcripts are queued instead and returned as a pending export.
+func (s *TranscriptService) Export(actorId uint, conversationId int64, format string) (*TranscriptFile, *model.TranscriptExport, error) {
+	if err := validateTranscriptFormat(format); err != nil {
+		return nil, nil, err
+	}
+	conversation, err := s.authorize(actorId, conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	includeInternal, err := s.conversations.canSeeInternal(actorId, conversation)
+	if err != nil {
+		return nil, nil, err
+	}
+	export := &model.TranscriptExport{RequestedByID: &actorId, IncludeInternal: includeInternal}
+	return s.export(conversation, format, export)
+}
+
+// VisitorExport renders the transcript of one of the visitor's chats
+func (s *TranscriptService) VisitorExport(visitorId uint, conversationId int64, format string) (*TranscriptFile, *model.TranscriptExport, error) {
+	if err := validateTranscriptFormat(format); err != nil {
+		return nil, nil, err
+	}
+	conversation, err := s.visitorConversation(visitorId, conversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.export(conversation, format, &model.TranscriptExport{VisitorID: &visitorId})
+}
+
+// GetExport returns an export the user requested, with its download link once ready
+func (s *TranscriptService) GetExport(actorId uint, exportId int64) (*model.TranscriptExport, error) {
+	export, err := s.findExport(exportId)
+	if err != nil {
+		return nil, err
+	}
+	if export.RequestedByID == nil || *export.RequestedByID != actorId {
+		return nil, ErrExportNotFound
+	}
+	return export, s.sign(export)
+}
+
+// GetVisitorExport returns an export the visitor requested, with its download link once ready
+func (s *TranscriptService) GetVisitorExport(visitorId uint, exportId int64) (*model.TranscriptExport, error) {
+	export, err := s.findExport(exportId)
+	if err != nil {
+		return nil, err
+	}
+	if export.VisitorID == nil || *export.VisitorID != visitorId {
+		return nil, ErrExportNotFound
+	}
+	return export, s.sign(export)
+}
+
+// Download returns the transcript a signed link points to
+func (s *TranscriptService) Download(token string) (*TranscriptFile, error) {
+	claims, err := util.ValidateDownloadToken(token)
+	if err != nil {
+		return nil, ErrInvalidDownloadLink
+	}
+	export, err := s.repo.FindWithContent(int64(claims.ExportID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidDownloadLink
+		}
+		return nil, err
+	}
+	if export.Status != model.ExportReady {
+		return nil, ErrExportNotReady
+	}
+	return &TranscriptFile{
+		Filename:    transcriptFilename(export.ConversationID, export.Format),
+		ContentType: transcriptContentType(export.Format),
+		Body:        []byte(export.Content),
+	}, nil
+}
+
+// RequestEmail has the transcript of a support chat emailed to its visitor when
+// the chat ends, or right away when it already has
+func (s *TranscriptService) RequestEmail(actorId uint, conversationId int64) error {
+	conversation, err := s.authorize(actorId, conversationId)
+	if err != nil {
+		return err
+	}
+	if !conversation.IsSupport() || conversation.VisitorID == nil {
+		return ErrNotSupportChat
+	}
+	visitor, err := s.visitorRepo.FindById(*conversation.VisitorID)
+	if err != nil {
+		return err
+	}
+	return s.requestEmail(conversation, visitor.Email)
+}
+
+// VisitorRequestEmail has the transcript of the visitor's current chat emailed to
+// them when it ends. An empty email uses the one in the visitor's profile.
+func (s *TranscriptService) VisitorRequestEmail(visitorId uint, email string) error {
+	conversation, err := s.conversations.repo.FindOpenSupport(visitorId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoOpenChat
+		}
+		return err
+	}
+	email = strings.TrimSpace(email)
+	if email == "" {
+		visitor, err := s.visitorRepo.FindById(visitorId)
+		if err != nil {
+			return err
+		}
+		email = visitor.Email
+	}
+	return s.requestEmail(conversation, email)
+}
+
+// ChatClosed queues the transcript email of a support chat that just ended, if one was requested
+func (s *TranscriptService) ChatClosed(conversationId int64) error {
+	conversation, err := s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		return err
+	}
+	if conversation.TranscriptEmail == "" {
+		return nil
+	}
+	return s.queueEmail(conversation, conversation.TranscriptEmail)
+}
+
+// ProcessExports generates queued transcripts, emails the ones meant for visitors
+// and deletes expired exports. Exports are claimed with a conditional update, so
+// several server replicas can run it at once. It runs as a background job.
+func (s *TranscriptService) ProcessExports(ctx context.Context) error {
+	now := time.Now()
+	if _, err := s.repo.DeleteExpired(now); err != nil {
+		return err
+	}
+	waiting, err := s.repo.FindWaiting(now.Add(-transcriptStaleAfter), transcriptBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, export := range waiting {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		claimed, err := s.repo.Claim(int64(export.ID), time.Now(), now.Add(-transcriptStaleAfter))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		content, err := s.generate(export)
+		if err != nil {
+			err = s.repo.Fail(int64(export.ID), err.Error(), time.Now())
+		} else {
+			err = s.repo.Complete(int64(export.ID), content, time.Now())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generate renders a claimed export and sends it when it is meant for an email
+func (s *TranscriptService) generate(export *model.TranscriptExport) (string, error) {
+	conversation, err := s.conversations.repo.FindById(int64(export.ConversationID))
+	if err != nil {
+		return "", err
+	}
+	t, count, err := s.build(conversation, export.IncludeInternal, 0)
+	if err != nil {
+		return "", err
+	}
+	body, err := t.render(export.Format)
+	if err != nil {
+		return "", err
+	}
+	if export.EmailTo == "" {
+		return string(body), nil
+	}
+
+	// Short transcripts go in the email itself, longer ones as a download link
+	subject := fmt.Sprintf("Transcript of your chat: %s", t.Title)
+	if count <= inlineTranscriptLimit {
+		return string(body), s.emailService.SendHTMLEmail(export.EmailTo, subject, string(body))
+	}
+	export.Status = model.ExportReady
+	if err := s.sign(export); err != nil {
+		return "", err
+	}
+	return string(body), s.emailService.SendTranscriptLinkEmail(export.EmailTo, TranscriptLinkEmail{
+		Title:       t.Title,
+		DownloadURL: export.DownloadURL,
+		ExpiresAt:   export.ExpiresAt.UTC().Format("2006-01-02"),
+	})
+}
+
+// export renders small transcripts right away and queues large ones
+func (s *TranscriptService) export(conversation *model.Conversation, format string, export *model.TranscriptExport) (*TranscriptFile, *model.TranscriptExport, error) {
+	count, err := s.conversations.msgRepo.CountByConversation(int64(conversation.ID), export.IncludeInternal)
+	if err != nil {
+		return nil, nil, err
+	}
+	if count > inlineTranscriptLimit {
+		export.ConversationID = conversation.ID
+		export.Format = format
+		export.Status = model.ExportPending
+		export.ExpiresAt = time.Now().Add(transcriptLinkTTL)
+		if err := s.repo.Create(export); err != nil {
+			return nil, nil, err
+		}
+		return nil, export, nil
+	}
+
+	t, _, err := s.build(conversation, export.IncludeInternal, inlineTranscriptLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := t.render(format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &TranscriptFile{
+		Filename:    transcriptFilename(conversation.ID, format),
+		ContentType: transcriptContentType(format),
+		Body:        body,
+	}, nil, nil
+}
+
+// build reads the conversation's messages page by page, up to limit when set,
+// and resolves their senders
+func (s *TranscriptService) build(conversation *model.Conversation, includeInternal bool, limit int) (*transcript, int, error) {
+	conversationId := int64(conversation.ID)
+	t := &transcript{
+		ConversationID: conversation.ID,
+		Title:          conversation.Name,
+		Type:           conversation.Type,
+		ClosedAt:       conversation.ClosedAt,
+		GeneratedAt:    time.Now(),
+	}
+	if t.Title == "" {
+		t.Title = fmt.Sprintf("Conversation #%d", conversation.ID)
+	}
+
+	var messages []*model.Message
+	var afterId int64
+	for {
+		page, err := s.conversations.msgRepo.FindAfter(conversationId, afterId, transcriptPageSize, includeInternal)
+		if err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, page...)
+		if len(page) < transcriptPageSize || (limit > 0 && len(messages) >= limit) {
+			break
+		}
+		afterId = int64(page[len(page)-1].ID)
+	}
+
+	names, err := s.senderNames(conversation, messages)
+	if err != nil {
+		return nil, 0, err
+	}
+	t.Messages = make([]transcriptLine, 0, len(messages))
+	for _, message := range messages {
+		sender := "System"
+		if message.VisitorID != nil {
+			sender = names.visitor
+		} else if message.Type == model.MessageTypeBot {
+			sender = "Bot"
+		} else if message.SenderID != 0 {
+			sender = names.users[message.SenderID]
+		}
+		t.Messages = append(t.Messages, transcriptLine{
+			ID:        message.ID,
+			Sender:    sender,
+			SenderID:  message.SenderID,
+			VisitorID: message.VisitorID,
+			Type:      message.Type,
+			Content:   message.Content,
+			Internal:  message.Internal,
+			CreatedAt: message.CreatedAt,
+		})
+	}
+	return t, len(messages), nil
+}
+
+type transcriptNames struct {
+	visitor string
+	users   map[uint]string
+}
+
+func (s *TranscriptService) senderNames(conversation *model.Conversation, messages []*model.Message) (*transcriptNames, error) {
+	names := &transcriptNames{visitor: "Visitor", users: map[uint]string{}}
+	if conversation.VisitorID != nil {
+		visitor, err := s.visitorRepo.FindById(*conversation.VisitorID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if visitor != nil {
+			names.visitor = visitor.DisplayName()
+		}
+	}
+
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, message := range messages {
+		if message.SenderID != 0 && !seen[message.SenderID] {
+			seen[message.SenderID] = true
+			ids = append(ids, message.SenderID)
+		}
+	}
+	users, err := s.conversations.userRepo.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names.users[user.ID] = user.Name
+	}
+	for _, id := range ids {
+		if _, ok := names.users[id]; !ok {
+			names.users[id] = "Deleted user"
+		}
+	}
+	return names, nil
+}
+
+// requestEmail remembers where to email the transcript, queueing it at once for closed chats
+func (s *TranscriptService) requestEmail(conversation *model.Conversation, email string) error {
+	if email == "" {
+		return ErrNoTranscriptEmail
+	}
+	if conversation.IsClosed() {
+		return s.queueEmail(conversation, email)
+	}
+	return s.conversations.repo.Update(int64(conversation.ID), map[string]interface{}{"transcript_email": email})
+}
+
+func (s *TranscriptService) queueEmail(conversation *model.Conversation, email string) error {
+	return s.repo.Create(&model.TranscriptExport{
+		ConversationID: conversation.ID,
+		VisitorID:      conversation.VisitorID,
+		Format:         model.TranscriptHTML,
+		EmailTo:        email,
+		Status:         model.ExportPending,
+		ExpiresAt:      time.Now().Add(transcriptLinkTTL),
+	})
+}
+
+// sign sets the download link of a ready export
+func (s *TranscriptService) sign(export *model.TranscriptExport) error {
+	if export.Status != model.ExportReady {
+		return nil
+	}
+	token, err := util.GenerateDownloadToken(export.ID, export.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	export.DownloadURL = fmt.Sprintf("%s/api/transcripts/download?token=%s", s.appURL, url.QueryEscape(token))
+	return nil
+}
+
+// authorize lets members export a conversation and agents and admins export any support chat
+func (s *TranscriptService) authorize(userId uint, conversationId int64) (*model.Conversation, error) {
+	conversation, _, err := s.conversations.authorize(userId, conversationId)
+	if !errors.Is(err, ErrNotMember) {
+		return conversation, err
+	}
+	user, err := s.conversations.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err = s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsStaff() || !conversation.IsSupport() {
+		return nil, ErrNotMember
+	}
+	return conversation, nil
+}
+
+func (s *TranscriptService) visitorConversation(visitorId uint, conversationId int64) (*model.Conversation, error) {
+	conversation, err := s.conversations.repo.FindById(conversationId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+	if conversation.VisitorID == nil || *conversation.VisitorID != visitorId {
+		return nil, ErrConversationNotFound
+	}
+	return conversation, nil
+}
+
+func (s *TranscriptService) findExport(exportId int64) (*model.TranscriptExport, error) {
+	export, err := s.repo.FindById(exportId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+func validateTranscriptFormat(format string) error {
+	if format != model.TranscriptText && format != model.TranscriptHTML && format != model.TranscriptJSON {
+		return ErrInvalidTranscriptFormat
+	}
+	return nil
+}
+
+func transcriptFilename(conversationId uint, format string) string {
+	return fmt.Sprintf("transcript-%d.%s", conversationId, format)
+}
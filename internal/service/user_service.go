@@ -1,20 +1,27 @@
 package service
 
 import (
+	"errors"
 	"go_starter/internal/model"
 	"go_starter/internal/repository"
 	"go_starter/internal/util"
+
+	"gorm.io/gorm"
 )
 
+var ErrInvalidPrivacy = errors.New("dm privacy must be everyone or contacts")
+
 type UserService struct {
 	repo         *repository.UserRepository
 	emailService *EmailService
+	webhooks     *WebhookService
 }
 
-func NewUserService(repo *repository.UserRepository) *UserService {
+func NewUserService(repo *repository.UserRepository, webhooks *WebhookService) *UserService {
 	return &UserService{
 		repo:         repo,
 		emailService: NewEmailService(),
+		webhooks:     webhooks,
 	}
 }
 
@@ -30,6 +37,7 @@ func (s *UserService) CreateUser(name, email, password string) (*model.User, err
 	if err != nil {
 		return nil, err
 	}
+	s.webhooks.UserRegistered(user)
 
 	// Send welcome email (async to not block the response)
 	go func() {
@@ -76,3 +84,26 @@ func (s *UserService) DeleteUser(userId int64) error {
 func (s *UserService) PaginateUsers(page int32, pageSize int32) ([]*model.User, error) {
 	return s.repo.Paginate(page, pageSize)
 }
+
+// UpdateDMPrivacy controls whether users who are not contacts may start a direct conversation
+func (s *UserService) UpdateDMPrivacy(userId int64, privacy string) (*model.User, error) {
+	if privacy != model.DMPrivacyEveryone && privacy != model.DMPrivacyContacts {
+		return nil, ErrInvalidPrivacy
+	}
+	if err := s.repo.UpdateById(userId, &model.User{DMPrivacy: privacy}); err != nil {
+		return nil, err
+	}
+	return s.repo.FindById(userId)
+}
+
+// Role returns the current role of a user, or an empty role when the user no longer exists
+func (s *UserService) Role(userId uint) (string, error) {
+	user, err := s.repo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return user.Role, nil
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/realtime"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVisitorNotFound    = errors.New("visitor not found")
+	ErrNoOpenChat         = errors.New("you have no open support chat")
+	ErrNotSupportChat     = errors.New("this action is only available in support conversations")
+	ErrVisitorLinked      = errors.New("this visitor session is already linked to another account")
+	ErrInvalidVisitorLink = errors.New("visitor token is invalid or expired")
+	ErrSupportOffline     = errors.New("support is closed right now, please leave a message instead")
+)
+
+// ChatRequest carries what the widget knows about the visitor when a chat starts
+type ChatRequest struct {
+	DepartmentID *uint
+	PageURL      string
+	Language     string
+}
+
+type VisitorService struct {
+	repo          *repository.VisitorRepository
+	conversations *ConversationService
+	departments   *DepartmentService
+	routing       *RoutingService
+	transcripts   *TranscriptService
+	sla           *SLAService
+	bot           *BotService
+	hub           *realtime.Hub
+}
+
+func NewVisitorService(repo *repository.VisitorRepository, conversations *ConversationService, departments *DepartmentService, routing *RoutingService, transcripts *TranscriptService, sla *SLAService, bot *BotService, hub *realtime.Hub) *VisitorService {
+	return &VisitorService{
+		repo:          repo,
+		conversations: conversations,
+		departments:   departments,
+		routing:       routing,
+		transcripts:   transcripts,
+		sla:           sla,
+		bot:           bot,
+		hub:           hub,
+	}
+}
+
+// StartSession creates a visitor and issues the session token the widget uses
+// for every later request. Name and email are optional.
+func (s *VisitorService) StartSession(name, email, pageURL, language string) (*model.Visitor, string, error) {
+	visitor := &model.Visitor{
+		Name:       strings.TrimSpace(name),
+		Email:      strings.TrimSpace(email),
+		PageURL:    strings.TrimSpace(pageURL),
+		Language:   strings.TrimSpace(language),
+		LastSeenAt: time.Now(),
+	}
+	if err := s.repo.Create(visitor); err != nil {
+		return nil, "", err
+	}
+
+	token, err := util.GenerateVisitorToken(visitor.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return visitor, token, nil
+}
+
+func (s *VisitorService) GetVisitor(visitorId uint) (*model.Visitor, error) {
+	visitor, err := s.findVisitor(visitorId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.TouchLastSeen(visitorId, time.Now()); err != nil {
+		return nil, err
+	}
+	return visitor, nil
+}
+
+// UpdateProfile sets the name and/or email the visitor chose to share. Nil fields are left unchanged.
+func (s *VisitorService) UpdateProfile(visitorId uint, name, email *string) (*model.Visitor, error) {
+	fields := map[string]interface{}{"last_seen_at": time.Now()}
+	if name != nil {
+		fields["name"] = strings.TrimSpace(*name)
+	}
+	if email != nil {
+		fields["email"] = strings.TrimSpace(*email)
+	}
+	if err := s.repo.Update(visitorId, fields); err != nil {
+		return nil, err
+	}
+	return s.findVisitor(visitorId)
+}
+
+// OpenChat returns the visitor's open support conversation or starts one and puts
+// it in the queue of the department the visitor picked, or the one matched by the
+// department rules. When the department has a bot flow, the bot takes the chat
+// first and queues it later. New chats are refused outside business hours.
+func (s *VisitorService) OpenChat(visitorId uint, request ChatRequest) (*model.Conversation, error) {
+	existing, err := s.conversations.repo.FindOpenSupport(visitorId)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Remember where the visitor is chatting from for routing and for agents
+	fields := map[string]interface{}{}
+	if pageURL := strings.TrimSpace(request.PageURL); pageURL != "" {
+		fields["page_url"] = pageURL
+	}
+	if language := strings.TrimSpace(request.Language); language != "" {
+		fields["language"] = language
+	}
+	if len(fields) > 0 {
+		if err := s.repo.Update(visitorId, fields); err != nil {
+			return nil, err
+		}
+	}
+	visitor, err := s.findVisitor(visitorId)
+	if err != nil {
+		return nil, err
+	}
+
+	departmentId, err := s.departments.Resolve(request.DepartmentID, visitor.PageURL, visitor.Language)
+	if err != nil {
+		return nil, err
+	}
+	availability, err := s.departments.Availability(departmentId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !availability.Online {
+		return nil, ErrSupportOffline
+	}
+
+	flow, err := s.bot.FlowFor(departmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	conversation := &model.Conversation{
+		Type:         model.ConversationTypeSupport,
+		Name:         visitor.DisplayName(),
+		VisitorID:    &visitor.ID,
+		Status:       model.SupportStatusQueued,
+		DepartmentID: departmentId,
+		Priority:     model.PriorityNormal,
+		QueuedAt:     &now,
+		RoutedAt:     &now,
+	}
+	if flow != nil {
+		conversation.Status = model.SupportStatusBot
+		conversation.QueuedAt = nil
+		conversation.RoutedAt = nil
+	}
+	if err := s.conversations.repo.Create(conversation, nil); err != nil {
+		return nil, err
+	}
+	s.conversations.webhooks.Publish(model.WebhookConversationCreated, conversation)
+	if err := s.conversations.postSystemMessage(int64(conversation.ID), fmt.Sprintf("%s started a chat", visitor.DisplayName())); err != nil {
+		return nil, err
+	}
+	if flow != nil {
+		if err := s.bot.Start(conversation, flow); err != nil {
+			return nil, err
+		}
+		return s.conversations.repo.FindById(int64(conversation.ID))
+	}
+	if err := s.sla.ChatOpened(conversation); err != nil {
+		return nil, err
+	}
+
+	if err := s.routing.RouteQueue(context.Background()); err != nil {
+		return nil, err
+	}
+	return s.conversations.repo.FindById(int64(conversation.ID))
+}
+
+// QueuePosition returns the visitor's open chat and its place in the queue, or 0
+// once an agent has picked it up
+func (s *VisitorService) QueuePosition(visitorId uint) (*model.Conversation, int64, error) {
+	conversation, err := s.CurrentChat(visitorId)
+	if err != nil {
+		return nil, 0, err
+	}
+	if conversation.Status != model.SupportStatusQueued {
+		return conversation, 0, nil
+	}
+	position, err := s.conversations.repo.QueuePosition(conversation)
+	if err != nil {
+		return nil, 0, err
+	}
+	return conversation, position, nil
+}
+
+// CurrentChat returns the visitor's open support conversation
+func (s *VisitorService) CurrentChat(visitorId uint) (*model.Conversation, error) {
+	conversation, err := s.conversations.repo.FindOpenSupport(visitorId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoOpenChat
+		}
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// SendMessage posts a visitor message into their open chat, starting one first when needed
+func (s *VisitorService) SendMessage(visitorId uint, content string) (*model.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	conversation, err := s.OpenChat(visitorId, ChatRequest{})
+	if err != nil {
+		return nil, err
+	}
+	conversationId := int64(conversation.ID)
+
+	message := &model.Message{
+		ConversationID: conversation.ID,
+		VisitorID:      &visitorId,
+		Type:           model.MessageTypeText,
+		Content:        content,
+	}
+	if err := s.conversations.msgRepo.Create(message); err != nil {
+		return nil, err
+	}
+	if err := s.conversations.recordActivity(conversationId); err != nil {
+		return nil, err
+	}
+	if err := s.repo.TouchLastSeen(visitorId, time.Now()); err != nil {
+		return nil, err
+	}
+
+	event := realtime.Event{Type: realtime.EventMessageCreated, Data: message}
+	s.conversations.broadcast(conversationId, event)
+	s.conversations.relayToVisitor(conversation, event)
+	s.conversations.notify(conversationId, message)
+	s.conversations.webhooks.Publish(model.WebhookMessageCreated, message)
+
+	if conversation.Status == model.SupportStatusBot {
+		if err := s.bot.Respond(conversation, content); err != nil {
+			return nil, err
+		}
+	}
+	return message, nil
+}
+
+// RequestAgent hands the visitor's chat from the bot to the agent queue
+func (s *VisitorService) RequestAgent(visitorId uint) (*model.Conversation, error) {
+	conversation, err := s.CurrentChat(visitorId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.Status != model.SupportStatusBot {
+		return nil, ErrNotWithBot
+	}
+	if err := s.bot.Handoff(conversation); err != nil {
+		return nil, err
+	}
+	return s.conversations.repo.FindById(int64(conversation.ID))
+}
+
+// ListMessages returns a page of the visitor's open chat history, newest first
+func (s *VisitorService) ListMessages(visitorId uint, beforeId int64, limit int) ([]*model.Message, error) {
+	conversation, err := s.CurrentChat(visitorId)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	if limit > maxMessagePageSize {
+		limit = maxMessagePageSize
+	}
+	return s.conversations.msgRepo.FindByConversation(int64(conversation.ID), beforeId, limit, nil, false)
+}
+
+// Typing tells the agents in the visitor's open chat that the visitor is typing
+func (s *VisitorService) Typing(visitorId uint) error {
+	conversation, err := s.CurrentChat(visitorId)
+	if err != nil {
+		return err
+	}
+	s.conversations.broadcast(int64(conversation.ID), realtime.Event{
+		Type: realtime.EventTyping,
+		Data: map[string]interface{}{"conversation_id": conversation.ID, "visitor_id": visitorId},
+	})
+	return nil
+}
+
+// EndChat lets the visitor close their open chat
+func (s *VisitorService) EndChat(visitorId uint) (*model.Conversation, error) {
+	visitor, err := s.findVisitor(visitorId)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := s.CurrentChat(visitorId)
+	if err != nil {
+		return nil, err
+	}
+	return s.close(conversation, fmt.Sprintf("%s ended the chat", visitor.DisplayName()))
+}
+
+// CloseChat lets an agent taking part in a support conversation close it
+func (s *VisitorService) CloseChat(agentId uint, conversationId int64) (*model.Conversation, error) {
+	conversation, _, err := s.conversations.authorize(agentId, conversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !conversation.IsSupport() {
+		return nil, ErrNotSupportChat
+	}
+	agent, err := s.conversations.findUser(agentId)
+	if err != nil {
+		return nil, err
+	}
+	if !agent.IsAgent() {
+		return nil, ErrForbidden
+	}
+	return s.close(conversation, fmt.Sprintf("%s closed the chat", agent.Name))
+}
+
+// LinkToUser attaches a visitor session to a registered account and gives the
+// account access to the visitor's earlier support conversations
+func (s *VisitorService) LinkToUser(visitorToken string, userId uint) (*model.Visitor, error) {
+	claims, err := util.ValidateVisitorToken(visitorToken)
+	if err != nil {
+		return nil, ErrInvalidVisitorLink
+	}
+	visitor, err := s.findVisitor(claims.VisitorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if visitor.UserID == nil {
+		linked, err := s.repo.LinkUser(visitor.ID, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !linked {
+			// Another account claimed the visitor in the meantime
+			if visitor, err = s.findVisitor(visitor.ID); err != nil {
+				return nil, err
+			}
+		} else {
+			visitor.UserID = &userId
+		}
+	}
+	if *visitor.UserID != userId {
+		return nil, ErrVisitorLinked
+	}
+
+	conversations, err := s.conversations.repo.FindSupportByVisitor(visitor.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, conversation := range conversations {
+		conversationId := int64(conversation.ID)
+		if _, err := s.conversations.repo.FindMember(conversationId, userId); err == nil {
+			continue
+		}
+		member := &model.ConversationMember{
+			ConversationID: conversation.ID,
+			UserID:         userId,
+			Role:           model.MemberRoleMember,
+			JoinedAt:       now,
+		}
+		if err := s.conversations.repo.AddMembers([]*model.ConversationMember{member}); err != nil {
+			return nil, err
+		}
+		s.conversations.broadcast(conversationId, realtime.Event{Type: realtime.EventMemberAdded, Data: []*model.ConversationMember{member}})
+	}
+	return visitor, nil
+}
+
+// close ends a support conversation once and records text in its history
+func (s *VisitorService) close(conversation *model.Conversation, text string) (*model.Conversation, error) {
+	conversationId := int64(conversation.ID)
+	now := time.Now()
+	closed, err := s.conversations.repo.Close(conversationId, now)
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, ErrConversationClosed
+	}
+	conversation.Status = model.SupportStatusClosed
+	conversation.ClosedAt = &now
+
+	event := realtime.Event{Type: realtime.EventConversationClosed, Data: conversation}
+	s.conversations.broadcast(conversationId, event)
+	s.conversations.relayToVisitor(conversation, event)
+	if err := s.conversations.postSystemMessage(conversationId, text); err != nil {
+		return nil, err
+	}
+	if err := s.routing.ChatClosed(conversation); err != nil {
+		return nil, err
+	}
+	if err := s.transcripts.ChatClosed(conversationId); err != nil {
+		return nil, err
+	}
+	s.conversations.webhooks.Publish(model.WebhookConversationClosed, conversation)
+	return conversation, nil
+}
+
+func (s *VisitorService) findVisitor(visitorId uint) (*model.Visitor, error) {
+	visitor, err := s.repo.FindById(visitorId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVisitorNotFound
+		}
+		return nil, err
+	}
+	return visitor, nil
+}
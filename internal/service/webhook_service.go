@@ -0,0 +1,529 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_starter/internal/model"
+	"go_starter/internal/repository"
+	"go_starter/internal/util"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL    = errors.New("webhook URL must be an absolute http or https URL of at most 500 characters")
+	ErrInvalidWebhookEvents = errors.New("events must be a non-empty list of known event types")
+	ErrDeliveryPending      = errors.New("this delivery is still queued")
+)
+
+const (
+	webhookBatchSize      = 50
+	webhookMaxAttempts    = 8
+	webhookRetryBase      = 30 * time.Second
+	webhookRetryMax       = 6 * time.Hour
+	webhookLease          = 2 * time.Minute // longer than any attempt may take
+	webhookResponseLimit  = 1000
+	maxWebhookURLLength   = 500
+	maxWebhookDescription = 255
+	maxDeliveryPageSize   = 100
+)
+
+// WebhookInput holds the editable fields of a webhook endpoint
+type WebhookInput struct {
+	URL         string
+	Description string
+	Events      []string
+	Enabled     bool
+}
+
+// WebhookEvent is the JSON body POSTed to endpoints
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDeliveryDetail is a delivery with the log of its attempts
+type WebhookDeliveryDetail struct {
+	*model.WebhookDelivery
+	Log []*model.WebhookAttempt `json:"log"`
+}
+
+// webhookUser is the public view of a user in event payloads
+type webhookUser struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// WebhookService lets admins register endpoints for chat events and delivers the
+// events to them. Events are queued in the database and sent by a background job,
+// each signed with the endpoint's secret: X-Webhook-Signature is "sha256=" and
+// the hex HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the body.
+type WebhookService struct {
+	repo     *repository.WebhookRepository
+	userRepo *repository.UserRepository
+	client   *http.Client
+	logger   *zap.Logger
+}
+
+func NewWebhookService(repo *repository.WebhookRepository, userRepo *repository.UserRepository, client *http.Client, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		repo:     repo,
+		userRepo: userRepo,
+		client:   client,
+		logger:   logger,
+	}
+}
+
+// ListEndpoints returns every webhook endpoint. Admins only.
+func (s *WebhookService) ListEndpoints(actorId uint) ([]*model.WebhookEndpoint, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.repo.FindEndpoints()
+}
+
+func (s *WebhookService) GetEndpoint(actorId uint, endpointId int64) (*model.WebhookEndpoint, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	return s.findEndpoint(endpointId)
+}
+
+// CreateEndpoint registers an endpoint and returns it with its signing secret,
+// which is only shown here and when rotated. Admins only.
+func (s *WebhookService) CreateEndpoint(actorId uint, input WebhookInput) (*model.WebhookEndpoint, string, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, "", err
+	}
+	endpoint := &model.WebhookEndpoint{CreatedByID: actorId}
+	if err := applyWebhookInput(endpoint, input); err != nil {
+		return nil, "", err
+	}
+	secret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint.Secret = secret
+	if err := s.repo.CreateEndpoint(endpoint); err != nil {
+		return nil, "", err
+	}
+	return endpoint, secret, nil
+}
+
+// UpdateEndpoint changes the endpoint's URL, description, events or enabled state.
+// Admins only.
+func (s *WebhookService) UpdateEndpoint(actorId uint, endpointId int64, input WebhookInput) (*model.WebhookEndpoint, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.findEndpoint(endpointId)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookInput(endpoint, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// RotateSecret replaces the endpoint's signing secret and returns the new one.
+// Deliveries still queued are signed with it. Admins only.
+func (s *WebhookService) RotateSecret(actorId uint, endpointId int64) (string, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return "", err
+	}
+	endpoint, err := s.findEndpoint(endpointId)
+	if err != nil {
+		return "", err
+	}
+	secret, err := util.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	endpoint.Secret = secret
+	if err := s.repo.SaveEndpoint(endpoint); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DeleteEndpoint removes the endpoint along with its delivery log. Admins only.
+func (s *WebhookService) DeleteEndpoint(actorId uint, endpointId int64) error {
+	if err := s.requireAdmin(actorId); err != nil {
+		return err
+	}
+	if _, err := s.findEndpoint(endpointId); err != nil {
+		return err
+	}
+	return s.repo.DeleteEndpoint(endpointId)
+}
+
+// Ping queues a webhook.ping event for the endpoint so admins can check it receives
+// and verifies deliveries. Admins only.
+func (s *WebhookService) Ping(actorId uint, endpointId int64) (*model.WebhookDelivery, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.findEndpoint(endpointId)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.queue(model.WebhookPing, map[string]interface{}{"endpoint_id": endpoint.ID}, []*model.WebhookEndpoint{endpoint})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// Deliveries returns a page of the endpoint's delivery log, newest first, older
+// than beforeId when set and optionally only those with the status. Admins only.
+func (s *WebhookService) Deliveries(actorId uint, endpointId int64, status string, beforeId int64, limit int) ([]*model.WebhookDelivery, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	if _, err := s.findEndpoint(endpointId); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxDeliveryPageSize {
+		limit = maxDeliveryPageSize
+	}
+	return s.repo.FindDeliveries(endpointId, status, beforeId, limit)
+}
+
+// Delivery returns one delivery with its payload and the log of its attempts.
+// Admins only.
+func (s *WebhookService) Delivery(actorId uint, endpointId, deliveryId int64) (*WebhookDeliveryDetail, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	delivery, err := s.findDelivery(endpointId, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := s.repo.FindAttempts(deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookDeliveryDetail{WebhookDelivery: delivery, Log: attempts}, nil
+}
+
+// Redeliver queues the payload of a delivered or dead-lettered delivery again as a
+// new delivery, leaving the original in the log. Admins only.
+func (s *WebhookService) Redeliver(actorId uint, endpointId, deliveryId int64) (*model.WebhookDelivery, error) {
+	if err := s.requireAdmin(actorId); err != nil {
+		return nil, err
+	}
+	original, err := s.findDelivery(endpointId, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == model.WebhookDeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	delivery := &model.WebhookDelivery{
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		MessageID:     original.MessageID,
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+		RedeliveryOf:  &original.ID,
+	}
+	if err := s.repo.CreateDeliveries([]*model.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Publish queues the event for every enabled endpoint subscribed to its type. It
+// runs after the change it reports is stored, so a failure is logged rather than
+// returned: webhooks must never fail the write that triggered them.
+func (s *WebhookService) Publish(eventType string, data interface{}) {
+	if err := s.publish(eventType, data); err != nil {
+		s.logger.Error("Failed to queue webhook event",
+			zap.String("event", eventType),
+			zap.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *WebhookService) publish(eventType string, data interface{}) error {
+	endpoints, err := s.repo.FindEnabledEndpoints()
+	if err != nil {
+		return err
+	}
+	var subscribed []*model.WebhookEndpoint
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(eventType) {
+			subscribed = append(subscribed, endpoint)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+	_, err = s.queue(eventType, data, subscribed)
+	return err
+}
+
+// UserRegistered publishes the user.registered event for a new account
+func (s *WebhookService) UserRegistered(user *model.User) {
+	s.Publish(model.WebhookUserRegistered, webhookUser{ID: user.ID, Name: user.Name, Email: user.Email})
+}
+
+// DeliverDue sends the deliveries whose next attempt is due. Failed attempts are
+// retried with exponential backoff and dead-lettered after the last one. Each
+// attempt is claimed first, so concurrent server replicas never send it twice.
+// It runs periodically as a background job.
+func (s *WebhookService) DeliverDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		now := time.Now()
+		due, err := s.repo.FindDue(now, webhookBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range due {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			claimed, err := s.repo.Claim(delivery, time.Now(), time.Now().Add(webhookLease))
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			if err := s.attempt(ctx, delivery); err != nil {
+				return err
+			}
+		}
+		if len(due) < webhookBatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// attempt sends the delivery once and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	delivery.Attempts++
+	record := &model.WebhookAttempt{DeliveryID: delivery.ID, Attempt: delivery.Attempts}
+
+	started := time.Now()
+	endpoint, err := s.repo.FindEndpointById(int64(delivery.EndpointID))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		record.Error = "endpoint was deleted"
+	case err != nil:
+		return err
+	case !endpoint.Enabled:
+		record.Error = "endpoint is disabled"
+	default:
+		record.ResponseStatus, record.ResponseBody, err = s.send(ctx, endpoint, delivery, started)
+		if err != nil {
+			record.Error = err.Error()
+		} else if record.ResponseStatus < 200 || record.ResponseStatus > 299 {
+			record.Error = "endpoint responded with status " + strconv.Itoa(record.ResponseStatus)
+		}
+	}
+	now := time.Now()
+	record.DurationMs = now.Sub(started).Milliseconds()
+	return s.repo.RecordAttempt(record, attemptOutcome(record, endpoint, now))
+}
+
+// attemptOutcome returns the delivery fields to store after the attempt: delivered
+// on success, otherwise retried after a backoff until the last attempt, or dead
+// at once when the endpoint is gone or disabled
+func attemptOutcome(record *model.WebhookAttempt, endpoint *model.WebhookEndpoint, now time.Time) map[string]interface{} {
+	fields := map[string]interface{}{"response_status": record.ResponseStatus, "error": record.Error}
+	switch {
+	case record.Error == "":
+		fields["status"] = model.WebhookDeliveryDelivered
+		fields["delivered_at"] = now
+	case endpoint == nil || !endpoint.Enabled || record.Attempt >= webhookMaxAttempts:
+		fields["status"] = model.WebhookDeliveryDead
+	default:
+		fields["next_attempt_at"] = now.Add(webhookBackoff(record.Attempt))
+	}
+	return fields
+}
+
+// send POSTs the signed payload and returns the response status and the start of
+// its body
+func (s *WebhookService) send(ctx context.Context, endpoint *model.WebhookEndpoint, delivery *model.WebhookDelivery, now time.Time) (int, string, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "go_starter-webhooks/1.0")
+	request.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(delivery.ID), 10))
+	request.Header.Set("X-Webhook-Event", delivery.EventType)
+	request.Header.Set("X-Webhook-Timestamp", timestamp)
+	request.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(endpoint.Secret, timestamp, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, webhookResponseLimit))
+	if err != nil {
+		return response.StatusCode, "", err
+	}
+	return response.StatusCode, string(body), nil
+}
+
+// queue stores one delivery of the event per endpoint
+func (s *WebhookService) queue(eventType string, data interface{}, endpoints []*model.WebhookEndpoint) ([]*model.WebhookDelivery, error) {
+	eventId, err := util.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	payload, err := json.Marshal(WebhookEvent{ID: "evt_" + eventId, Type: eventType, CreatedAt: now, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	// Payloads carrying a message are deleted when the message is purged
+	var messageId *uint
+	if message, ok := data.(*model.Message); ok {
+		messageId = &message.ID
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       "evt_" + eventId,
+			EventType:     eventType,
+			Payload:       string(payload),
+			MessageID:     messageId,
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := s.repo.CreateDeliveries(deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookService) findEndpoint(endpointId int64) (*model.WebhookEndpoint, error) {
+	endpoint, err := s.repo.FindEndpointById(endpointId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *WebhookService) findDelivery(endpointId, deliveryId int64) (*model.WebhookDelivery, error) {
+	if _, err := s.findEndpoint(endpointId); err != nil {
+		return nil, err
+	}
+	delivery, err := s.repo.FindDelivery(deliveryId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	if int64(delivery.EndpointID) != endpointId {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) requireAdmin(userId uint) error {
+	user, err := s.userRepo.FindById(int64(userId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// applyWebhookInput validates the input and copies it to the endpoint
+func applyWebhookInput(endpoint *model.WebhookEndpoint, input WebhookInput) error {
+	rawURL := strings.TrimSpace(input.URL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(rawURL) > maxWebhookURLLength {
+		return ErrInvalidWebhookURL
+	}
+	if len(input.Events) == 0 {
+		return ErrInvalidWebhookEvents
+	}
+	seen := map[string]bool{}
+	events := make([]string, 0, len(input.Events))
+	for _, event := range input.Events {
+		if !model.IsValidWebhookEvent(event) {
+			return ErrInvalidWebhookEvents
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	description := strings.TrimSpace(input.Description)
+	if len(description) > maxWebhookDescription {
+		description = description[:maxWebhookDescription]
+	}
+
+	endpoint.URL = rawURL
+	endpoint.Description = description
+	endpoint.Events = events
+	endpoint.Enabled = input.Enabled
+	return nil
+}
+
+// webhookBackoff returns how long to wait after the given number of failed
+// attempts: 30s, 1m, 2m, 4m and so on, at most 6 hours
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+// signWebhook returns the hex HMAC-SHA256 of the timestamp and payload
+func signWebhook(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
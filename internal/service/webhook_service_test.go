@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go_starter/internal/model"
+)
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	var got *http.Request
+	var body string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		got, body = r, string(raw)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	s := &WebhookService{client: receiver.Client()}
+	endpoint := &model.WebhookEndpoint{URL: receiver.URL, Secret: "s3cret", Enabled: true}
+	delivery := &model.WebhookDelivery{ID: 7, EventType: model.WebhookMessageCreated, Payload: `{"type":"message.created"}`}
+	now := time.Unix(1700000000, 0)
+
+	status, response, err := s.send(context.Background(), endpoint, delivery, now)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if status != http.StatusAccepted || response != "ok" {
+		t.Fatalf("got status %d and body %q, want 202 and \"ok\"", status, response)
+	}
+	if body != delivery.Payload {
+		t.Errorf("receiver got body %q, want %q", body, delivery.Payload)
+	}
+
+	timestamp := got.Header.Get("X-Webhook-Timestamp")
+	if timestamp != strconv.FormatInt(now.Unix(), 10) {
+		t.Errorf("got timestamp %q, want %d", timestamp, now.Unix())
+	}
+	want := "sha256=" + signWebhook("s3cret", timestamp, body)
+	if signature := got.Header.Get("X-Webhook-Signature"); signature != want {
+		t.Errorf("got signature %q, want %q", signature, want)
+	}
+	if signature := got.Header.Get("X-Webhook-Signature"); signature == "sha256="+signWebhook("other", timestamp, body) {
+		t.Error("signature does not depend on the secret")
+	}
+	if event := got.Header.Get("X-Webhook-Event"); event != model.WebhookMessageCreated {
+		t.Errorf("got event header %q, want %q", event, model.WebhookMessageCreated)
+	}
+	if id := got.Header.Get("X-Webhook-Id"); id != "7" {
+		t.Errorf("got id header %q, want \"7\"", id)
+	}
+}
+
+func TestWebhookRetriesWithBackoffUntilDelivered(t *testing.T) {
+	failures := 2
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	s := &WebhookService{client: receiver.Client()}
+	endpoint := &model.WebhookEndpoint{URL: receiver.URL, Secret: "s3cret", Enabled: true}
+	delivery := &model.WebhookDelivery{ID: 1, EventType: model.WebhookPing, Payload: `{}`}
+	now := time.Unix(1700000000, 0)
+
+	wantDelays := []time.Duration{30 * time.Second, time.Minute}
+	for attempt := 1; attempt <= failures+1; attempt++ {
+		record := &model.WebhookAttempt{Attempt: attempt}
+		status, _, err := s.send(context.Background(), endpoint, delivery, now)
+		if err != nil {
+			t.Fatalf("attempt %d: %v", attempt, err)
+		}
+		record.ResponseStatus = status
+		if status < 200 || status > 299 {
+			record.Error = "endpoint responded with status " + strconv.Itoa(status)
+		}
+
+		fields := attemptOutcome(record, endpoint, now)
+		if attempt <= failures {
+			next, ok := fields["next_attempt_at"].(time.Time)
+			if !ok {
+				t.Fatalf("attempt %d: not scheduled for a retry: %v", attempt, fields)
+			}
+			if delay := next.Sub(now); delay != wantDelays[attempt-1] {
+				t.Errorf("attempt %d: retry after %v, want %v", attempt, delay, wantDelays[attempt-1])
+			}
+			now = next
+			continue
+		}
+		if fields["status"] != model.WebhookDeliveryDelivered {
+			t.Errorf("attempt %d: got status %v, want delivered", attempt, fields["status"])
+		}
+	}
+	if calls != failures+1 {
+		t.Errorf("receiver got %d calls, want %d", calls, failures+1)
+	}
+}
+
+func TestWebhookDeadLettersAfterLastAttempt(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	receiver.Close() // nothing listens any more, so every attempt fails to connect
+
+	s := &WebhookService{client: &http.Client{Timeout: time.Second}}
+	endpoint := &model.WebhookEndpoint{URL: receiver.URL, Secret: "s3cret", Enabled: true}
+	delivery := &model.WebhookDelivery{ID: 1, EventType: model.WebhookPing, Payload: `{}`}
+
+	_, _, err := s.send(context.Background(), endpoint, delivery, time.Now())
+	if err == nil {
+		t.Fatal("send to a closed receiver succeeded")
+	}
+	record := &model.WebhookAttempt{Attempt: webhookMaxAttempts, Error: err.Error()}
+	if fields := attemptOutcome(record, endpoint, time.Now()); fields["status"] != model.WebhookDeliveryDead {
+		t.Errorf("last attempt: got status %v, want dead", fields["status"])
+	}
+
+	record.Attempt = 1
+	disabled := &model.WebhookEndpoint{URL: receiver.URL, Enabled: false}
+	if fields := attemptOutcome(record, disabled, time.Now()); fields["status"] != model.WebhookDeliveryDead {
+		t.Errorf("disabled endpoint: got status %v, want dead", fields["status"])
+	}
+	if fields := attemptOutcome(record, nil, time.Now()); fields["status"] != model.WebhookDeliveryDead {
+		t.Errorf("deleted endpoint: got status %v, want dead", fields["status"])
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{10, 4*time.Hour + 16*time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -12,6 +12,13 @@ var (
 	JWTSecretKey = []byte(os.Getenv("JWT_SECRET"))
 )
 
+// Audiences of tokens that do not identify a user, so they are never accepted as user tokens
+const (
+	VisitorTokenAudience    = "visitor"
+	RatingTokenAudience     = "chat-rating"
+	TranscriptTokenAudience = "transcript"
+)
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
@@ -60,5 +67,143 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	// Visitor session, rating and download tokens are signed with the same key but do not identify a user
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// VisitorClaims identifies an anonymous website visitor
+type VisitorClaims struct {
+	VisitorID uint `json:"visitor_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateVisitorToken Generates a long-lived session token for a website visitor
+func GenerateVisitorToken(visitorID uint) (string, error) {
+	now := time.Now()
+
+	claims := &VisitorClaims{
+		VisitorID: visitorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{VisitorTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(30 * 24 * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JWTSecretKey)
+}
+
+// ValidateVisitorToken validates a visitor session token and returns its claims.
+// User tokens are rejected because they lack the visitor audience.
+func ValidateVisitorToken(tokenString string) (*VisitorClaims, error) {
+	claims := &VisitorClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("Invalid signing method")
+		}
+		return JWTSecretKey, nil
+	}, jwt.WithAudience(VisitorTokenAudience))
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.VisitorID == 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// RatingClaims lets whoever holds the link rate one closed support conversation
+type RatingClaims struct {
+	ConversationID uint `json:"conversation_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateRatingToken Generates the token of an emailed rating link
+func GenerateRatingToken(conversationID uint, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	claims := &RatingClaims{
+		ConversationID: conversationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{RatingTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JWTSecretKey)
+}
+
+// ValidateRatingToken validates the token of a rating link and returns its claims
+func ValidateRatingToken(tokenString string) (*RatingClaims, error) {
+	claims := &RatingClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("Invalid signing method")
+		}
+		return JWTSecretKey, nil
+	}, jwt.WithAudience(RatingTokenAudience))
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.ConversationID == 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// DownloadClaims grant access to one generated transcript export
+type DownloadClaims struct {
+	ExportID uint `json:"export_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateDownloadToken Generates the token of a signed transcript download link
+func GenerateDownloadToken(exportID uint, expiresAt time.Time) (string, error) {
+	now := time.Now()
+
+	claims := &DownloadClaims{
+		ExportID: exportID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{TranscriptTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(JWTSecretKey)
+}
+
+// ValidateDownloadToken validates the token of a transcript download link and returns its claims
+func ValidateDownloadToken(tokenString string) (*DownloadClaims, error) {
+	claims := &DownloadClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("Invalid signing method")
+		}
+		return JWTSecretKey, nil
+	}, jwt.WithAudience(TranscriptTokenAudience))
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.ExportID == 0 {
+		return nil, errors.New("invalid token")
+	}
+
 	return claims, nil
 }
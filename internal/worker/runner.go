@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job is a background task run periodically by the Runner
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner runs background jobs inside the server process until its context is cancelled
+type Runner struct {
+	jobs   []Job
+	logger *zap.Logger
+	wg     sync.WaitGroup
+}
+
+func NewRunner(logger *zap.Logger) *Runner {
+	return &Runner{logger: logger}
+}
+
+// Add registers a job; it must be called before Start
+func (r *Runner) Add(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+// Start launches every job in its own goroutine. Each job runs once immediately and
+// then on every tick of its interval.
+func (r *Runner) Start(ctx context.Context) {
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(ctx, job)
+	}
+	r.logger.Info("Background jobs started", zap.Int("jobs", len(r.jobs)))
+}
+
+// Wait blocks until every job has stopped after the context was cancelled
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce runs the job, logging failures and recovering from panics so one bad
+// run does not stop the job
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.logger.Error("Background job panicked",
+				zap.String("job", job.Name),
+				zap.Any("panic", rec),
+			)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		r.logger.Error("Background job failed",
+			zap.String("job", job.Name),
+			zap.String("error", err.Error()),
+		)
+	}
+}